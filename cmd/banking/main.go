@@ -1,9 +1,11 @@
 package main
 
 import (
-	"org/gg/banking/internal/config"
+	"context"
+	"org/gg/banking/internal/cli"
+	"os"
 )
 
 func main() {
-	config.SetupApp()
+	os.Exit(cli.Execute(context.Background()))
 }
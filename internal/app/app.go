@@ -0,0 +1,673 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"org/gg/banking/internal/audit"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/controllers"
+	"org/gg/banking/internal/database"
+	"org/gg/banking/internal/events"
+	"org/gg/banking/internal/metrics"
+	authmiddleware "org/gg/banking/internal/middleware/auth"
+	httplogger "org/gg/banking/internal/middleware/logger"
+	ratelimitmiddleware "org/gg/banking/internal/middleware/ratelimit"
+	"org/gg/banking/internal/ratelimit"
+	"org/gg/banking/internal/repository"
+	"org/gg/banking/internal/routes"
+	"org/gg/banking/internal/services"
+	"org/gg/banking/internal/statements"
+	"org/gg/banking/internal/webhooks"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// App holds every component of the banking API and controls its lifecycle
+type App struct {
+	config *config.AppConfiguration
+	logger *slog.Logger
+
+	db     *sql.DB
+	ownsDB bool
+
+	customerRepository         repository.ICustomerRepository
+	accountRepository          repository.IAccountRepository
+	customerService            services.ICustomerService
+	customerController         controllers.ICustomerController
+	customerImportController   controllers.ICustomerImportController
+	customerExportController   controllers.ICustomerExportController
+	accountStatementController controllers.IAccountStatementController
+
+	auditRepository repository.IAuditRepository
+	transactor      repository.ITransactor
+	auditController controllers.IAuditController
+	// checkpointer is nil unless audit checkpoints are enabled
+	checkpointer *audit.Checkpointer
+
+	outboxRepository repository.IOutboxRepository
+	eventPublisher   events.IEventPublisher
+	// relay is nil when the outbox relay is disabled
+	relay *events.Relay
+
+	webhookRepository repository.IWebhookRepository
+	webhookController controllers.IWebhookController
+	// dispatcher is nil unless webhooks are enabled
+	dispatcher *webhooks.Dispatcher
+
+	apiKeyRepository repository.IAPIKeyRepository
+	apiKeyService    services.IAPIKeyService
+	apiKeyController controllers.IAPIKeyController
+
+	tokenVerifier auth.ITokenVerifier
+	policy        services.IPolicy
+	// rolePolicy is the configured policy, kept to apply reloaded rules
+	rolePolicy *services.RolePolicy
+
+	redactor    *httplogger.Redactor
+	bodyCapture *httplogger.BodyCapture
+
+	rateLimitStore ratelimit.IStore
+	// rateLimiter is nil when rate limiting is disabled
+	rateLimiter *ratelimit.Limiter
+
+	router *gin.Engine
+	server *http.Server
+
+	stopReload      func()
+	stopBackground  context.CancelFunc
+	backgroundTasks sync.WaitGroup
+}
+
+// Option overrides a component before the application is wired together
+type Option func(*App)
+
+// WithLogger replaces the application logger, which defaults to logger.Logger
+func WithLogger(appLogger *slog.Logger) Option {
+	return func(a *App) {
+		a.logger = appLogger
+	}
+}
+
+// WithDB uses an existing database connection instead of opening one from the configuration.
+// The caller stays responsible for closing it.
+func WithDB(db *sql.DB) Option {
+	return func(a *App) {
+		a.db = db
+	}
+}
+
+// WithCustomerRepository replaces the Postgres customer repository
+func WithCustomerRepository(customerRepository repository.ICustomerRepository) Option {
+	return func(a *App) {
+		a.customerRepository = customerRepository
+	}
+}
+
+// WithAccountRepository replaces the Postgres account repository
+func WithAccountRepository(accountRepository repository.IAccountRepository) Option {
+	return func(a *App) {
+		a.accountRepository = accountRepository
+	}
+}
+
+// WithAuditRepository replaces the Postgres audit log repository
+func WithAuditRepository(auditRepository repository.IAuditRepository) Option {
+	return func(a *App) {
+		a.auditRepository = auditRepository
+	}
+}
+
+// WithOutboxRepository replaces the Postgres outbox repository
+func WithOutboxRepository(outboxRepository repository.IOutboxRepository) Option {
+	return func(a *App) {
+		a.outboxRepository = outboxRepository
+	}
+}
+
+// WithEventPublisher replaces the configured publisher the outbox relay delivers domain events to
+func WithEventPublisher(eventPublisher events.IEventPublisher) Option {
+	return func(a *App) {
+		a.eventPublisher = eventPublisher
+	}
+}
+
+// WithWebhookRepository replaces the Postgres webhook repository, which also queues the deliveries
+func WithWebhookRepository(webhookRepository repository.IWebhookRepository) Option {
+	return func(a *App) {
+		a.webhookRepository = webhookRepository
+	}
+}
+
+// WithTransactor replaces the Postgres transactor that makes changes and their audit entries atomic
+func WithTransactor(transactor repository.ITransactor) Option {
+	return func(a *App) {
+		a.transactor = transactor
+	}
+}
+
+// WithCustomerService replaces the customer service
+func WithCustomerService(customerService services.ICustomerService) Option {
+	return func(a *App) {
+		a.customerService = customerService
+	}
+}
+
+// WithAPIKeyRepository replaces the Postgres api key repository
+func WithAPIKeyRepository(apiKeyRepository repository.IAPIKeyRepository) Option {
+	return func(a *App) {
+		a.apiKeyRepository = apiKeyRepository
+	}
+}
+
+// WithAPIKeyService replaces the api key service, which also authenticates X-API-Key headers
+func WithAPIKeyService(apiKeyService services.IAPIKeyService) Option {
+	return func(a *App) {
+		a.apiKeyService = apiKeyService
+	}
+}
+
+// WithRateLimitStore replaces the rate limit store selected by rate_limit.store
+func WithRateLimitStore(rateLimitStore ratelimit.IStore) Option {
+	return func(a *App) {
+		a.rateLimitStore = rateLimitStore
+	}
+}
+
+// WithTokenVerifier replaces the JWT verifier built from the auth configuration
+func WithTokenVerifier(tokenVerifier auth.ITokenVerifier) Option {
+	return func(a *App) {
+		a.tokenVerifier = tokenVerifier
+	}
+}
+
+// WithPolicy replaces the authorization policy built from the configuration
+func WithPolicy(policy services.IPolicy) Option {
+	return func(a *App) {
+		a.policy = policy
+	}
+}
+
+// WithCustomerController replaces the customer controller
+func WithCustomerController(customerController controllers.ICustomerController) Option {
+	return func(a *App) {
+		a.customerController = customerController
+	}
+}
+
+// New wires the application together. Components supplied through options are used as-is,
+// everything else is built from the configuration.
+func New(cfg *config.AppConfiguration, options ...Option) (_ *App, err error) {
+	if cfg == nil {
+		return nil, errors.New("configuration is required")
+	}
+	// Settings such as a zero poll interval would only fail once a background task starts, or panic there
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%w:\n%w", config.ErrInvalid, err)
+	}
+
+	a := &App{config: cfg, logger: logger.Logger}
+	for _, option := range options {
+		option(a)
+	}
+	defer func() {
+		// A connection opened by a build step would otherwise leak
+		if err != nil {
+			if closeErr := a.closeOwnedDB(); closeErr != nil {
+				err = errors.Join(err, closeErr)
+			}
+		}
+	}()
+
+	redactor, err := httplogger.NewRedactor(cfg.Logging.Redaction)
+	if err != nil {
+		return nil, fmt.Errorf("configuring log redaction: %w", err)
+	}
+	a.redactor = redactor
+	a.bodyCapture = httplogger.NewBodyCapture(cfg.Logging.Body)
+
+	if err := a.buildPolicy(); err != nil {
+		return nil, err
+	}
+	var authMiddleware gin.HandlerFunc
+	if cfg.Auth.Enabled {
+		if a.tokenVerifier == nil {
+			if a.tokenVerifier, err = auth.NewJWTVerifier(cfg.Auth); err != nil {
+				return nil, fmt.Errorf("configuring authentication: %w", err)
+			}
+		}
+		var apiKeys auth.IAPIKeyAuthenticator
+		if cfg.Auth.APIKeys.Enabled {
+			if err := a.buildAPIKeyComponents(); err != nil {
+				return nil, err
+			}
+			apiKeys = a.apiKeyService
+		}
+		authMiddleware = authmiddleware.AuthMiddleware(a.tokenVerifier, apiKeys, a.logger)
+	}
+
+	if err := a.buildComponents(); err != nil {
+		return nil, err
+	}
+	if cfg.Audit.Checkpoints.Enabled {
+		if err := a.buildCheckpointer(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Events.Webhooks.Enabled {
+		if err := a.buildWebhooks(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Events.Relay.Enabled {
+		if err := a.buildRelay(); err != nil {
+			return nil, err
+		}
+	}
+	var rateLimitMiddleware, preAuthRateLimitMiddleware gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		if err := a.buildRateLimiter(); err != nil {
+			return nil, err
+		}
+		rateLimitMiddleware = ratelimitmiddleware.RateLimitMiddleware(a.rateLimiter, a.logger)
+		preAuthRateLimitMiddleware = ratelimitmiddleware.PreAuthRateLimitMiddleware(a.rateLimiter, a.logger)
+	}
+	a.stopReload = config.OnReload(a.reload)
+	if a.db != nil {
+		metrics.RegisterDB(a.db)
+	}
+
+	gin.SetMode(cfg.Server.Mode)
+	a.router = routes.SetupRouter(routes.RouterConfig{
+		Logger:          a.logger,
+		ServiceName:     cfg.Tracing.ServiceName,
+		AccessLogFormat: cfg.Logging.Access.Format,
+		Redactor:        a.redactor,
+		BodyCapture:     a.bodyCapture,
+		MetricsPath:     a.metricsPath(),
+		TrustedProxies:  cfg.Server.TrustedProxies,
+	})
+	routes.RegisterRoutes(a.router, routes.RoutesConfig{
+		CustomerController:         a.customerController,
+		CustomerImportController:   a.customerImportController,
+		CustomerExportController:   a.customerExportController,
+		AccountStatementController: a.accountStatementController,
+		APIKeyController:           a.apiKeyController,
+		AuditController:            a.auditController,
+		WebhookController:          a.webhookController,
+		Auth:                       authMiddleware,
+		ProtectedGroups:            cfg.Auth.ProtectedGroups,
+		PreAuthRateLimit:           preAuthRateLimitMiddleware,
+		RateLimit:                  rateLimitMiddleware,
+	})
+
+	a.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler: a.router,
+	}
+
+	return a, nil
+}
+
+// buildComponents creates every component that was not provided through an option.
+// The database is only opened when a component built here actually needs it.
+func (a *App) buildComponents() error {
+	if a.customerController == nil {
+		if a.customerService == nil {
+			if err := a.buildRepositories(); err != nil {
+				return err
+			}
+			customerService := services.NewCustomerService(a.customerRepository, a.accountRepository, a.auditRepository, a.outboxRepository, a.transactor, a.policy)
+			if a.config.Cache.Customers.Enabled {
+				customerService = services.NewCachedCustomerService(customerService, a.policy, a.config.Cache.Customers)
+			}
+			a.customerService = services.NewTracedCustomerService(customerService)
+		}
+		a.customerController = controllers.NewCustomerController(a.customerService)
+	}
+	// An import creates customers in bulk. Without authentication the allow-all policy would let anyone do so,
+	// recorded only as the system actor, so the endpoint is only served with it.
+	if a.customerImportController == nil && a.config.Auth.Enabled {
+		if err := a.buildRepositories(); err != nil {
+			return err
+		}
+		a.customerImportController = controllers.NewCustomerImportController(services.NewTracedCustomerImportService(
+			services.NewCustomerImportService(a.customerRepository, a.auditRepository, a.outboxRepository, a.transactor, a.policy, a.config.Imports),
+		))
+	}
+	// An export streams every customer and their accounts in one response. Without authentication the allow-all
+	// policy would let anyone download them, so the endpoint is only served with it.
+	if a.customerExportController == nil && a.config.Auth.Enabled {
+		if err := a.buildRepositories(); err != nil {
+			return err
+		}
+		a.customerExportController = controllers.NewCustomerExportController(services.NewTracedCustomerExportService(
+			services.NewCustomerExportService(a.customerRepository, a.policy),
+		))
+	}
+	if a.accountStatementController == nil {
+		if err := a.buildRepositories(); err != nil {
+			return err
+		}
+		statementService, err := services.NewAccountStatementService(a.accountRepository, a.customerRepository, a.policy, a.config.Statements)
+		if err != nil {
+			return err
+		}
+		renderers, err := statements.NewRenderers(a.config.Statements.Branding)
+		if err != nil {
+			return fmt.Errorf("configuring statements: %w", err)
+		}
+		a.accountStatementController = controllers.NewAccountStatementController(services.NewTracedAccountStatementService(statementService), renderers)
+	}
+
+	// The audit log holds before and after snapshots of every customer. Without authentication the allow-all policy
+	// would hand them to anyone, so the endpoint is only served with it.
+	if a.auditController == nil && a.config.Auth.Enabled {
+		if err := a.buildRepositories(); err != nil {
+			return err
+		}
+		a.auditController = controllers.NewAuditController(
+			services.NewTracedAuditService(services.NewAuditService(a.auditRepository, a.policy)),
+		)
+	}
+
+	return nil
+}
+
+// buildAPIKeyComponents creates the api key service and its administration controller.
+// Keys are only managed when authentication is enabled, otherwise the admin endpoints would be public.
+func (a *App) buildAPIKeyComponents() error {
+	if a.apiKeyService == nil {
+		if err := a.buildRepositories(); err != nil {
+			return err
+		}
+		if a.apiKeyRepository == nil {
+			db, err := a.database()
+			if err != nil {
+				return err
+			}
+			a.apiKeyRepository = repository.NewAPIKeyRepository(db, a.logger)
+		}
+		a.apiKeyService = services.NewTracedAPIKeyService(
+			services.NewAPIKeyService(a.apiKeyRepository, a.auditRepository, a.transactor, a.policy, a.config.Auth.APIKeys.RotationGrace),
+		)
+	}
+	a.apiKeyController = controllers.NewAPIKeyController(a.apiKeyService)
+
+	return nil
+}
+
+// buildCheckpointer creates the writer of signed audit checkpoints
+func (a *App) buildCheckpointer() error {
+	if err := a.buildRepositories(); err != nil {
+		return err
+	}
+	key, err := audit.LoadPrivateKey(a.config.Audit.Checkpoints.SigningKeyFile)
+	if err != nil {
+		return fmt.Errorf("loading audit checkpoint signing key: %w", err)
+	}
+	a.checkpointer = audit.NewCheckpointer(a.auditRepository, key, a.config.Audit.Checkpoints.Path, a.logger)
+
+	return nil
+}
+
+// buildWebhooks creates the delivery worker and, when authentication is enabled, the webhook subscription endpoints
+func (a *App) buildWebhooks() error {
+	if err := a.buildRepositories(); err != nil {
+		return err
+	}
+	if a.webhookRepository == nil {
+		db, err := a.database()
+		if err != nil {
+			return err
+		}
+		a.webhookRepository = repository.NewWebhookRepository(db, a.logger)
+	}
+	// A subscription sends signed customer events to a URL of the subscriber's choosing. Without authentication the
+	// allow-all policy would let anyone subscribe, so the endpoints are only served with it.
+	if a.config.Auth.Enabled {
+		a.webhookController = controllers.NewWebhookController(services.NewTracedWebhookService(
+			services.NewWebhookService(a.webhookRepository, a.auditRepository, a.transactor, a.policy, a.config.Events.Webhooks.AllowHTTP),
+		))
+	}
+	a.dispatcher = webhooks.NewDispatcher(a.webhookRepository, a.config.Events.Webhooks, a.logger)
+
+	return nil
+}
+
+// buildRelay creates the outbox relay and the configured publisher unless one was provided through an option.
+// With webhooks enabled the relay also queues every event for the webhook subscriptions.
+func (a *App) buildRelay() error {
+	if err := a.buildRepositories(); err != nil {
+		return err
+	}
+	if a.eventPublisher == nil {
+		a.eventPublisher = events.NewLogPublisher(a.logger)
+	}
+	if a.webhookRepository != nil && a.config.Events.Webhooks.Enabled {
+		a.eventPublisher = events.NewFanoutPublisher(a.eventPublisher, webhooks.NewPublisher(a.webhookRepository))
+	}
+	a.relay = events.NewRelay(a.outboxRepository, a.eventPublisher, a.config.Events.Relay, a.logger)
+
+	return nil
+}
+
+// buildRateLimiter creates the rate limiter on the configured store unless one was provided through an option
+func (a *App) buildRateLimiter() error {
+	if a.rateLimitStore == nil {
+		switch strings.ToLower(a.config.RateLimit.Store) {
+		case "postgres":
+			db, err := a.database()
+			if err != nil {
+				return err
+			}
+			a.rateLimitStore = repository.NewRateLimitRepository(db, a.logger)
+		default:
+			a.rateLimitStore = ratelimit.NewMemoryStore()
+		}
+	}
+	a.rateLimiter = ratelimit.NewLimiter(a.config.RateLimit, a.rateLimitStore)
+
+	return nil
+}
+
+// buildPolicy creates the authorization policy unless one was provided through an option.
+// Without authentication there is no principal to authorize, so every call is allowed.
+func (a *App) buildPolicy() error {
+	if a.policy != nil {
+		return nil
+	}
+
+	if !a.config.Auth.Enabled {
+		a.logger.Warn("Authentication is disabled, authorization policies are not enforced and endpoints that need a caller are not served")
+		a.policy = services.NewAllowAllPolicy()
+		return nil
+	}
+
+	rolePolicy, err := services.NewRolePolicy(a.config.Authorization)
+	if err != nil {
+		return fmt.Errorf("configuring authorization: %w", err)
+	}
+	a.policy, a.rolePolicy = rolePolicy, rolePolicy
+	return nil
+}
+
+// buildRepositories creates the Postgres repositories and transactor that were not provided through an option
+func (a *App) buildRepositories() error {
+	if a.customerRepository != nil && a.accountRepository != nil && a.auditRepository != nil && a.outboxRepository != nil && a.transactor != nil {
+		return nil
+	}
+
+	db, err := a.database()
+	if err != nil {
+		return err
+	}
+
+	if a.customerRepository == nil {
+		a.customerRepository = repository.NewCustomerRepository(db, a.logger)
+	}
+	if a.accountRepository == nil {
+		a.accountRepository = repository.NewAccountRepository(db, a.logger)
+	}
+	if a.auditRepository == nil {
+		a.auditRepository = repository.NewAuditRepository(db, a.logger)
+	}
+	if a.outboxRepository == nil {
+		a.outboxRepository = repository.NewOutboxRepository(db, a.logger)
+	}
+	if a.transactor == nil {
+		a.transactor = repository.NewTransactor(db, a.logger)
+	}
+
+	return nil
+}
+
+// database returns the configured connection, opening it on first use unless one was provided with WithDB
+func (a *App) database() (*sql.DB, error) {
+	if a.db != nil {
+		return a.db, nil
+	}
+
+	db, err := database.Connect(context.Background(), a.config.Database)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+	a.logger.Info("Connected to database successfully")
+
+	a.db = db
+	a.ownsDB = true
+	return db, nil
+}
+
+// metricsPath returns the path of the metrics endpoint, empty when metrics are disabled
+func (a *App) metricsPath() string {
+	if !a.config.Metrics.Enabled {
+		return ""
+	}
+	return a.config.Metrics.Path
+}
+
+// reload applies the runtime-tunable settings of a reloaded configuration
+func (a *App) reload(cfg *config.AppConfiguration) error {
+	// Settings that can be refused are applied first, so nothing else changes when they are
+	if err := a.redactor.Update(cfg.Logging.Redaction); err != nil {
+		return fmt.Errorf("updating log redaction: %w", err)
+	}
+	if a.rolePolicy != nil {
+		if err := a.rolePolicy.Update(cfg.Authorization); err != nil {
+			return fmt.Errorf("updating authorization policies: %w", err)
+		}
+	}
+	a.bodyCapture.Update(cfg.Logging.Body)
+	if a.rateLimiter != nil {
+		a.rateLimiter.Update(cfg.RateLimit)
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler serving the API, useful for in-process tests
+func (a *App) Handler() http.Handler {
+	return a.router
+}
+
+// Start serves HTTP requests until ctx is cancelled or the server fails.
+// Call Stop afterwards to shut down gracefully.
+func (a *App) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", a.server.Addr, err)
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- a.server.Serve(listener)
+	}()
+	a.logger.Info("HTTP server started", slog.String("address", listener.Addr().String()))
+
+	// Background tasks also stop when Stop is called after the server failed
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	a.stopBackground = stopBackground
+	if a.checkpointer != nil {
+		a.runInBackground(func() { a.checkpointer.Run(backgroundCtx, a.config.Audit.Checkpoints.Interval) })
+	}
+	if a.relay != nil {
+		a.runInBackground(func() { a.relay.Run(backgroundCtx) })
+	}
+	if a.dispatcher != nil {
+		a.runInBackground(func() { a.dispatcher.Run(backgroundCtx) })
+	}
+
+	select {
+	case err := <-serverErrors:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// runInBackground runs task in a goroutine Stop waits for
+func (a *App) runInBackground(task func()) {
+	a.backgroundTasks.Add(1)
+	go func() {
+		defer a.backgroundTasks.Done()
+		task()
+	}()
+}
+
+// Stop drains in-flight requests, waits for the background tasks and releases resources owned by the application
+func (a *App) Stop(ctx context.Context) error {
+	var errs []error
+
+	if a.stopReload != nil {
+		a.stopReload()
+	}
+
+	if err := a.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shutting down HTTP server: %w", err))
+	}
+
+	if a.stopBackground != nil {
+		a.stopBackground()
+	}
+	stopped := make(chan struct{})
+	go func() {
+		a.backgroundTasks.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		// The tasks still use the database, keep it open
+		return errors.Join(append(errs, fmt.Errorf("waiting for background tasks: %w", ctx.Err()))...)
+	}
+
+	if err := a.closeOwnedDB(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// closeOwnedDB closes the database connection if it was opened by New rather than supplied through WithDB
+func (a *App) closeOwnedDB() error {
+	if !a.ownsDB || a.db == nil {
+		return nil
+	}
+
+	if err := a.db.Close(); err != nil {
+		return fmt.Errorf("closing database connection: %w", err)
+	}
+	a.logger.Info("Database connection closed")
+	return nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"org/gg/banking/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns the smallest configuration that passes Validate
+func validConfig() *config.AppConfiguration {
+	cfg := &config.AppConfiguration{}
+	cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.DBName = "localhost", 5432, "banking", "banking"
+	cfg.Server.Port, cfg.Server.LoggLevel = 8080, "info"
+	cfg.Imports.BatchSize, cfg.Imports.MaxRows = 100, 1000
+	cfg.Statements.MaxDays, cfg.Statements.Branding.Color = 366, "#1F4E79"
+	return cfg
+}
+
+func TestNewRejectsInvalidConfiguration(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("validConfig() is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.AppConfiguration)
+		want   string
+	}{
+		{
+			name: "relay without poll interval",
+			modify: func(cfg *config.AppConfiguration) {
+				cfg.Events.Relay.Enabled, cfg.Events.Relay.Publisher = true, "log"
+				cfg.Events.Relay.Lease, cfg.Events.Relay.RetryInitial, cfg.Events.Relay.RetryMax = time.Second, time.Second, time.Second
+				cfg.Events.Relay.BatchSize = 10
+			},
+			want: "events.relay.poll_interval",
+		},
+		{
+			name: "webhooks without the relay",
+			modify: func(cfg *config.AppConfiguration) {
+				cfg.Events.Webhooks.Enabled = true
+			},
+			want: "events.webhooks requires events.relay.enabled",
+		},
+		{
+			name:   "missing database",
+			modify: func(cfg *config.AppConfiguration) { cfg.Database.Host = "" },
+			want:   "database.host is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			_, err := New(cfg)
+			if !errors.Is(err, config.ErrInvalid) {
+				t.Fatalf("New() error = %v, want %v", err, config.ErrInvalid)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"org/gg/banking/internal/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GenesisHash is the previous hash of the first chained entry
+var GenesisHash = strings.Repeat("0", 64)
+
+// Hash returns the hex SHA-256 over the content of entry and its PrevHash.
+// Snapshots are hashed in a canonical form because Postgres stores them as JSONB, which does not keep
+// the original key order or whitespace. The id is not hashed, the order is fixed by the previous hash.
+func Hash(entry models.AuditEntry) (string, error) {
+	before, err := canonicalJSON(entry.Before)
+	if err != nil {
+		return "", fmt.Errorf("before snapshot: %w", err)
+	}
+	after, err := canonicalJSON(entry.After)
+	if err != nil {
+		return "", fmt.Errorf("after snapshot: %w", err)
+	}
+
+	// A JSON array keeps the fields apart, no separator could be forged inside a value
+	content, err := json.Marshal([]any{
+		entry.PrevHash,
+		entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+		entry.Actor,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		before,
+		after,
+		entry.RequestID,
+		entry.ClientIP,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON decodes a snapshot so it encodes again with sorted keys and without whitespace
+func canonicalJSON(snapshot json.RawMessage) (any, error) {
+	if len(snapshot) == 0 {
+		return nil, nil
+	}
+	var value any
+	if err := json.Unmarshal(snapshot, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// BrokenLink is the first entry that does not verify
+type BrokenLink struct {
+	EntryID int64  `json:"entry_id"`
+	Reason  string `json:"reason"`
+}
+
+// Report summarizes a verification
+type Report struct {
+	// Unchained counts the entries recorded before the chain was introduced
+	Unchained int `json:"unchained"`
+	// Entries counts the chained entries that verified
+	Entries  int    `json:"entries"`
+	LastID   int64  `json:"last_id"`
+	LastHash string `json:"last_hash"`
+	// Checkpoints counts the signed checkpoints matched by the chain
+	Checkpoints int         `json:"checkpoints"`
+	Broken      *BrokenLink `json:"broken,omitempty"`
+}
+
+// Verifier checks entries in id order and records the first broken link
+type Verifier struct {
+	report Report
+	// checkpoints maps an entry id to the hashes signed for it that were not reached yet
+	checkpoints map[int64][]string
+}
+
+// NewVerifier creates a verifier that also requires the chain to match the given checkpoints, whose
+// signatures must already be verified. Checkpoints let the chain be trusted even against someone able
+// to rewrite the whole table and recompute every hash.
+func NewVerifier(checkpoints []Checkpoint) *Verifier {
+	v := &Verifier{checkpoints: make(map[int64][]string, len(checkpoints))}
+	for _, checkpoint := range checkpoints {
+		v.checkpoints[checkpoint.EntryID] = append(v.checkpoints[checkpoint.EntryID], checkpoint.Hash)
+	}
+	return v
+}
+
+// Check verifies the next entry and returns false once the chain is broken
+func (v *Verifier) Check(entry models.AuditEntry) bool {
+	if v.report.Broken != nil {
+		return false
+	}
+
+	if entry.Hash == "" {
+		if v.report.Entries == 0 {
+			v.report.Unchained++
+			return true
+		}
+		return v.broken(entry.ID, "entry has no hash")
+	}
+
+	previous := GenesisHash
+	if v.report.Entries > 0 {
+		previous = v.report.LastHash
+	}
+	if entry.PrevHash != previous {
+		if v.report.Entries == 0 {
+			return v.broken(entry.ID, "first chained entry does not start from the genesis hash")
+		}
+		return v.broken(entry.ID, fmt.Sprintf("previous hash does not match entry %d, an entry was removed, inserted or reordered", v.report.LastID))
+	}
+
+	hash, err := Hash(entry)
+	if err != nil {
+		return v.broken(entry.ID, fmt.Sprintf("content cannot be hashed: %v", err))
+	}
+	if hash != entry.Hash {
+		return v.broken(entry.ID, "content does not match its hash, the entry was altered")
+	}
+
+	if signed, ok := v.checkpoints[entry.ID]; ok {
+		for _, signedHash := range signed {
+			if signedHash != entry.Hash {
+				return v.broken(entry.ID, "hash differs from a signed checkpoint, the chain was rewritten")
+			}
+		}
+		v.report.Checkpoints += len(signed)
+		delete(v.checkpoints, entry.ID)
+	}
+
+	v.report.Entries++
+	v.report.LastID, v.report.LastHash = entry.ID, entry.Hash
+	return true
+}
+
+// Report finishes the verification. Checkpoints that were never reached mean signed entries went missing.
+func (v *Verifier) Report() Report {
+	if v.report.Broken == nil && len(v.checkpoints) > 0 {
+		missing := slices.Min(slices.Collect(maps.Keys(v.checkpoints)))
+		v.broken(missing, "entry covered by a signed checkpoint is missing, the log was truncated or rewritten")
+	}
+	return v.report
+}
+
+func (v *Verifier) broken(entryID int64, reason string) bool {
+	v.report.Broken = &BrokenLink{EntryID: entryID, Reason: reason}
+	return false
+}
@@ -0,0 +1,275 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"org/gg/banking/internal/models"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chain builds n hashed entries with ids starting at firstID, linked from the genesis hash
+func chain(t *testing.T, firstID int64, n int) []models.AuditEntry {
+	t.Helper()
+	entries := make([]models.AuditEntry, n)
+	previous := GenesisHash
+	for i := range entries {
+		entry := models.AuditEntry{
+			ID:         firstID + int64(i),
+			OccurredAt: time.Date(2026, 1, 1, 12, 0, i, 0, time.UTC),
+			Actor:      "admin@example.com",
+			Action:     models.AuditActionUpdate,
+			EntityType: models.AuditEntityCustomer,
+			EntityID:   "42",
+			Before:     json.RawMessage(`{"phone":"555-1234","email":"john.doe@example.com"}`),
+			After:      json.RawMessage(`{"phone":"555-0000","email":"john.doe@example.com"}`),
+			RequestID:  "req-1",
+			ClientIP:   "192.0.2.1",
+			PrevHash:   previous,
+		}
+		hash, err := Hash(entry)
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		entry.Hash = hash
+		entries[i] = entry
+		previous = hash
+	}
+	return entries
+}
+
+func TestHashIsCanonical(t *testing.T) {
+	entry := chain(t, 1, 1)[0]
+
+	reordered := entry
+	// Postgres returns JSONB with its own key order and spacing
+	reordered.Before = json.RawMessage(`{ "email": "john.doe@example.com", "phone": "555-1234" }`)
+	reordered.OccurredAt = entry.OccurredAt.In(time.FixedZone("EET", 2*60*60))
+	if hash, err := Hash(reordered); err != nil || hash != entry.Hash {
+		t.Errorf("Hash of the same content in another form = %s, %v, want %s", hash, err, entry.Hash)
+	}
+
+	changed := entry
+	changed.Actor = "someone@example.com"
+	if hash, _ := Hash(changed); hash == entry.Hash {
+		t.Error("Hash did not change with the actor")
+	}
+
+	invalid := entry
+	invalid.After = json.RawMessage(`{"phone":`)
+	if _, err := Hash(invalid); err == nil {
+		t.Error("Hash of an invalid snapshot succeeded")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	unchained := func(id int64) models.AuditEntry {
+		return models.AuditEntry{ID: id, Actor: "system", Action: models.AuditActionCreate}
+	}
+
+	tests := []struct {
+		name          string
+		entries       func() []models.AuditEntry
+		wantEntries   int
+		wantUnchained int
+		wantBrokenID  int64
+		wantReason    string
+	}{
+		{
+			name:        "intact",
+			entries:     func() []models.AuditEntry { return chain(t, 1, 5) },
+			wantEntries: 5,
+		},
+		{
+			name:        "empty",
+			entries:     func() []models.AuditEntry { return nil },
+			wantEntries: 0,
+		},
+		{
+			name: "entries recorded before the chain",
+			entries: func() []models.AuditEntry {
+				return append([]models.AuditEntry{unchained(1), unchained(2)}, chain(t, 3, 3)...)
+			},
+			wantEntries:   3,
+			wantUnchained: 2,
+		},
+		{
+			name: "unchained entry after the chain started",
+			entries: func() []models.AuditEntry {
+				return append(chain(t, 1, 2), unchained(3))
+			},
+			wantBrokenID: 3,
+			wantReason:   "entry has no hash",
+		},
+		{
+			name: "altered content",
+			entries: func() []models.AuditEntry {
+				entries := chain(t, 1, 4)
+				entries[2].After = json.RawMessage(`{"phone":"555-9999","email":"john.doe@example.com"}`)
+				return entries
+			},
+			wantBrokenID: 3,
+			wantReason:   "content does not match its hash",
+		},
+		{
+			name: "removed entry",
+			entries: func() []models.AuditEntry {
+				entries := chain(t, 1, 4)
+				return append(entries[:1], entries[2:]...)
+			},
+			wantBrokenID: 3,
+			wantReason:   "previous hash does not match entry 1",
+		},
+		{
+			name: "reordered entries",
+			entries: func() []models.AuditEntry {
+				entries := chain(t, 1, 4)
+				entries[1], entries[2] = entries[2], entries[1]
+				return entries
+			},
+			wantBrokenID: 3,
+			wantReason:   "previous hash does not match entry 1",
+		},
+		{
+			name: "chain not starting at genesis",
+			entries: func() []models.AuditEntry {
+				return chain(t, 1, 3)[1:]
+			},
+			wantBrokenID: 2,
+			wantReason:   "first chained entry does not start from the genesis hash",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(nil)
+			for _, entry := range tt.entries() {
+				if !verifier.Check(entry) {
+					break
+				}
+			}
+			report := verifier.Report()
+
+			if tt.wantReason == "" {
+				if report.Broken != nil {
+					t.Fatalf("chain broken at %d: %s", report.Broken.EntryID, report.Broken.Reason)
+				}
+				if report.Entries != tt.wantEntries || report.Unchained != tt.wantUnchained {
+					t.Errorf("report = %+v, want %d entries and %d unchained", report, tt.wantEntries, tt.wantUnchained)
+				}
+				return
+			}
+			if report.Broken == nil {
+				t.Fatalf("report = %+v, want the chain broken at %d", report, tt.wantBrokenID)
+			}
+			if report.Broken.EntryID != tt.wantBrokenID || !strings.Contains(report.Broken.Reason, tt.wantReason) {
+				t.Errorf("broken at %d: %s, want %d: %s", report.Broken.EntryID, report.Broken.Reason, tt.wantBrokenID, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestVerifierCheckpoints(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	entries := chain(t, 1, 4)
+	// The whole table rewritten with valid hashes, by someone without the signing key
+	rewritten := chain(t, 1, 4)
+	rewritten[0].Actor = "intruder"
+	previous := GenesisHash
+	for i := range rewritten {
+		rewritten[i].PrevHash = previous
+		rewritten[i].Hash, _ = Hash(rewritten[i])
+		previous = rewritten[i].Hash
+	}
+
+	tests := []struct {
+		name            string
+		entries         []models.AuditEntry
+		checkpoints     []Checkpoint
+		wantCheckpoints int
+		wantBrokenID    int64
+		wantReason      string
+	}{
+		{
+			name:            "matching checkpoints",
+			entries:         entries,
+			checkpoints:     []Checkpoint{Sign(privateKey, entries[1], time.Now()), Sign(privateKey, entries[3], time.Now())},
+			wantCheckpoints: 2,
+		},
+		{
+			name:         "rewritten chain",
+			entries:      rewritten,
+			checkpoints:  []Checkpoint{Sign(privateKey, entries[1], time.Now())},
+			wantBrokenID: 2,
+			wantReason:   "differs from a signed checkpoint",
+		},
+		{
+			name:         "truncated chain",
+			entries:      entries[:2],
+			checkpoints:  []Checkpoint{Sign(privateKey, entries[3], time.Now())},
+			wantBrokenID: 4,
+			wantReason:   "covered by a signed checkpoint is missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checkpoints.jsonl")
+			for _, checkpoint := range tt.checkpoints {
+				if err := AppendCheckpoint(path, checkpoint); err != nil {
+					t.Fatalf("AppendCheckpoint: %v", err)
+				}
+			}
+			checkpoints, err := ReadCheckpoints(path, publicKey)
+			if err != nil {
+				t.Fatalf("ReadCheckpoints: %v", err)
+			}
+
+			verifier := NewVerifier(checkpoints)
+			for _, entry := range tt.entries {
+				if !verifier.Check(entry) {
+					break
+				}
+			}
+			report := verifier.Report()
+
+			if tt.wantReason == "" {
+				if report.Broken != nil || report.Checkpoints != tt.wantCheckpoints {
+					t.Errorf("report = %+v, want %d checkpoints matched", report, tt.wantCheckpoints)
+				}
+				return
+			}
+			if report.Broken == nil || report.Broken.EntryID != tt.wantBrokenID || !strings.Contains(report.Broken.Reason, tt.wantReason) {
+				t.Errorf("report = %+v, want broken at %d: %s", report, tt.wantBrokenID, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestReadCheckpointsRejectsForeignSignatures(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoints.jsonl")
+	if err := AppendCheckpoint(path, Sign(otherKey, chain(t, 1, 1)[0], time.Now())); err != nil {
+		t.Fatalf("AppendCheckpoint: %v", err)
+	}
+	if _, err := ReadCheckpoints(path, publicKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("ReadCheckpoints error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	missing, err := ReadCheckpoints(filepath.Join(t.TempDir(), "missing.jsonl"), publicKey)
+	if err != nil || len(missing) != 0 {
+		t.Errorf("ReadCheckpoints of a missing file = %v, %v, want none", missing, err)
+	}
+}
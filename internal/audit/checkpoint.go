@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/models"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrInvalidSignature is returned for checkpoints not signed by the expected key
+var ErrInvalidSignature = errors.New("invalid checkpoint signature")
+
+// Checkpoint is a signed statement of the chain head at a point in time. Kept outside the database,
+// checkpoints prove that the entries up to EntryID have not been rewritten since.
+type Checkpoint struct {
+	EntryID   int64     `json:"entry_id"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	// Signature is the base64 Ed25519 signature over the other fields
+	Signature string `json:"signature"`
+}
+
+// payload is the signed content of the checkpoint
+func (c Checkpoint) payload() []byte {
+	return fmt.Appendf(nil, "banking-audit-checkpoint\n%d\n%s\n%s", c.EntryID, c.Hash, c.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// Sign returns a checkpoint of the chain head signed with key
+func Sign(key ed25519.PrivateKey, head models.AuditEntry, createdAt time.Time) Checkpoint {
+	checkpoint := Checkpoint{
+		EntryID:   head.ID,
+		Hash:      head.Hash,
+		CreatedAt: createdAt.UTC(),
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, checkpoint.payload()))
+	return checkpoint
+}
+
+// Verify reports whether the checkpoint was signed by the private half of key
+func (c Checkpoint) Verify(key ed25519.PublicKey) bool {
+	signature, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, c.payload(), signature)
+}
+
+// LoadPrivateKey reads a PKCS #8 PEM Ed25519 key, as written by openssl genpkey -algorithm ed25519
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads a PKIX PEM Ed25519 public key, as written by openssl pkey -pubout.
+// A private key is accepted too, its public half is returned.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == "PRIVATE KEY" {
+		privateKey, err := LoadPrivateKey(path)
+		if err != nil {
+			return nil, err
+		}
+		return privateKey.Public().(ed25519.PublicKey), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 public key", path)
+	}
+	return publicKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+	return block, nil
+}
+
+// AppendCheckpoint adds a checkpoint as one JSON line to the file at path, creating it if needed
+func AppendCheckpoint(path string, checkpoint Checkpoint) error {
+	line, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ReadCheckpoints returns the checkpoints in the file at path, which must all be signed by key.
+// A missing file has no checkpoints.
+func ReadCheckpoints(path string, key ed25519.PublicKey) ([]Checkpoint, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var checkpoints []Checkpoint
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var checkpoint Checkpoint
+		if err := json.Unmarshal(scanner.Bytes(), &checkpoint); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		if !checkpoint.Verify(key) {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, ErrInvalidSignature)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return checkpoints, nil
+}
+
+// IChainStore reads the chain head, implemented by the audit repository
+type IChainStore interface {
+	// Head returns the newest chained entry, or an entry with a zero ID when nothing is chained yet
+	Head(ctx context.Context) (models.AuditEntry, error)
+}
+
+// Checkpointer signs the chain head and appends it to a checkpoint file
+type Checkpointer struct {
+	store  IChainStore
+	key    ed25519.PrivateKey
+	path   string
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// lastID is the entry of the last checkpoint written, periodic runs skip an unchanged head
+	lastID int64
+}
+
+func NewCheckpointer(store IChainStore, key ed25519.PrivateKey, path string, logger *slog.Logger) *Checkpointer {
+	return &Checkpointer{
+		store:  store,
+		key:    key,
+		path:   path,
+		logger: logger,
+	}
+}
+
+// Checkpoint signs the current head and appends it to the file. It returns false without writing
+// when nothing is chained yet.
+func (c *Checkpointer) Checkpoint(ctx context.Context) (Checkpoint, bool, error) {
+	return c.write(ctx, false)
+}
+
+// Run writes a checkpoint every interval while the head moves, until ctx is cancelled
+func (c *Checkpointer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkpoint, written, err := c.write(ctx, true)
+			if err != nil {
+				c.logger.ErrorContext(ctx, "Failed to write audit checkpoint", slog.Any("error", err))
+				continue
+			}
+			if written {
+				c.logger.InfoContext(ctx, "Audit checkpoint written", slog.Int64("entry_id", checkpoint.EntryID), slog.String("path", c.path))
+			}
+		}
+	}
+}
+
+func (c *Checkpointer) write(ctx context.Context, skipUnchanged bool) (Checkpoint, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head, err := c.store.Head(ctx)
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("reading audit chain head: %w", err)
+	}
+	if head.ID == 0 || (skipUnchanged && head.ID == c.lastID) {
+		return Checkpoint{}, false, nil
+	}
+
+	checkpoint := Sign(c.key, head, time.Now())
+	if err := AppendCheckpoint(c.path, checkpoint); err != nil {
+		return Checkpoint{}, false, fmt.Errorf("writing audit checkpoint: %w", err)
+	}
+	c.lastID = head.ID
+	return checkpoint, true, nil
+}
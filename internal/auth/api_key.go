@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidAPIKey is returned for unknown, revoked and expired API keys alike
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeySubjectPrefix starts the subject of principals authenticated with an API key
+const APIKeySubjectPrefix = "api-key:"
+
+// IAPIKeyAuthenticator authenticates an API key and returns the principal it acts as
+type IAPIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (Principal, error)
+}
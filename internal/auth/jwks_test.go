@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer serves a key set that tests can replace and counts its fetches
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	document []byte
+	status   int
+	fetches  int
+}
+
+func newJWKSServer(t *testing.T, document []byte) *jwksServer {
+	t.Helper()
+	server := &jwksServer{document: document, status: http.StatusOK}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		server.fetches++
+		w.WriteHeader(server.status)
+		_, _ = w.Write(server.document)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *jwksServer) serve(status int, document []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.document = document
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// age pretends the key set was fetched d ago
+func (s *keySet) age(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetched = s.fetched.Add(-d)
+}
+
+func TestKeySetRefetchesOnUnknownKid(t *testing.T) {
+	oldKey := newTestRSAKey(t)
+	newKey := newTestRSAKey(t)
+	server := newJWKSServer(t, jwksDocument(t, rsaJWK("old", oldKey)))
+
+	keys := newKeySet("", server.URL, time.Hour)
+	ctx := context.Background()
+
+	if _, err := keys.key(ctx, "old"); err != nil {
+		t.Fatalf("key(old) error = %v", err)
+	}
+	if got := server.fetchCount(); got != 1 {
+		t.Fatalf("fetches after the first lookup = %d, want 1", got)
+	}
+
+	// The identity provider rotates its signing key
+	server.serve(http.StatusOK, jwksDocument(t, rsaJWK("new", newKey)))
+
+	// A miss right after a fetch is not refetched, so unknown kids cannot hammer the key set endpoint
+	if _, err := keys.key(ctx, "new"); err == nil {
+		t.Error("key(new) found a key before the refetch interval elapsed")
+	}
+	if got := server.fetchCount(); got != 1 {
+		t.Errorf("fetches after an early miss = %d, want 1", got)
+	}
+
+	keys.age(minRefetchInterval + time.Second)
+	key, err := keys.key(ctx, "new")
+	if err != nil {
+		t.Fatalf("key(new) after the refetch interval error = %v", err)
+	}
+	if !newKey.PublicKey.Equal(key) {
+		t.Error("key(new) returned a different key")
+	}
+	if got := server.fetchCount(); got != 2 {
+		t.Errorf("fetches after the refresh = %d, want 2", got)
+	}
+
+	// The refreshed set replaces the cached one
+	if _, err := keys.key(ctx, "old"); err == nil {
+		t.Error("key(old) still found after the key set was replaced")
+	}
+}
+
+func TestKeySetRefreshesStaleKeys(t *testing.T) {
+	rsaKey := newTestRSAKey(t)
+	server := newJWKSServer(t, jwksDocument(t, rsaJWK("key-1", rsaKey)))
+
+	keys := newKeySet("", server.URL, time.Minute)
+	ctx := context.Background()
+	if _, err := keys.key(ctx, "key-1"); err != nil {
+		t.Fatalf("key() error = %v", err)
+	}
+
+	keys.age(2 * time.Minute)
+	if _, err := keys.key(ctx, "key-1"); err != nil {
+		t.Fatalf("key() of a stale set error = %v", err)
+	}
+	if got := server.fetchCount(); got != 2 {
+		t.Errorf("fetches = %d, want 2", got)
+	}
+
+	// An unavailable endpoint keeps the cached key in service
+	server.serve(http.StatusServiceUnavailable, nil)
+	keys.age(2 * time.Minute)
+	if _, err := keys.key(ctx, "key-1"); err != nil {
+		t.Errorf("key() while the endpoint is down error = %v", err)
+	}
+	if _, err := keys.key(ctx, "key-2"); err == nil {
+		t.Error("key(key-2) found a key that was never published")
+	}
+}
+
+func TestJWTVerifierRefetchesRotatedKey(t *testing.T) {
+	oldKey := newTestRSAKey(t)
+	newKey := newTestRSAKey(t)
+	server := newJWKSServer(t, jwksDocument(t, rsaJWK("old", oldKey)))
+
+	authConfig := testAuthConfig("RS256")
+	authConfig.JWKSURL = server.URL
+	authConfig.JWKSRefresh = time.Hour
+	verifier, err := NewJWTVerifier(authConfig)
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+
+	server.serve(http.StatusOK, jwksDocument(t, rsaJWK("old", oldKey), rsaJWK("new", newKey)))
+	verifier.(*jwtVerifier).keys.age(minRefetchInterval + time.Second)
+
+	token := sign(t, jwt.SigningMethodRS256, "new", newKey, validClaims(nil))
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Errorf("Verify() with the rotated key error = %v", err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey := newTestRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating EC key: %v", err)
+	}
+	ecJWK := jsonWebKey{
+		Kty: "EC",
+		Kid: "ec",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+		Y:   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+	}
+	encryptionKey := rsaJWK("enc", rsaKey)
+	encryptionKey.Use = "enc"
+	weakExponent := rsaJWK("weak", rsaKey)
+	weakExponent.E = base64.RawURLEncoding.EncodeToString([]byte{1})
+	offCurve := ecJWK
+	offCurve.Y = ecJWK.X
+	unknownCurve := ecJWK
+	unknownCurve.Crv = "P-192"
+
+	tests := []struct {
+		name     string
+		document []byte
+		wantKids []string
+		wantErr  bool
+	}{
+		{name: "rsa and ec", document: jwksDocument(t, rsaJWK("rsa", rsaKey), ecJWK), wantKids: []string{"rsa", "ec"}},
+		{name: "encryption keys skipped", document: jwksDocument(t, rsaJWK("rsa", rsaKey), encryptionKey), wantKids: []string{"rsa"}},
+		{name: "unsupported key types skipped", document: jwksDocument(t, rsaJWK("rsa", rsaKey), jsonWebKey{Kty: "oct", Kid: "oct"}), wantKids: []string{"rsa"}},
+		{name: "only encryption keys", document: jwksDocument(t, encryptionKey), wantErr: true},
+		{name: "empty", document: []byte(`{"keys":[]}`), wantErr: true},
+		{name: "invalid json", document: []byte(`{"keys":`), wantErr: true},
+		{name: "weak exponent", document: jwksDocument(t, weakExponent), wantErr: true},
+		{name: "invalid modulus", document: jwksDocument(t, jsonWebKey{Kty: "RSA", Kid: "bad", N: "!", E: "AQAB"}), wantErr: true},
+		{name: "point off the curve", document: jwksDocument(t, offCurve), wantErr: true},
+		{name: "unknown curve", document: jwksDocument(t, unknownCurve), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseJWKS(tt.document)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseJWKS() = %v, want an error", keys)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJWKS() error = %v", err)
+			}
+			if len(keys) != len(tt.wantKids) {
+				t.Errorf("parseJWKS() returned %d keys, want %v", len(keys), tt.wantKids)
+			}
+			for _, kid := range tt.wantKids {
+				if _, ok := keys[kid]; !ok {
+					t.Errorf("parseJWKS() is missing key %q", kid)
+				}
+			}
+		})
+	}
+
+	if key, _ := parseJWKS(jwksDocument(t, rsaJWK("rsa", rsaKey))); !rsaKey.PublicKey.Equal(key["rsa"].(*rsa.PublicKey)) {
+		t.Error("parseJWKS() decoded a different RSA key")
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"org/gg/banking/internal/config"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is wrapped by every token verification failure
+var ErrInvalidToken = errors.New("invalid token")
+
+// ITokenVerifier authenticates a bearer token and returns its principal
+type ITokenVerifier interface {
+	Verify(ctx context.Context, token string) (Principal, error)
+}
+
+type jwtVerifier struct {
+	parser     *jwt.Parser
+	secret     []byte
+	keys       *keySet
+	rolesClaim string
+	emailClaim string
+}
+
+// NewJWTVerifier creates a verifier accepting HS256 tokens signed with the shared secret and RS256/ES256 tokens
+// signed with a key of the configured JWKS
+func NewJWTVerifier(authConfig config.AuthConfiguration) (ITokenVerifier, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(authConfig.Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(authConfig.ClockSkew),
+	}
+	if authConfig.Issuer != "" {
+		options = append(options, jwt.WithIssuer(authConfig.Issuer))
+	}
+	if authConfig.Audience != "" {
+		options = append(options, jwt.WithAudience(authConfig.Audience))
+	}
+
+	verifier := &jwtVerifier{
+		parser:     jwt.NewParser(options...),
+		secret:     []byte(authConfig.HMACSecret),
+		rolesClaim: authConfig.RolesClaim,
+		emailClaim: authConfig.EmailClaim,
+	}
+
+	if authConfig.JWKSFile != "" || authConfig.JWKSURL != "" {
+		verifier.keys = newKeySet(authConfig.JWKSFile, authConfig.JWKSURL, authConfig.JWKSRefresh)
+		// A broken key file is a configuration error, an unreachable key set URL is retried on use
+		if err := verifier.keys.load(context.Background()); err != nil && authConfig.JWKSURL == "" {
+			return nil, err
+		}
+	}
+
+	return verifier, nil
+}
+
+// Verify checks the signature, expiry, issuer and audience of the token and maps its claims to a principal
+func (v *jwtVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		return v.key(ctx, token)
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	principal := Principal{Subject: subject, Roles: stringList(claimValue(claims, v.rolesClaim))}
+	if email, ok := claimValue(claims, v.emailClaim).(string); ok {
+		principal.Email = email
+	}
+	return principal, nil
+}
+
+// key selects the verification key matching the token's algorithm
+func (v *jwtVerifier) key(ctx context.Context, token *jwt.Token) (any, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(v.secret) == 0 {
+			return nil, errors.New("no shared secret configured")
+		}
+		return v.secret, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		if v.keys == nil {
+			return nil, errors.New("no JWKS configured")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.key(ctx, kid)
+	default:
+		return nil, fmt.Errorf("unsupported signing method %s", token.Method.Alg())
+	}
+}
+
+// claimValue resolves a claim by name, following dots into nested objects such as realm_access.roles
+func claimValue(claims jwt.MapClaims, name string) any {
+	var value any = map[string]any(claims)
+	for _, part := range strings.Split(name, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[part]
+	}
+	return value
+}
+
+// stringList accepts a JSON array of strings or a space separated string, as used by the scope claim
+func stringList(value any) []string {
+	switch typed := value.(type) {
+	case string:
+		return strings.Fields(typed)
+	case []any:
+		var list []string
+		for _, item := range typed {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"org/gg/banking/internal/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret-with-enough-entropy-for-hs256"
+
+// newTestRSAKey generates a fresh RSA signing key
+func newTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	return key
+}
+
+// rsaJWK encodes the public half of key as a JWKS entry
+func rsaJWK(kid string, key *rsa.PrivateKey) jsonWebKey {
+	return jsonWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func jwksDocument(t *testing.T, keys ...jsonWebKey) []byte {
+	t.Helper()
+	document, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("encoding JWKS: %v", err)
+	}
+	return document
+}
+
+func writeJWKS(t *testing.T, keys ...jsonWebKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(t, keys...), 0o600); err != nil {
+		t.Fatalf("writing JWKS: %v", err)
+	}
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+// validClaims returns claims accepted by testAuthConfig, tests override single claims
+func validClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://issuer.example.com",
+		"aud":   "banking-api",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "john.doe@example.com",
+		"realm_access": map[string]any{
+			"roles": []any{"banking:read", "banking:write"},
+		},
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func testAuthConfig(algorithms ...string) config.AuthConfiguration {
+	return config.AuthConfiguration{
+		Algorithms: algorithms,
+		HMACSecret: testSecret,
+		Issuer:     "https://issuer.example.com",
+		Audience:   "banking-api",
+		ClockSkew:  30 * time.Second,
+		RolesClaim: "realm_access.roles",
+		EmailClaim: "email",
+	}
+}
+
+func TestJWTVerifierVerify(t *testing.T) {
+	rsaKey := newTestRSAKey(t)
+	otherKey := newTestRSAKey(t)
+	jwksFile := writeJWKS(t, rsaJWK("key-1", rsaKey))
+
+	hmacOnly := testAuthConfig("HS256")
+	rsaOnly := testAuthConfig("RS256")
+	rsaOnly.JWKSFile = jwksFile
+	both := testAuthConfig("HS256", "RS256")
+	both.JWKSFile = jwksFile
+
+	tests := []struct {
+		name       string
+		authConfig config.AuthConfiguration
+		token      string
+		want       Principal
+		wantErr    bool
+	}{
+		{
+			name:       "valid HS256",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(nil)),
+			want:       Principal{Subject: "user-1", Email: "john.doe@example.com", Roles: []string{"banking:read", "banking:write"}},
+		},
+		{
+			name:       "valid RS256",
+			authConfig: rsaOnly,
+			token:      sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, validClaims(nil)),
+			want:       Principal{Subject: "user-1", Email: "john.doe@example.com", Roles: []string{"banking:read", "banking:write"}},
+		},
+		{
+			name:       "RS256 without kid matches the only key",
+			authConfig: rsaOnly,
+			token:      sign(t, jwt.SigningMethodRS256, "", rsaKey, validClaims(nil)),
+			want:       Principal{Subject: "user-1", Email: "john.doe@example.com", Roles: []string{"banking:read", "banking:write"}},
+		},
+		{
+			name:       "space separated roles",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"realm_access": map[string]any{"roles": "banking:read admin"}})),
+			want:       Principal{Subject: "user-1", Email: "john.doe@example.com", Roles: []string{"banking:read", "admin"}},
+		},
+		{
+			name:       "expired within clock skew",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()})),
+			want:       Principal{Subject: "user-1", Email: "john.doe@example.com", Roles: []string{"banking:read", "banking:write"}},
+		},
+		{
+			name:       "expired",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+			wantErr:    true,
+		},
+		{
+			name:       "without exp",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"exp": nil})),
+			wantErr:    true,
+		},
+		{
+			name:       "not yet valid",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()})),
+			wantErr:    true,
+		},
+		{
+			name:       "wrong issuer",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"iss": "https://evil.example.com"})),
+			wantErr:    true,
+		},
+		{
+			name:       "wrong audience",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"aud": "other-api"})),
+			wantErr:    true,
+		},
+		{
+			name:       "audience list containing ours",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"aud": []string{"other-api", "banking-api"}})),
+			want:       Principal{Subject: "user-1", Email: "john.doe@example.com", Roles: []string{"banking:read", "banking:write"}},
+		},
+		{
+			name:       "without sub",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(jwt.MapClaims{"sub": nil})),
+			wantErr:    true,
+		},
+		{
+			name:       "wrong secret",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte("another-secret"), validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "HS256 when only RS256 is configured",
+			authConfig: rsaOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "", []byte(testSecret), validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "HS256 signed with the RSA public key",
+			authConfig: rsaOnly,
+			token:      sign(t, jwt.SigningMethodHS256, "key-1", rsaKey.PublicKey.N.Bytes(), validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "RS256 when only HS256 is configured",
+			authConfig: hmacOnly,
+			token:      sign(t, jwt.SigningMethodRS256, "key-1", rsaKey, validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "RS384 not configured",
+			authConfig: both,
+			token:      sign(t, jwt.SigningMethodRS384, "key-1", rsaKey, validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "alg none",
+			authConfig: both,
+			token:      sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "RS256 signed by an unknown key",
+			authConfig: rsaOnly,
+			token:      sign(t, jwt.SigningMethodRS256, "key-1", otherKey, validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "RS256 with an unknown kid",
+			authConfig: rsaOnly,
+			token:      sign(t, jwt.SigningMethodRS256, "key-2", rsaKey, validClaims(nil)),
+			wantErr:    true,
+		},
+		{
+			name:       "malformed",
+			authConfig: both,
+			token:      "not.a.token",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := NewJWTVerifier(tt.authConfig)
+			if err != nil {
+				t.Fatalf("NewJWTVerifier: %v", err)
+			}
+
+			got, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Verify() error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJWTVerifierRejectsBrokenKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, []byte(`{"keys":[]}`), 0o600); err != nil {
+		t.Fatalf("writing JWKS: %v", err)
+	}
+
+	authConfig := testAuthConfig("RS256")
+	authConfig.JWKSFile = path
+	if _, err := NewJWTVerifier(authConfig); err == nil {
+		t.Error("NewJWTVerifier() accepted a key file without keys")
+	}
+}
+
+func TestClaimValue(t *testing.T) {
+	claims := jwt.MapClaims{
+		"scope":        "read write",
+		"realm_access": map[string]any{"roles": []any{"admin", 1, "auditor"}},
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "scope", want: []string{"read", "write"}},
+		{name: "realm_access.roles", want: []string{"admin", "auditor"}},
+		{name: "realm_access.missing", want: nil},
+		{name: "scope.nested", want: nil},
+		{name: "missing", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stringList(claimValue(claims, tt.name)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stringList(claimValue(%s)) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// step is one operation against the cache: add, get, remove, purge or advance the clock
+type step struct {
+	op          string
+	key         string
+	value       int
+	advance     time.Duration
+	wantValue   int
+	wantOK      bool
+	wantEvicted bool
+}
+
+func TestLRU(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		ttl     time.Duration
+		steps   []step
+		wantLen int
+	}{
+		{
+			name: "hit and miss",
+			size: 2, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "get", key: "a", wantValue: 1, wantOK: true},
+				{op: "get", key: "b", wantOK: false},
+			},
+			wantLen: 1,
+		},
+		{
+			name: "evicts the least recently used",
+			size: 2, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "add", key: "b", value: 2},
+				{op: "get", key: "a", wantValue: 1, wantOK: true},
+				{op: "add", key: "c", value: 3, wantEvicted: true},
+				{op: "get", key: "b", wantOK: false},
+				{op: "get", key: "a", wantValue: 1, wantOK: true},
+				{op: "get", key: "c", wantValue: 3, wantOK: true},
+			},
+			wantLen: 2,
+		},
+		{
+			name: "replacing refreshes recency without evicting",
+			size: 2, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "add", key: "b", value: 2},
+				{op: "add", key: "a", value: 10},
+				{op: "add", key: "c", value: 3, wantEvicted: true},
+				{op: "get", key: "a", wantValue: 10, wantOK: true},
+				{op: "get", key: "b", wantOK: false},
+			},
+			wantLen: 2,
+		},
+		{
+			name: "expires after the ttl",
+			size: 2, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "advance", advance: 59 * time.Second},
+				{op: "get", key: "a", wantValue: 1, wantOK: true},
+				{op: "advance", advance: time.Second},
+				{op: "get", key: "a", wantOK: false},
+			},
+			wantLen: 0,
+		},
+		{
+			name: "hits do not extend the ttl",
+			size: 2, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "advance", advance: 30 * time.Second},
+				{op: "get", key: "a", wantValue: 1, wantOK: true},
+				{op: "advance", advance: 30 * time.Second},
+				{op: "get", key: "a", wantOK: false},
+			},
+			wantLen: 0,
+		},
+		{
+			name: "replacing renews the ttl",
+			size: 2, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "advance", advance: 45 * time.Second},
+				{op: "add", key: "a", value: 2},
+				{op: "advance", advance: 45 * time.Second},
+				{op: "get", key: "a", wantValue: 2, wantOK: true},
+			},
+			wantLen: 1,
+		},
+		{
+			name: "expired entries count until dropped",
+			size: 2, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "add", key: "b", value: 2},
+				{op: "advance", advance: time.Hour},
+				{op: "get", key: "a", wantOK: false},
+			},
+			wantLen: 1,
+		},
+		{
+			name: "remove and purge",
+			size: 3, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "add", key: "b", value: 2},
+				{op: "remove", key: "a"},
+				{op: "remove", key: "missing"},
+				{op: "get", key: "a", wantOK: false},
+				{op: "get", key: "b", wantValue: 2, wantOK: true},
+				{op: "purge"},
+				{op: "get", key: "b", wantOK: false},
+				{op: "add", key: "c", value: 3},
+			},
+			wantLen: 1,
+		},
+		{
+			name: "size below one holds one entry",
+			size: 0, ttl: time.Minute,
+			steps: []step{
+				{op: "add", key: "a", value: 1},
+				{op: "add", key: "b", value: 2, wantEvicted: true},
+				{op: "get", key: "a", wantOK: false},
+				{op: "get", key: "b", wantValue: 2, wantOK: true},
+			},
+			wantLen: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			lru := NewLRU[string, int](tt.size, tt.ttl)
+			lru.now = func() time.Time { return now }
+
+			for i, s := range tt.steps {
+				switch s.op {
+				case "add":
+					if evicted := lru.Add(s.key, s.value); evicted != s.wantEvicted {
+						t.Errorf("step %d: Add(%s) evicted = %v, want %v", i, s.key, evicted, s.wantEvicted)
+					}
+				case "get":
+					value, ok := lru.Get(s.key)
+					if ok != s.wantOK || value != s.wantValue {
+						t.Errorf("step %d: Get(%s) = %d, %v, want %d, %v", i, s.key, value, ok, s.wantValue, s.wantOK)
+					}
+				case "remove":
+					lru.Remove(s.key)
+				case "purge":
+					lru.Purge()
+				case "advance":
+					now = now.Add(s.advance)
+				default:
+					t.Fatalf("unknown op %q", s.op)
+				}
+			}
+
+			if got := lru.Len(); got != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", got, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestLRUConcurrentUse(t *testing.T) {
+	lru := NewLRU[string, int](16, time.Minute)
+
+	var wg sync.WaitGroup
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				key := fmt.Sprint((worker + i) % 32)
+				lru.Add(key, i)
+				lru.Get(key)
+				if i%10 == 0 {
+					lru.Remove(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := lru.Len(); got > 16 {
+		t.Errorf("Len() = %d, want at most 16", got)
+	}
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newAccountsCommand(options *rootOptions) *cobra.Command {
+	accounts := &cobra.Command{
+		Use:   "accounts",
+		Short: "Manage accounts",
+	}
+
+	accounts.AddCommand(newAccountsCloseCommand(options))
+
+	return accounts
+}
+
+func newAccountsCloseCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "close <account_number>",
+		Short: "Close an open account",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			accountService := newAccountService(db)
+			if err := accountService.CloseAccount(operatorContext(cmd.Context()), args[0]); err != nil {
+				return err
+			}
+
+			return options.render(cmd.OutOrStdout(), map[string]string{"closed": args[0]}, table{
+				headers: []string{"CLOSED"},
+				rows:    [][]string{{args[0]}},
+			})
+		},
+	}
+}
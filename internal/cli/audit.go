@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"org/gg/banking/internal/audit"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func newAuditCommand(options *rootOptions) *cobra.Command {
+	auditCommand := &cobra.Command{
+		Use:   "audit",
+		Short: "Verify and checkpoint the audit log hash chain",
+	}
+
+	auditCommand.AddCommand(
+		newAuditVerifyCommand(options),
+		newAuditCheckpointCommand(options),
+	)
+
+	return auditCommand
+}
+
+func newAuditVerifyCommand(options *rootOptions) *cobra.Command {
+	var checkpointsPath, publicKeyPath string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Walk the audit hash chain and report the first broken link",
+		Long: "Walk the audit hash chain and report the first broken link. The chain must also match the signed checkpoints " +
+			"when checkpoints are enabled or --checkpoints is given. Exits with code 3 when the chain is broken.",
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings := options.config.Audit.Checkpoints
+			if !cmd.Flags().Changed("checkpoints") && settings.Enabled {
+				checkpointsPath = settings.Path
+			}
+			if publicKeyPath == "" {
+				publicKeyPath = settings.PublicKeyFile
+			}
+			if publicKeyPath == "" {
+				publicKeyPath = settings.SigningKeyFile
+			}
+
+			var checkpoints []audit.Checkpoint
+			if checkpointsPath != "" {
+				if publicKeyPath == "" {
+					return usageError{err: errors.New("--public-key is required to verify checkpoints")}
+				}
+				key, err := audit.LoadPublicKey(publicKeyPath)
+				if err != nil {
+					return fmt.Errorf("loading checkpoint public key: %w", err)
+				}
+				checkpoints, err = audit.ReadCheckpoints(checkpointsPath, key)
+				if errors.Is(err, audit.ErrInvalidSignature) {
+					return checkFailedError{message: err.Error()}
+				}
+				if err != nil {
+					return fmt.Errorf("reading checkpoints: %w", err)
+				}
+			}
+
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			verifier := audit.NewVerifier(checkpoints)
+			if err := repository.NewAuditRepository(db, logger.Logger).Walk(cmd.Context(), verifier.Check); err != nil {
+				return fmt.Errorf("reading audit log: %w", err)
+			}
+			report := verifier.Report()
+
+			status := "ok"
+			if report.Broken != nil {
+				status = fmt.Sprintf("broken at entry %d: %s", report.Broken.EntryID, report.Broken.Reason)
+			}
+			if err := options.render(cmd.OutOrStdout(), report, table{
+				headers: []string{"UNCHAINED", "ENTRIES", "CHECKPOINTS", "LAST ID", "STATUS"},
+				rows: [][]string{{
+					strconv.Itoa(report.Unchained),
+					strconv.Itoa(report.Entries),
+					fmt.Sprintf("%d/%d", report.Checkpoints, len(checkpoints)),
+					strconv.FormatInt(report.LastID, 10),
+					status,
+				}},
+			}); err != nil {
+				return err
+			}
+
+			if report.Broken != nil {
+				return checkFailedError{message: fmt.Sprintf("audit chain broken at entry %d", report.Broken.EntryID)}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&checkpointsPath, "checkpoints", "", "checkpoint file to verify against, defaults to audit.checkpoints.path when checkpoints are enabled")
+	cmd.Flags().StringVar(&publicKeyPath, "public-key", "", "PEM Ed25519 key verifying the checkpoints, defaults to audit.checkpoints.public_key_file")
+
+	return cmd
+}
+
+func newAuditCheckpointCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "checkpoint",
+		Short: "Sign the current audit chain head and append it to the checkpoint file",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings := options.config.Audit.Checkpoints
+			if settings.SigningKeyFile == "" {
+				return errors.New("audit.checkpoints.signing_key_file is not configured")
+			}
+			key, err := audit.LoadPrivateKey(settings.SigningKeyFile)
+			if err != nil {
+				return fmt.Errorf("loading checkpoint signing key: %w", err)
+			}
+
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			checkpointer := audit.NewCheckpointer(repository.NewAuditRepository(db, logger.Logger), key, settings.Path, logger.Logger)
+			checkpoint, written, err := checkpointer.Checkpoint(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if !written {
+				fmt.Fprintln(cmd.ErrOrStderr(), "No chained audit entries yet, nothing to checkpoint")
+				return nil
+			}
+
+			return options.render(cmd.OutOrStdout(), checkpoint, table{
+				headers: []string{"ENTRY ID", "HASH", "CREATED AT", "FILE"},
+				rows: [][]string{{
+					strconv.FormatInt(checkpoint.EntryID, 10),
+					checkpoint.Hash,
+					checkpoint.CreatedAt.Format(time.RFC3339),
+					settings.Path,
+				}},
+			})
+		},
+	}
+}
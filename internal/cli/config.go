@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newConfigCommand(options *rootOptions) *cobra.Command {
+	configCommand := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the application configuration",
+	}
+
+	configCommand.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Validate the configuration file",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.config.Validate(); err != nil {
+				return checkFailedError{message: "invalid configuration:\n" + err.Error()}
+			}
+
+			file := viper.ConfigFileUsed()
+			return options.render(cmd.OutOrStdout(), map[string]any{"file": file, "valid": true}, table{
+				headers: []string{"FILE", "VALID"},
+				rows:    [][]string{{file, "true"}},
+			})
+		},
+	})
+
+	return configCommand
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+func newCustomersCommand(options *rootOptions) *cobra.Command {
+	customers := &cobra.Command{
+		Use:   "customers",
+		Short: "Inspect and manage customers",
+	}
+
+	customers.AddCommand(
+		newCustomersListCommand(options),
+		newCustomersShowCommand(options),
+		newCustomersDeleteCommand(options),
+	)
+
+	return customers
+}
+
+func newCustomersListCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all customers",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			customers, err := repository.NewCustomerRepository(db, logger.Logger).FindAll(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("listing customers: %w", err)
+			}
+			if customers == nil {
+				customers = []models.Customer{}
+			}
+
+			rows := make([][]string, 0, len(customers))
+			for _, customer := range customers {
+				rows = append(rows, []string{
+					strconv.FormatInt(customer.ID, 10),
+					customer.FirstName,
+					customer.LastName,
+					customer.Email,
+					customer.Phone,
+				})
+			}
+			return options.render(cmd.OutOrStdout(), customers, table{
+				headers: []string{"ID", "FIRST NAME", "LAST NAME", "EMAIL", "PHONE"},
+				rows:    rows,
+			})
+		},
+	}
+}
+
+func newCustomersShowCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <email>",
+		Short: "Show a customer together with their accounts",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			customerService := newCustomerService(db)
+			customer, err := customerService.FindCustomerWithAccounts(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			rows := make([][]string, 0, len(customer.Accounts))
+			for _, account := range customer.Accounts {
+				rows = append(rows, []string{
+					customer.Email,
+					account.AccountNumber,
+					strconv.FormatFloat(account.Balance, 'f', 2, 64),
+					account.AccountDescription,
+				})
+			}
+			return options.render(cmd.OutOrStdout(), customer, table{
+				headers: []string{"CUSTOMER", "ACCOUNT", "BALANCE", "DESCRIPTION"},
+				rows:    rows,
+			})
+		},
+	}
+}
+
+func newCustomersDeleteCommand(options *rootOptions) *cobra.Command {
+	var confirmed bool
+
+	command := &cobra.Command{
+		Use:   "delete <email>",
+		Short: "Delete a customer and all of their accounts",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !confirmed {
+				return usageError{err: errors.New("refusing to delete without --yes")}
+			}
+
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			customerService := newCustomerService(db)
+			if err := customerService.DeleteCustomerByEmail(operatorContext(cmd.Context()), args[0]); err != nil {
+				return err
+			}
+
+			return options.render(cmd.OutOrStdout(), map[string]string{"deleted": args[0]}, table{
+				headers: []string{"DELETED"},
+				rows:    [][]string{{args[0]}},
+			})
+		},
+	}
+	command.Flags().BoolVar(&confirmed, "yes", false, "confirm the deletion")
+
+	return command
+}
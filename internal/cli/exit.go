@@ -0,0 +1,80 @@
+package cli
+
+import (
+	stderrors "errors"
+	"net/http"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/repository"
+
+	"github.com/spf13/cobra"
+)
+
+// Process exit codes returned by Execute
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitUsage       = 2
+	ExitCheckFailed = 3
+	ExitNotFound    = 4
+	ExitConfig      = 5
+)
+
+// usageError marks an invalid invocation such as an unknown flag or a missing argument
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
+// checkFailedError marks a command that ran successfully but found problems, like reconcile
+type checkFailedError struct {
+	message string
+}
+
+func (e checkFailedError) Error() string {
+	return e.message
+}
+
+// exitCode maps an error returned by a command to a process exit code
+func exitCode(err error) int {
+	var usageErr usageError
+	if stderrors.As(err, &usageErr) {
+		return ExitUsage
+	}
+
+	var checkErr checkFailedError
+	if stderrors.As(err, &checkErr) {
+		return ExitCheckFailed
+	}
+
+	if stderrors.Is(err, config.ErrInvalid) {
+		return ExitConfig
+	}
+
+	var appErr errors.AppError
+	if stderrors.As(err, &appErr) && appErr.StatusCode == http.StatusNotFound {
+		return ExitNotFound
+	}
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return ExitNotFound
+	}
+
+	return ExitFailure
+}
+
+// usageArgs wraps a cobra argument validator so its failures exit with ExitUsage
+func usageArgs(validator cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validator(cmd, args); err != nil {
+			return usageError{err: err}
+		}
+		return nil
+	}
+}
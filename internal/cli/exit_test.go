@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"org/gg/banking/internal/config"
+	apperrors "org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/repository"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "failure", err: errors.New("connection refused"), want: ExitFailure},
+		{name: "usage", err: usageError{err: errors.New("unknown flag: --verbose")}, want: ExitUsage},
+		{name: "check failed", err: checkFailedError{message: "2 problems found"}, want: ExitCheckFailed},
+		{name: "not found", err: apperrors.NotFoundError("Customer not found"), want: ExitNotFound},
+		{name: "wrapped not found", err: fmt.Errorf("closing account: %w", repository.ErrNotFound), want: ExitNotFound},
+		{name: "other app error", err: apperrors.BadRequestError("Invalid email"), want: ExitFailure},
+		{name: "invalid configuration", err: fmt.Errorf("initializing application: %w: %w", config.ErrInvalid, errors.New("server.port 0 is out of range")), want: ExitConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
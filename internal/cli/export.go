@@ -0,0 +1,150 @@
+package cli
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/exports"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"org/gg/banking/internal/services"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newExportCommand(options *rootOptions) *cobra.Command {
+	var format, expand string
+	var columns []string
+	var page models.CustomerPage
+
+	cmd := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Export customers, optionally with their accounts, to a CSV, NDJSON or XLSX file",
+		Long: "Export customers to a CSV, NDJSON or XLSX file, the same formats as GET /api/v1/customers/export. " +
+			"The format follows the file extension unless --format is given, - writes to stdout. " +
+			"The file only appears once the export is complete.",
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			if format == "" {
+				format = filepath.Ext(path)
+			}
+			contentType, err := exports.ContentTypeOf(format)
+			if err != nil {
+				return usageError{err: stderrors.New("--format must be csv, ndjson or xlsx")}
+			}
+
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			destination, commit, err := exportDestination(cmd.OutOrStdout(), path)
+			if err != nil {
+				return err
+			}
+			defer destination.Close()
+
+			exportService := services.NewCustomerExportService(repository.NewCustomerRepository(db, logger.Logger), services.NewAllowAllPolicy())
+			var written *countingWriter
+			err = exportService.Export(cmd.Context(), models.CustomerExportRequest{Expand: expand, Columns: columns, Page: page}, func(columns []string) (exports.IWriter, error) {
+				writer, err := exports.NewWriter(contentType, destination, columns)
+				written = &countingWriter{IWriter: writer}
+				return written, err
+			})
+			var appErr errors.AppError
+			if stderrors.As(err, &appErr) && appErr.StatusCode == http.StatusBadRequest {
+				return usageError{err: err}
+			}
+			if err != nil {
+				return err
+			}
+			if err := commit(); err != nil {
+				return err
+			}
+
+			if path == "-" {
+				return nil
+			}
+			return options.render(cmd.ErrOrStderr(), map[string]any{"file": path, "rows": written.rows}, table{
+				headers: []string{"FILE", "ROWS"},
+				rows:    [][]string{{path, strconv.Itoa(written.rows)}},
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "csv, ndjson or xlsx, defaults to the file extension")
+	cmd.Flags().StringVar(&expand, "expand", "", "accounts exports a row per account")
+	cmd.Flags().IntVar(&page.Limit, "limit", 0, "exports at most this many customers, all of them by default")
+	cmd.Flags().IntVar(&page.Offset, "offset", 0, "skips this many customers, ordered by id")
+	cmd.Flags().StringSliceVar(&columns, "columns", nil, "columns to export in order, all of them by default: "+
+		strings.Join(models.CustomerExportColumns, ",")+" and with --expand accounts "+strings.Join(models.AccountExportColumns, ","))
+
+	return cmd
+}
+
+// exportDestination opens where an export goes. A file is written under a temporary name in the same directory and
+// commit renames it, so readers never see a partial export. Closing without commit removes it.
+func exportDestination(stdout io.Writer, path string) (destination io.WriteCloser, commit func() error, err error) {
+	if path == "-" {
+		return nopWriteCloser{stdout}, func() error { return nil }, nil
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating export file: %w", err)
+	}
+	committed := false
+	commit = func() error {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("writing export file: %w", err)
+		}
+		if err := os.Rename(file.Name(), path); err != nil {
+			return fmt.Errorf("moving export file into place: %w", err)
+		}
+		committed = true
+		return nil
+	}
+	return removeUnlessCommitted{File: file, committed: &committed}, commit, nil
+}
+
+// countingWriter counts the records written to an export
+type countingWriter struct {
+	exports.IWriter
+	rows int
+}
+
+func (w *countingWriter) Write(values []any) error {
+	w.rows++
+	return w.IWriter.Write(values)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// removeUnlessCommitted deletes a temporary export file that was not moved into place
+type removeUnlessCommitted struct {
+	*os.File
+	committed *bool
+}
+
+func (f removeUnlessCommitted) Close() error {
+	if *f.committed {
+		return nil
+	}
+	f.File.Close()
+	return os.Remove(f.File.Name())
+}
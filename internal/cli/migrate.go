@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"org/gg/banking/internal/database"
+
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending database migrations",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			applied, err := database.Migrate(cmd.Context(), db)
+			if err != nil {
+				return err
+			}
+
+			rows := make([][]string, 0, len(applied))
+			for _, version := range applied {
+				rows = append(rows, []string{version})
+			}
+			return options.render(cmd.OutOrStdout(), map[string]any{"applied": applied}, table{
+				headers: []string{"APPLIED"},
+				rows:    rows,
+			})
+		},
+	}
+}
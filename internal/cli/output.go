@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// outputFormat is the value of the --output flag
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+)
+
+func (f *outputFormat) String() string {
+	return string(*f)
+}
+
+func (f *outputFormat) Set(value string) error {
+	switch outputFormat(value) {
+	case outputTable, outputJSON:
+		*f = outputFormat(value)
+		return nil
+	default:
+		return fmt.Errorf("must be one of %s, %s", outputTable, outputJSON)
+	}
+}
+
+func (f *outputFormat) Type() string {
+	return "format"
+}
+
+// table is the tabular rendering of a command result
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+// render writes data as indented JSON or as an aligned table depending on the selected format
+func (o *rootOptions) render(w io.Writer, data any, t table) error {
+	if o.output == outputJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.headers, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
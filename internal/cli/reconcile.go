@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+
+	"github.com/spf13/cobra"
+)
+
+func newReconcileCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reconcile",
+		Short: "Check customers and accounts for inconsistencies",
+		Long:  "Check customers and accounts for inconsistencies. Exits with code 3 when any are found.",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			discrepancies, err := repository.NewReconciliationRepository(db, logger.Logger).FindDiscrepancies(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if discrepancies == nil {
+				discrepancies = []models.Discrepancy{}
+			}
+
+			rows := make([][]string, 0, len(discrepancies))
+			for _, discrepancy := range discrepancies {
+				rows = append(rows, []string{discrepancy.Check, discrepancy.EntityType, discrepancy.EntityKey, discrepancy.Detail})
+			}
+			if err := options.render(cmd.OutOrStdout(), discrepancies, table{
+				headers: []string{"CHECK", "ENTITY", "KEY", "DETAIL"},
+				rows:    rows,
+			}); err != nil {
+				return err
+			}
+
+			if len(discrepancies) > 0 {
+				return checkFailedError{message: fmt.Sprintf("found %d discrepancies", len(discrepancies))}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/database"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// rootOptions holds the state shared by every subcommand
+type rootOptions struct {
+	output outputFormat
+	config *config.AppConfiguration
+}
+
+// newRootCommand builds the banking command tree
+func newRootCommand() *cobra.Command {
+	options := &rootOptions{output: outputTable}
+
+	root := &cobra.Command{
+		Use:           "banking",
+		Short:         "Banking API server and operational tooling",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadConfig()
+			if err != nil {
+				return err
+			}
+			options.config = cfg
+
+			// Keep stdout clean for command output, only the server logs there
+			output := os.Stderr
+			if cmd.Name() == "serve" {
+				output = os.Stdout
+			}
+			return logger.InitLogger(loggerConfig(cfg, output))
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			logger.Close()
+		},
+	}
+
+	root.PersistentFlags().Var(&options.output, "output", "output format: table or json")
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError{err: err}
+	})
+
+	root.AddCommand(
+		newServeCommand(options),
+		newMigrateCommand(options),
+		newSeedCommand(options),
+		newCustomersCommand(options),
+		newAccountsCommand(options),
+		newExportCommand(options),
+		newReconcileCommand(options),
+		newAuditCommand(options),
+		newConfigCommand(options),
+	)
+
+	return root
+}
+
+// loggerConfig converts the logging section of the configuration into the logger settings
+func loggerConfig(cfg *config.AppConfiguration, output io.Writer) logger.LoggerConfig {
+	sinks := make([]logger.SinkConfig, 0, len(cfg.Logging.Sinks))
+	for _, sink := range cfg.Logging.Sinks {
+		sinks = append(sinks, logger.SinkConfig{
+			Type:           sink.Type,
+			Format:         sink.Format,
+			Level:          sink.Level,
+			Path:           sink.Path,
+			MaxSizeMB:      sink.MaxSizeMB,
+			MaxBackups:     sink.MaxBackups,
+			MaxAgeDays:     sink.MaxAgeDays,
+			RotateInterval: sink.RotateInterval,
+			Compress:       sink.Compress,
+		})
+	}
+
+	return logger.LoggerConfig{
+		Level:     cfg.Server.LoggLevel,
+		Format:    cfg.Logging.Format,
+		AddSource: cfg.Logging.AddSource,
+		Sinks:     sinks,
+		Output:    output,
+	}
+}
+
+// Execute runs the command line and returns the process exit code
+func Execute(ctx context.Context) int {
+	root := newRootCommand()
+	// Cobra reports an unknown command with a plain error, resolving the command first lets it exit with ExitUsage
+	_, _, err := root.Find(os.Args[1:])
+	if err != nil {
+		err = usageError{err: err}
+	} else {
+		err = root.ExecuteContext(ctx)
+	}
+	if err == nil {
+		return ExitOK
+	}
+
+	code := exitCode(err)
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	if code == ExitUsage {
+		fmt.Fprintln(os.Stderr, "Run 'banking --help' for usage.")
+	}
+
+	return code
+}
+
+// openDatabase connects to the configured database for commands that need it
+func (o *rootOptions) openDatabase(ctx context.Context) (*sql.DB, error) {
+	db, err := database.Connect(ctx, o.config.Database)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+
+	return db, nil
+}
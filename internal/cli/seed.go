@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+
+	"github.com/spf13/cobra"
+)
+
+// seedCustomer is a sample customer together with the accounts created for it
+type seedCustomer struct {
+	customer       models.Customer
+	accounts       []models.Account
+	closedAccounts []string
+}
+
+// seedData is the sample data set used for local development
+var seedData = []seedCustomer{
+	{
+		customer: models.Customer{FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Phone: "555-1234"},
+		accounts: []models.Account{
+			{AccountNumber: "ACC-10001", Balance: 6000.00, AccountDescription: "Checking Account"},
+			{AccountNumber: "ACC-10002", Balance: 25000.00, AccountDescription: "Savings Account"},
+		},
+	},
+	{
+		customer: models.Customer{FirstName: "Jane", LastName: "Smith", Email: "jane.smith@example.com", Phone: "555-5678"},
+		accounts: []models.Account{
+			{AccountNumber: "ACC-20001", Balance: 7500.50, AccountDescription: "Checking Account"},
+			{AccountNumber: "ACC-20002", Balance: 42000.75, AccountDescription: "Investment Account"},
+		},
+		closedAccounts: []string{"ACC-20002"},
+	},
+	{
+		customer: models.Customer{FirstName: "George", LastName: "Gkezeris", Email: "gg@gmail.com", Phone: "699999"},
+		accounts: []models.Account{
+			{AccountNumber: "ACC-30001", Balance: 1500.25, AccountDescription: "Checking Account"},
+			{AccountNumber: "ACC-30002", Balance: 15000.00, AccountDescription: "Savings Account"},
+			{AccountNumber: "ACC-30003", Balance: 50000.00, AccountDescription: "Retirement Account"},
+		},
+	},
+}
+
+func newSeedCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "seed",
+		Short: "Insert sample customers and accounts, skipping customers that already exist",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := options.openDatabase(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			customerRepository := repository.NewCustomerRepository(db, logger.Logger)
+			accountRepository := repository.NewAccountRepository(db, logger.Logger)
+			transactor := repository.NewTransactor(db, logger.Logger)
+
+			var rows [][]string
+			var created []string
+			for _, seed := range seedData {
+				var status string
+				err := transactor.WithinTx(cmd.Context(), func(ctx context.Context) (err error) {
+					status, err = seedOne(ctx, customerRepository, accountRepository, seed)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+				if status == "created" {
+					created = append(created, seed.customer.Email)
+				}
+				rows = append(rows, []string{seed.customer.Email, status})
+			}
+
+			return options.render(cmd.OutOrStdout(), map[string]any{"created": created}, table{
+				headers: []string{"CUSTOMER", "STATUS"},
+				rows:    rows,
+			})
+		},
+	}
+}
+
+// seedOne inserts a single sample customer with its accounts unless the customer already exists. It runs in a
+// transaction, so a failure leaves no customer without its accounts behind.
+func seedOne(ctx context.Context, customerRepository repository.ICustomerRepository, accountRepository repository.IAccountRepository, seed seedCustomer) (string, error) {
+	_, err := customerRepository.FindByEmail(ctx, seed.customer.Email)
+	if err == nil {
+		return "skipped", nil
+	}
+	if !stderrors.Is(err, repository.ErrNotFound) {
+		return "", err
+	}
+
+	customer, err := customerRepository.Create(ctx, seed.customer)
+	if err != nil {
+		return "", fmt.Errorf("seeding customer %s: %w", seed.customer.Email, err)
+	}
+
+	for _, account := range seed.accounts {
+		if _, err := accountRepository.CreateAccount(ctx, customer.ID, account); err != nil {
+			return "", fmt.Errorf("seeding account %s: %w", account.AccountNumber, err)
+		}
+	}
+	for _, accountNumber := range seed.closedAccounts {
+		if err := accountRepository.CloseAccount(ctx, accountNumber); err != nil {
+			return "", fmt.Errorf("closing seeded account %s: %w", accountNumber, err)
+		}
+	}
+
+	return "created", nil
+}
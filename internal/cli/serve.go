@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/app"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/tracing"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// shutdownTimeout bounds how long in-flight requests may take to finish after a stop signal
+const shutdownTimeout = 10 * time.Second
+
+func newServeCommand(options *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP API server",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shutdownTracing, err := tracing.Init(cmd.Context(), options.config.Tracing)
+			if err != nil {
+				return fmt.Errorf("initializing tracing: %w", err)
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+				defer cancel()
+				if err := shutdownTracing(ctx); err != nil {
+					logger.Logger.Error("Failed to flush traces", slog.Any("error", err))
+				}
+			}()
+
+			application, err := app.New(options.config)
+			if err != nil {
+				return fmt.Errorf("initializing application: %w", err)
+			}
+			// One-shot commands run with the configuration they started with, only the server reloads it
+			config.Watch()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			startErr := application.Start(ctx)
+			if startErr != nil {
+				logger.Logger.Error("Server stopped unexpectedly", slog.Any("error", startErr))
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := application.Stop(shutdownCtx); err != nil {
+				return fmt.Errorf("shutting down: %w", err)
+			}
+
+			return startErr
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/repository"
+	"org/gg/banking/internal/services"
+	"os/user"
+)
+
+// newCustomerService wires the customer service for operator commands, which are not subject to the policies
+func newCustomerService(db *sql.DB) services.ICustomerService {
+	return services.NewCustomerService(
+		repository.NewCustomerRepository(db, logger.Logger),
+		repository.NewAccountRepository(db, logger.Logger),
+		repository.NewAuditRepository(db, logger.Logger),
+		repository.NewOutboxRepository(db, logger.Logger),
+		repository.NewTransactor(db, logger.Logger),
+		services.NewAllowAllPolicy(),
+	)
+}
+
+// newAccountService wires the account service for operator commands
+func newAccountService(db *sql.DB) services.IAccountService {
+	return services.NewAccountService(
+		repository.NewCustomerRepository(db, logger.Logger),
+		repository.NewAccountRepository(db, logger.Logger),
+		repository.NewAuditRepository(db, logger.Logger),
+		repository.NewOutboxRepository(db, logger.Logger),
+		repository.NewTransactor(db, logger.Logger),
+	)
+}
+
+// operatorContext attributes changes made by a command to the operating system user in the audit log
+func operatorContext(ctx context.Context) context.Context {
+	subject := "cli"
+	if current, err := user.Current(); err == nil {
+		subject += ":" + current.Username
+	}
+	return auth.WithPrincipal(ctx, auth.Principal{Subject: subject})
+}
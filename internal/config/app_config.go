@@ -1,87 +1,236 @@
 package config
 
 import (
-	"database/sql"
+	"errors"
 	"fmt"
-	"log"
-	"org/gg/banking/internal/config/logger"
-	"org/gg/banking/internal/controllers"
-	"org/gg/banking/internal/repository"
-	"org/gg/banking/internal/routes"
-	"org/gg/banking/internal/services"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
-	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
 	"github.com/spf13/viper"
 )
 
-func SetupApp() {
-	config, err := LoadConfig()
-	if err != nil {
-		panic(fmt.Sprintf("failed to load config: %v", err))
-	}
+type AppConfiguration struct {
+	Database      DatabaseConfiguration
+	Server        ServerConfiguration
+	Logging       LoggingConfiguration
+	Metrics       MetricsConfiguration
+	Tracing       TracingConfiguration
+	Auth          AuthConfiguration
+	Authorization AuthorizationConfiguration
+	RateLimit     RateLimitConfiguration `mapstructure:"rate_limit"`
+	Audit         AuditConfiguration
+	Events        EventsConfiguration
+	Cache         CacheConfiguration
+	Imports       ImportConfiguration
+	Statements    StatementConfiguration
+	Features      map[string]bool
+}
 
-	// initialize logger
-	logger.InitLogger(logger.LoggerConfig{
-		Level: config.Server.LoggLevel,
-	})
+// StatementConfiguration controls account statements
+type StatementConfiguration struct {
+	// Timezone is the IANA time zone, such as Europe/Athens, whose days the dates of a statement cover
+	Timezone string
+	// MaxDays bounds the period of a single statement
+	MaxDays  int `mapstructure:"max_days"`
+	Branding StatementBrandingConfiguration
+}
 
-	db := connectToPostgres(config)
-	// Ensure DB is closed when application terminates
-	defer func(db *sql.DB) {
-		err := db.Close()
-		if err != nil {
-			log.Println("Error closing database connection:", err)
-		} else {
-			log.Println("Database connection closed")
-		}
-	}(db)
+// StatementBrandingConfiguration styles the PDF statements. Title and Footer are text/template templates, their
+// fields are listed in the README.
+type StatementBrandingConfiguration struct {
+	BankName string `mapstructure:"bank_name"`
+	// Address lines are printed under the bank name
+	Address []string
+	// Logo is a PNG or JPEG file printed in the top left corner
+	Logo string
+	// Color is the hex accent of the title and the table header, such as #1F4E79
+	Color  string
+	Title  string
+	Footer string
+	// Font and BoldFont are TrueType files replacing Helvetica, needed for text outside the Western European
+	// characters. BoldFont defaults to Font.
+	Font     string
+	BoldFont string `mapstructure:"bold_font"`
+}
 
-	// Create components
-	customerRepository := repository.NewCustomerRepository(db)
-	accountRepository := repository.NewAccountRepository(db)
-	customerService := services.NewCustomerService(customerRepository, accountRepository)
-	customerController := controllers.NewCustomerController(customerService)
+// ImportConfiguration controls bulk customer imports
+type ImportConfiguration struct {
+	// BatchSize is how many rows are copied and inserted per transaction
+	BatchSize int `mapstructure:"batch_size"`
+	// MaxRows bounds the rows of a single import, reading stops with an error past it
+	MaxRows int `mapstructure:"max_rows"`
+}
 
-	// Setup router and routes
-	router := routes.SetupRouter()
-	routes.RegisterRoutes(router, customerController)
+// CacheConfiguration controls the in-process read caches
+type CacheConfiguration struct {
+	Customers CacheSettings
+}
 
-	gin.SetMode(config.Server.Mode)
-	errRouter := router.Run(fmt.Sprintf(":%d", config.Server.Port))
+// CacheSettings bounds one LRU cache. Entries are dropped when this instance changes them and otherwise expire after
+// TTL, which bounds how stale a change made elsewhere, by another instance or the CLI, can be.
+type CacheSettings struct {
+	Enabled bool
+	// Size is the maximum number of entries
+	Size int
+	TTL  time.Duration
+}
 
-	if errRouter != nil {
-		panic(fmt.Sprintf("failed to start server: %v", err))
-	}
+// EventsConfiguration controls the publishing of domain events. Events are always written to the outbox,
+// the relay publishes them.
+type EventsConfiguration struct {
+	Relay    EventRelayConfiguration
+	Webhooks WebhookConfiguration
 }
 
-func connectToPostgres(config *AppConfiguration) *sql.DB {
-	// Setup database connection
-	dbConfig := config.Database
-	// Create connection string from config
-	driverName := "postgres"
-	connectionString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.DBName)
+// WebhookConfiguration controls the delivery of events to registered webhook subscriptions
+type WebhookConfiguration struct {
+	// Enabled queues every published event for its subscriptions and runs the delivery worker, it needs the relay
+	Enabled bool
+	// AllowHTTP accepts plain http subscription URLs, meant for local receivers only
+	AllowHTTP bool `mapstructure:"allow_http"`
+	// PollInterval is how often due deliveries are checked for
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	BatchSize    int           `mapstructure:"batch_size"`
+	// Workers bounds the deliveries in flight at once
+	Workers int
+	// Timeout bounds a single delivery request
+	Timeout time.Duration
+	// Lease is how long a claimed delivery is reserved, it is attempted again if not confirmed by then
+	Lease time.Duration
+	// MaxAttempts is how many attempts a delivery gets before it is dead
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// RetryInitial is the delay before the first retry, doubled for each further attempt up to RetryMax
+	RetryInitial time.Duration `mapstructure:"retry_initial"`
+	RetryMax     time.Duration `mapstructure:"retry_max"`
+}
 
-	// Open database connection
-	db, err := sql.Open(driverName, connectionString)
-	if err != nil {
-		panic(fmt.Sprintf("failed to connect to database: %v", err))
-	}
+// EventRelayConfiguration controls the outbox relay
+type EventRelayConfiguration struct {
+	Enabled bool
+	// Publisher is where events go: log
+	Publisher string
+	// PollInterval is how often the outbox is checked for due events
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	BatchSize    int           `mapstructure:"batch_size"`
+	// Lease is how long a claimed event is reserved, it is delivered again if not confirmed by then
+	Lease time.Duration
+	// RetryInitial is the delay before the first retry, doubled for each further attempt up to RetryMax
+	RetryInitial time.Duration `mapstructure:"retry_initial"`
+	RetryMax     time.Duration `mapstructure:"retry_max"`
+	// Retention is how long published events are kept, 0 keeps them forever
+	Retention time.Duration
+}
 
-	// Ping the database to verify connection
-	if err := db.Ping(); err != nil {
-		log.Fatal("Failed to ping database:", err)
-	}
+// AuditConfiguration controls the audit log
+type AuditConfiguration struct {
+	Checkpoints AuditCheckpointConfiguration
+}
 
-	log.Println("Connected to database successfully")
-	return db
+// AuditCheckpointConfiguration controls the signed checkpoints of the audit hash chain
+type AuditCheckpointConfiguration struct {
+	// Enabled writes a checkpoint every Interval while the server runs and new entries were recorded
+	Enabled  bool
+	Interval time.Duration
+	// Path is the file checkpoints are appended to, one JSON object per line
+	Path string
+	// SigningKeyFile is a PKCS #8 PEM Ed25519 private key signing the checkpoints
+	SigningKeyFile string `mapstructure:"signing_key_file"`
+	// PublicKeyFile verifies the checkpoints, defaults to the public half of SigningKeyFile
+	PublicKeyFile string `mapstructure:"public_key_file"`
 }
 
-type AppConfiguration struct {
-	Database DatabaseConfiguration
-	Server   ServerConfiguration
+// RateLimitConfiguration controls the token bucket rate limiter
+type RateLimitConfiguration struct {
+	Enabled bool
+	// Store is memory, buckets per instance, or postgres, buckets shared by every instance
+	Store string
+	// Default applies to routes without their own rule
+	Default RateLimitRule
+	Routes  []RateLimitRule
+	// PreAuth is counted per client ip before authentication on protected groups, so failed authentication
+	// attempts are throttled too. Its Route and Key are ignored, 0 requests disables it.
+	PreAuth RateLimitRule `mapstructure:"pre_auth"`
+}
+
+// RateLimitRule allows Requests per Period with bursts of up to Burst requests, counted per Key
+type RateLimitRule struct {
+	// Route is a method and route template, such as "GET /api/v1/customers/"
+	Route string
+	// Requests is refilled over Period, 0 disables limiting for the route
+	Requests int
+	Period   time.Duration
+	// Burst is the bucket size, defaults to Requests
+	Burst int
+	// Key is ip, api_key or principal. Callers without an API key or principal are counted by ip.
+	// Route rules default to the key of the default rule.
+	Key string
+}
+
+// AuthorizationConfiguration declares which roles may perform which actions
+type AuthorizationConfiguration struct {
+	// Policies maps a resource and a verb, such as customers and read, to the allowed roles.
+	// A role suffixed with :own, such as customer:own, only grants access to the caller's own records.
+	Policies map[string]map[string][]string
+}
+
+// AuthConfiguration controls bearer token authentication
+type AuthConfiguration struct {
+	Enabled bool
+	// ProtectedGroups lists the route groups, such as /api/v1, that require a token. Other groups stay public.
+	ProtectedGroups []string `mapstructure:"protected_groups"`
+	// Algorithms lists the accepted signing algorithms: HS256, RS256 and ES256
+	Algorithms []string
+	// HMACSecret verifies HS256 tokens
+	HMACSecret string `mapstructure:"hmac_secret"`
+	// JWKSFile or JWKSURL provide the public keys verifying RS256 and ES256 tokens
+	JWKSFile string `mapstructure:"jwks_file"`
+	JWKSURL  string `mapstructure:"jwks_url"`
+	// JWKSRefresh is how long keys fetched from JWKSURL are cached
+	JWKSRefresh time.Duration `mapstructure:"jwks_refresh"`
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// ClockSkew tolerates clock differences when checking exp and nbf
+	ClockSkew time.Duration `mapstructure:"clock_skew"`
+	// RolesClaim and EmailClaim name the claims mapped to the principal, dots select nested claims
+	RolesClaim string              `mapstructure:"roles_claim"`
+	EmailClaim string              `mapstructure:"email_claim"`
+	APIKeys    APIKeyConfiguration `mapstructure:"api_keys"`
+}
+
+// APIKeyConfiguration controls authentication with X-API-Key headers
+type APIKeyConfiguration struct {
+	Enabled bool
+	// RotationGrace is how long a rotated key keeps working next to its replacement
+	RotationGrace time.Duration `mapstructure:"rotation_grace"`
+}
+
+// TracingConfiguration controls the OpenTelemetry span exporter
+type TracingConfiguration struct {
+	Enabled     bool
+	ServiceName string `mapstructure:"service_name"`
+	// Exporter is otlp, stdout or file
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP collector
+	Endpoint string
+	// Insecure sends spans to the collector over plain HTTP
+	Insecure bool
+	// Path is the file the file exporter appends spans to
+	Path string
+	// SampleRatio is the share of new traces that are recorded, traces started upstream follow the caller's decision
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// MetricsConfiguration controls the Prometheus endpoint
+type MetricsConfiguration struct {
+	Enabled bool
+	// Path is where the metrics are served, /metrics by default
+	Path string
 }
 
 type DatabaseConfiguration struct {
@@ -90,12 +239,363 @@ type DatabaseConfiguration struct {
 	User     string
 	Password string
 	DBName   string
+	// ApplicationName identifies the service in pg_stat_activity and the Postgres log
+	ApplicationName string `mapstructure:"application_name"`
 }
 
 type ServerConfiguration struct {
 	Port      int
 	Mode      string
-	LoggLevel string
+	LoggLevel string `mapstructure:"log_level"`
+	// TrustedProxies lists the ips and CIDRs allowed to set X-Forwarded-For, no proxy is trusted when empty
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+type LoggingConfiguration struct {
+	// Format is json, text or pretty and applies to sinks without their own format
+	Format string
+	// AddSource adds the source file and line of the log call to every record
+	AddSource bool `mapstructure:"add_source"`
+	// Sinks lists where log records are written, a single stdout sink is used when empty
+	Sinks     []LogSinkConfiguration
+	Access    AccessLogConfiguration
+	Redaction RedactionConfiguration
+	Body      BodyCaptureConfiguration
+}
+
+// AccessLogConfiguration controls the one line per request access log
+type AccessLogConfiguration struct {
+	// Format is common, combined or json
+	Format string
+}
+
+// LogSinkConfiguration describes one log destination
+type LogSinkConfiguration struct {
+	// Type is stdout, stderr or file
+	Type   string
+	Format string
+	// Level overrides server.log_level for this sink
+	Level string
+	Path  string
+	// MaxSizeMB rotates the file once it reaches the size, MaxBackups and MaxAgeDays bound the rotated files kept
+	MaxSizeMB  int `mapstructure:"max_size_mb"`
+	MaxBackups int `mapstructure:"max_backups"`
+	MaxAgeDays int `mapstructure:"max_age_days"`
+	// RotateInterval additionally rotates the file on a fixed schedule, such as 24h
+	RotateInterval time.Duration `mapstructure:"rotate_interval"`
+	Compress       bool
+}
+
+// BodyCaptureConfiguration bounds how much of the HTTP traffic is kept in memory for logging
+type BodyCaptureConfiguration struct {
+	// Enabled turns on verbose request and response logging with headers and bodies, meant for development
+	Enabled bool
+	// MaxBytes caps the captured request and response body, larger bodies are truncated
+	MaxBytes int `mapstructure:"max_bytes"`
+	// SamplePercent is the share of successful requests whose bodies are logged, failures are always logged
+	SamplePercent int `mapstructure:"sample_percent"`
+	// SkipContentTypes lists content type prefixes, such as binary or streaming types, that are never captured
+	SkipContentTypes []string `mapstructure:"skip_content_types"`
+}
+
+// RedactionConfiguration controls which parts of logged HTTP traffic are masked
+type RedactionConfiguration struct {
+	// Headers lists request and response headers whose values are never logged
+	Headers []string
+	// BodyFields masks JSON body fields selected by a JSON path such as $.accounts[*].account_number
+	BodyFields []BodyFieldRedaction `mapstructure:"body_fields"`
+	// SkipBodyRoutes lists routes, as "METHOD /route/template" or "/route/template", whose bodies are not logged
+	SkipBodyRoutes []string `mapstructure:"skip_body_routes"`
+	// QueryParams masks the values of query string parameters, such as the actor filter of the audit log
+	QueryParams []QueryParamRedaction `mapstructure:"query_params"`
+}
+
+type BodyFieldRedaction struct {
+	Path string
+	// Mask is one of full, partial, email or last4
+	Mask string
+}
+
+type QueryParamRedaction struct {
+	// Name is matched case insensitively
+	Name string
+	// Mask is one of full, partial, email or last4
+	Mask string
+}
+
+// ErrInvalid is wrapped by the error of an application refusing to start with a configuration that fails Validate
+var ErrInvalid = errors.New("invalid configuration")
+
+// Validate reports every setting that would prevent the application from starting
+func (c *AppConfiguration) Validate() error {
+	var errs []error
+
+	if c.Database.Host == "" {
+		errs = append(errs, errors.New("database.host is required"))
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port %d is out of range", c.Database.Port))
+	}
+	if c.Database.User == "" {
+		errs = append(errs, errors.New("database.user is required"))
+	}
+	if c.Database.DBName == "" {
+		errs = append(errs, errors.New("database.dbname is required"))
+	}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %d is out of range", c.Server.Port))
+	}
+	switch c.Server.Mode {
+	case "", "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("server.mode %q must be one of debug, release, test", c.Server.Mode))
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("server.trusted_proxies entry %q is not an ip or CIDR", proxy))
+		}
+	}
+	if !validLogLevel(c.Server.LoggLevel) {
+		errs = append(errs, fmt.Errorf("server.log_level %q must be one of debug, info, warn, error", c.Server.LoggLevel))
+	}
+
+	if !validLogFormat(c.Logging.Format) {
+		errs = append(errs, fmt.Errorf("logging.format %q must be one of json, text, pretty", c.Logging.Format))
+	}
+	for i, sink := range c.Logging.Sinks {
+		switch strings.ToLower(sink.Type) {
+		case "stdout", "stderr":
+		case "file":
+			if sink.Path == "" {
+				errs = append(errs, fmt.Errorf("logging.sinks[%d].path is required for file sinks", i))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("logging.sinks[%d].type %q must be one of stdout, stderr, file", i, sink.Type))
+		}
+		if !validLogFormat(sink.Format) {
+			errs = append(errs, fmt.Errorf("logging.sinks[%d].format %q must be one of json, text, pretty", i, sink.Format))
+		}
+		if !validLogLevel(sink.Level) {
+			errs = append(errs, fmt.Errorf("logging.sinks[%d].level %q must be one of debug, info, warn, error", i, sink.Level))
+		}
+		if sink.MaxSizeMB < 0 || sink.MaxBackups < 0 || sink.MaxAgeDays < 0 || sink.RotateInterval < 0 {
+			errs = append(errs, fmt.Errorf("logging.sinks[%d] rotation settings must not be negative", i))
+		}
+	}
+
+	switch strings.ToLower(c.Logging.Access.Format) {
+	case "", "common", "combined", "json":
+	default:
+		errs = append(errs, fmt.Errorf("logging.access.format %q must be one of common, combined, json", c.Logging.Access.Format))
+	}
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("metrics.path %q must start with /", c.Metrics.Path))
+	}
+
+	if c.Auth.Enabled {
+		if len(c.Auth.Algorithms) == 0 {
+			errs = append(errs, errors.New("auth.algorithms must list at least one algorithm"))
+		}
+		for _, algorithm := range c.Auth.Algorithms {
+			switch algorithm {
+			case "HS256":
+				if c.Auth.HMACSecret == "" {
+					errs = append(errs, errors.New("auth.hmac_secret is required for HS256"))
+				}
+			case "RS256", "ES256":
+				if c.Auth.JWKSFile == "" && c.Auth.JWKSURL == "" {
+					errs = append(errs, fmt.Errorf("auth.jwks_file or auth.jwks_url is required for %s", algorithm))
+				}
+			default:
+				errs = append(errs, fmt.Errorf("auth.algorithms entry %q must be one of HS256, RS256, ES256", algorithm))
+			}
+		}
+		if c.Auth.JWKSFile != "" && c.Auth.JWKSURL != "" {
+			errs = append(errs, errors.New("auth.jwks_file and auth.jwks_url are mutually exclusive"))
+		}
+		if c.Auth.APIKeys.RotationGrace < 0 {
+			errs = append(errs, errors.New("auth.api_keys.rotation_grace must not be negative"))
+		}
+	}
+
+	if c.Audit.Checkpoints.Enabled {
+		if c.Audit.Checkpoints.Interval <= 0 {
+			errs = append(errs, errors.New("audit.checkpoints.interval must be positive"))
+		}
+		if c.Audit.Checkpoints.Path == "" {
+			errs = append(errs, errors.New("audit.checkpoints.path is required"))
+		}
+		if c.Audit.Checkpoints.SigningKeyFile == "" {
+			errs = append(errs, errors.New("audit.checkpoints.signing_key_file is required"))
+		}
+	}
+
+	if c.Events.Relay.Enabled {
+		relay := c.Events.Relay
+		if strings.ToLower(relay.Publisher) != "log" {
+			errs = append(errs, fmt.Errorf("events.relay.publisher %q must be log", relay.Publisher))
+		}
+		if relay.PollInterval <= 0 || relay.Lease <= 0 || relay.RetryInitial <= 0 {
+			errs = append(errs, errors.New("events.relay.poll_interval, lease and retry_initial must be positive"))
+		}
+		if relay.RetryMax < relay.RetryInitial {
+			errs = append(errs, errors.New("events.relay.retry_max must not be less than retry_initial"))
+		}
+		if relay.BatchSize <= 0 {
+			errs = append(errs, fmt.Errorf("events.relay.batch_size %d must be positive", relay.BatchSize))
+		}
+		if relay.Retention < 0 {
+			errs = append(errs, errors.New("events.relay.retention must not be negative"))
+		}
+	}
+
+	if c.Events.Webhooks.Enabled {
+		webhooks := c.Events.Webhooks
+		if !c.Events.Relay.Enabled {
+			errs = append(errs, errors.New("events.webhooks requires events.relay.enabled"))
+		}
+		if webhooks.PollInterval <= 0 || webhooks.Timeout <= 0 || webhooks.Lease <= 0 || webhooks.RetryInitial <= 0 {
+			errs = append(errs, errors.New("events.webhooks.poll_interval, timeout, lease and retry_initial must be positive"))
+		}
+		if webhooks.Lease <= webhooks.Timeout {
+			errs = append(errs, errors.New("events.webhooks.lease must be longer than timeout"))
+		}
+		if webhooks.RetryMax < webhooks.RetryInitial {
+			errs = append(errs, errors.New("events.webhooks.retry_max must not be less than retry_initial"))
+		}
+		if webhooks.BatchSize <= 0 || webhooks.Workers <= 0 || webhooks.MaxAttempts <= 0 {
+			errs = append(errs, errors.New("events.webhooks.batch_size, workers and max_attempts must be positive"))
+		}
+	}
+
+	if c.Cache.Customers.Enabled && (c.Cache.Customers.Size <= 0 || c.Cache.Customers.TTL <= 0) {
+		errs = append(errs, errors.New("cache.customers.size and ttl must be positive"))
+	}
+
+	if c.Imports.BatchSize <= 0 || c.Imports.MaxRows <= 0 {
+		errs = append(errs, errors.New("imports.batch_size and max_rows must be positive"))
+	}
+
+	errs = append(errs, c.Statements.validate()...)
+
+	if c.RateLimit.Enabled {
+		switch strings.ToLower(c.RateLimit.Store) {
+		case "memory", "postgres":
+		default:
+			errs = append(errs, fmt.Errorf("rate_limit.store %q must be one of memory, postgres", c.RateLimit.Store))
+		}
+		errs = append(errs, c.RateLimit.Default.validate("rate_limit.default")...)
+		errs = append(errs, c.RateLimit.PreAuth.validate("rate_limit.pre_auth")...)
+		for i, rule := range c.RateLimit.Routes {
+			path := fmt.Sprintf("rate_limit.routes[%d]", i)
+			if rule.Route == "" {
+				errs = append(errs, fmt.Errorf("%s.route is required", path))
+			}
+			errs = append(errs, rule.validate(path)...)
+		}
+	}
+
+	if c.Tracing.Enabled {
+		switch strings.ToLower(c.Tracing.Exporter) {
+		case "otlp":
+			if c.Tracing.Endpoint == "" {
+				errs = append(errs, errors.New("tracing.endpoint is required for the otlp exporter"))
+			}
+		case "stdout":
+		case "file":
+			if c.Tracing.Path == "" {
+				errs = append(errs, errors.New("tracing.path is required for the file exporter"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("tracing.exporter %q must be one of otlp, stdout, file", c.Tracing.Exporter))
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			errs = append(errs, fmt.Errorf("tracing.sample_ratio %v must be between 0 and 1", c.Tracing.SampleRatio))
+		}
+	}
+
+	if c.Logging.Body.MaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("logging.body.max_bytes %d must not be negative", c.Logging.Body.MaxBytes))
+	}
+	if c.Logging.Body.SamplePercent < 0 || c.Logging.Body.SamplePercent > 100 {
+		errs = append(errs, fmt.Errorf("logging.body.sample_percent %d must be between 0 and 100", c.Logging.Body.SamplePercent))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s StatementConfiguration) validate() []error {
+	var errs []error
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		errs = append(errs, fmt.Errorf("statements.timezone %q is not a time zone", s.Timezone))
+	}
+	if s.MaxDays <= 0 {
+		errs = append(errs, fmt.Errorf("statements.max_days %d must be positive", s.MaxDays))
+	}
+
+	branding := s.Branding
+	if !validHexColor(branding.Color) {
+		errs = append(errs, fmt.Errorf("statements.branding.color %q must be a hex color such as #1F4E79", branding.Color))
+	}
+	switch strings.ToLower(filepath.Ext(branding.Logo)) {
+	case "", ".png", ".jpg", ".jpeg":
+	default:
+		errs = append(errs, fmt.Errorf("statements.branding.logo %q must be a PNG or JPEG file", branding.Logo))
+	}
+	if branding.BoldFont != "" && branding.Font == "" {
+		errs = append(errs, errors.New("statements.branding.bold_font requires statements.branding.font"))
+	}
+	for name, text := range map[string]string{"title": branding.Title, "footer": branding.Footer} {
+		if _, err := template.New(name).Parse(text); err != nil {
+			errs = append(errs, fmt.Errorf("statements.branding.%s is not a valid template: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// validHexColor reports whether color is written as #RRGGBB
+func validHexColor(color string) bool {
+	if len(color) != 7 || color[0] != '#' {
+		return false
+	}
+	_, err := strconv.ParseUint(color[1:], 16, 32)
+	return err == nil
+}
+
+func (r RateLimitRule) validate(path string) []error {
+	var errs []error
+	if r.Requests < 0 {
+		errs = append(errs, fmt.Errorf("%s.requests must not be negative", path))
+	}
+	if r.Requests > 0 && r.Period <= 0 {
+		errs = append(errs, fmt.Errorf("%s.period must be positive", path))
+	}
+	if r.Burst < 0 {
+		errs = append(errs, fmt.Errorf("%s.burst must not be negative", path))
+	}
+	switch strings.ToLower(r.Key) {
+	case "", "ip", "api_key", "principal":
+	default:
+		errs = append(errs, fmt.Errorf("%s.key %q must be one of ip, api_key, principal", path, r.Key))
+	}
+	return errs
+}
+
+func validLogLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "", "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+func validLogFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case "", "json", "text", "pretty":
+		return true
+	}
+	return false
 }
 
 func LoadConfig() (*AppConfiguration, error) {
@@ -104,6 +604,58 @@ func LoadConfig() (*AppConfiguration, error) {
 	viper.SetConfigName(env)
 	viper.SetConfigName("config")
 	viper.SetConfigType("yml")
+	viper.SetDefault("database.application_name", "banking-api")
+	viper.SetDefault("auth.protected_groups", []string{"/api/v1"})
+	viper.SetDefault("auth.algorithms", []string{"HS256"})
+	viper.SetDefault("auth.jwks_refresh", "15m")
+	viper.SetDefault("auth.clock_skew", "30s")
+	viper.SetDefault("auth.roles_claim", "roles")
+	viper.SetDefault("auth.email_claim", "email")
+	viper.SetDefault("auth.api_keys.enabled", true)
+	viper.SetDefault("auth.api_keys.rotation_grace", "24h")
+	viper.SetDefault("rate_limit.store", "memory")
+	viper.SetDefault("rate_limit.default.period", "1m")
+	viper.SetDefault("rate_limit.default.key", "principal")
+	viper.SetDefault("rate_limit.pre_auth.requests", 600)
+	viper.SetDefault("rate_limit.pre_auth.period", "1m")
+	viper.SetDefault("rate_limit.pre_auth.burst", 120)
+	viper.SetDefault("audit.checkpoints.interval", "1h")
+	viper.SetDefault("audit.checkpoints.path", "logs/audit-checkpoints.jsonl")
+	viper.SetDefault("events.relay.publisher", "log")
+	viper.SetDefault("events.relay.poll_interval", "1s")
+	viper.SetDefault("events.relay.batch_size", 100)
+	viper.SetDefault("events.relay.lease", "30s")
+	viper.SetDefault("events.relay.retry_initial", "1s")
+	viper.SetDefault("events.relay.retry_max", "5m")
+	viper.SetDefault("events.webhooks.poll_interval", "1s")
+	viper.SetDefault("events.webhooks.batch_size", 50)
+	viper.SetDefault("events.webhooks.workers", 8)
+	viper.SetDefault("events.webhooks.timeout", "10s")
+	viper.SetDefault("events.webhooks.lease", "1m")
+	viper.SetDefault("events.webhooks.max_attempts", 10)
+	viper.SetDefault("events.webhooks.retry_initial", "10s")
+	viper.SetDefault("events.webhooks.retry_max", "1h")
+	viper.SetDefault("cache.customers.size", 10000)
+	viper.SetDefault("cache.customers.ttl", "30s")
+	viper.SetDefault("imports.batch_size", 1000)
+	viper.SetDefault("imports.max_rows", 100000)
+	viper.SetDefault("statements.timezone", "UTC")
+	viper.SetDefault("statements.max_days", 366)
+	viper.SetDefault("statements.branding.bank_name", "GG Banking")
+	viper.SetDefault("statements.branding.color", "#1F4E79")
+	viper.SetDefault("statements.branding.title", "Statement of account {{.AccountNumber}}")
+	viper.SetDefault("statements.branding.footer", "{{.BankName}} - Page {{.Page}} of {{.Pages}}")
+	viper.SetDefault("tracing.service_name", "banking-api")
+	viper.SetDefault("tracing.exporter", "otlp")
+	viper.SetDefault("tracing.endpoint", "localhost:4318")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.path", "/metrics")
+	viper.SetDefault("logging.format", "json")
+	viper.SetDefault("logging.access.format", "json")
+	viper.SetDefault("logging.body.enabled", false)
+	viper.SetDefault("logging.body.max_bytes", 4096)
+	viper.SetDefault("logging.body.sample_percent", 100)
 
 	// Get the project root directory
 	projectRoot, err := os.Getwd()
@@ -111,6 +663,15 @@ func LoadConfig() (*AppConfiguration, error) {
 		return nil, fmt.Errorf("failed to get working directory: %w", err)
 	}
 
+	// Reloads use viper too, it is not safe for concurrent use
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	// Settings can be overridden per environment, e.g. BANKING_LOGGING_BODY_ENABLED=true
+	viper.SetEnvPrefix("banking")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
 	// Try multiple possible config locations
 	viper.AddConfigPath(fmt.Sprintf("%s/configs", projectRoot)) // From project root
 	viper.AddConfigPath("configs")                              // Direct subfolder
@@ -120,6 +681,18 @@ func LoadConfig() (*AppConfiguration, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	config, err := unmarshalConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	current.Store(config)
+
+	return config, nil
+}
+
+// unmarshalConfig decodes the configuration currently held by viper
+func unmarshalConfig() (*AppConfiguration, error) {
 	var config AppConfiguration
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
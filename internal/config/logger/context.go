@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type requestContextKey struct{}
+
+// RequestContext carries the request-scoped attributes added to every log record
+type RequestContext struct {
+	ID       string
+	Route    string
+	ClientIP string
+	Start    time.Time
+
+	mu         sync.Mutex
+	customerID int64
+}
+
+// WithRequest returns a copy of ctx carrying the attributes of an HTTP request
+func WithRequest(ctx context.Context, id, route, clientIP string, start time.Time) context.Context {
+	return context.WithValue(ctx, requestContextKey{}, &RequestContext{
+		ID:       id,
+		Route:    route,
+		ClientIP: clientIP,
+		Start:    start,
+	})
+}
+
+// RequestFromContext returns the request attributes stored in ctx, or nil outside of a request
+func RequestFromContext(ctx context.Context) *RequestContext {
+	if ctx == nil {
+		return nil
+	}
+	request, _ := ctx.Value(requestContextKey{}).(*RequestContext)
+	return request
+}
+
+// RequestID returns the id of the request handled in ctx, or an empty string outside of a request
+func RequestID(ctx context.Context) string {
+	if request := RequestFromContext(ctx); request != nil {
+		return request.ID
+	}
+	return ""
+}
+
+// SetCustomerID records the customer a request operates on so later log records include it
+func SetCustomerID(ctx context.Context, customerID int64) {
+	if request := RequestFromContext(ctx); request != nil {
+		request.mu.Lock()
+		request.customerID = customerID
+		request.mu.Unlock()
+	}
+}
+
+// CustomerID returns the customer recorded with SetCustomerID, or zero if none was
+func (r *RequestContext) CustomerID() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.customerID
+}
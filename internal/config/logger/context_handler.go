@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ContextHandler decorates another handler with the request attributes found in the record's context
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps handler so records logged with a request context carry its attributes
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: handler}
+}
+
+// Handle adds request id, route, customer id, latency and the current trace and span ids before delegating
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	if request := RequestFromContext(ctx); request != nil {
+		record.AddAttrs(
+			slog.String("request_id", request.ID),
+			slog.String("route", request.Route),
+			slog.Float64("latency_ms", float64(time.Since(request.Start).Microseconds())/1000),
+		)
+		if customerID := request.CustomerID(); customerID != 0 {
+			record.AddAttrs(slog.Int64("customer_id", customerID))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextHandler(t *testing.T) {
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+
+	tests := []struct {
+		name     string
+		ctx      func() context.Context
+		want     map[string]any
+		wantNone []string
+	}{
+		{
+			name:     "outside of a request",
+			ctx:      context.Background,
+			wantNone: []string{"request_id", "route", "latency_ms", "customer_id", "trace_id", "span_id"},
+		},
+		{
+			name: "request",
+			ctx: func() context.Context {
+				return WithRequest(context.Background(), "req-42", "/api/v1/customers/:email", "127.0.0.1", time.Now())
+			},
+			want:     map[string]any{"request_id": "req-42", "route": "/api/v1/customers/:email"},
+			wantNone: []string{"customer_id", "trace_id"},
+		},
+		{
+			name: "request with customer",
+			ctx: func() context.Context {
+				ctx := WithRequest(context.Background(), "req-42", "/api/v1/customers/:email", "127.0.0.1", time.Now())
+				SetCustomerID(ctx, 7)
+				return ctx
+			},
+			want: map[string]any{"request_id": "req-42", "customer_id": float64(7)},
+		},
+		{
+			name: "span",
+			ctx: func() context.Context {
+				return trace.ContextWithSpanContext(context.Background(), spanContext)
+			},
+			want:     map[string]any{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7"},
+			wantNone: []string{"request_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			// Attributes and groups added to the logger must keep the request attributes
+			logger := slog.New(NewContextHandler(slog.NewJSONHandler(&out, nil))).With("component", "test")
+
+			logger.InfoContext(tt.ctx(), "handled")
+
+			var record map[string]any
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatalf("decoding log record %q: %v", out.String(), err)
+			}
+			if record["component"] != "test" {
+				t.Errorf("record lost the logger attributes: %v", record)
+			}
+			for key, want := range tt.want {
+				if record[key] != want {
+					t.Errorf("%s = %v, want %v", key, record[key], want)
+				}
+			}
+			for _, key := range tt.wantNone {
+				if value, ok := record[key]; ok {
+					t.Errorf("%s = %v, want no such attribute", key, value)
+				}
+			}
+			if _, isRequest := tt.want["request_id"]; isRequest {
+				if latency, ok := record["latency_ms"].(float64); !ok || latency < 0 {
+					t.Errorf("latency_ms = %v, want the time since the request started", record["latency_ms"])
+				}
+			}
+		})
+	}
+}
@@ -1,24 +1,182 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
-var Logger *slog.Logger
+var Logger *slog.Logger = slog.Default()
+
+// level backs the handler level so it can be changed at runtime
+var level = new(slog.LevelVar)
+
+var (
+	sinksMu     sync.Mutex
+	openClosers []io.Closer
+)
+
+// Output formats
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatPretty = "pretty"
+)
+
+// Sink types
+const (
+	SinkStdout = "stdout"
+	SinkStderr = "stderr"
+	SinkFile   = "file"
+)
 
 // LoggerConfig holds the configuration for the logger
 type LoggerConfig struct {
 	Level string
+	// Format is json, text or pretty and applies to sinks without their own format
+	Format string
+	// AddSource adds the source file and line of the log call to every record
+	AddSource bool
+	// Sinks lists the destinations records are written to, defaults to a single stdout sink
+	Sinks []SinkConfig
+	// Output replaces stdout for console sinks, e.g. os.Stderr for commands that print results
+	Output io.Writer
+}
+
+// SinkConfig describes one log destination
+type SinkConfig struct {
+	// Type is stdout, stderr or file
+	Type   string
+	Format string
+	// Level is the minimum level of this sink, defaults to the logger level and follows its runtime changes
+	Level string
+	// Path, MaxSizeMB, MaxBackups, MaxAgeDays, RotateInterval and Compress apply to file sinks
+	Path           string
+	MaxSizeMB      int
+	MaxBackups     int
+	MaxAgeDays     int
+	RotateInterval time.Duration
+	Compress       bool
 }
 
-func InitLogger(loggerConfig LoggerConfig) {
-	Logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: parseLogLevel(loggerConfig.Level),
-	}))
+// InitLogger builds the logger from the configuration and installs it as the slog default.
+// File sinks of a previous call are closed.
+func InitLogger(loggerConfig LoggerConfig) error {
+	level.Set(parseLogLevel(loggerConfig.Level))
 
+	sinks := loggerConfig.Sinks
+	if len(sinks) == 0 {
+		sinks = []SinkConfig{{Type: SinkStdout}}
+	}
+
+	var handlers []slog.Handler
+	var closers []io.Closer
+	for _, sink := range sinks {
+		handler, closer, err := newSinkHandler(sink, loggerConfig)
+		if err != nil {
+			closeAll(closers)
+			return err
+		}
+		handlers = append(handlers, handler)
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+	}
+
+	var handler slog.Handler = newFanoutHandler(handlers)
+	if len(handlers) == 1 {
+		handler = handlers[0]
+	}
+	Logger = slog.New(NewContextHandler(handler))
 	slog.SetDefault(Logger)
+
+	sinksMu.Lock()
+	previous := openClosers
+	openClosers = closers
+	sinksMu.Unlock()
+
+	return closeAll(previous)
+}
+
+// Close flushes and closes the file sinks opened by InitLogger
+func Close() error {
+	sinksMu.Lock()
+	closers := openClosers
+	openClosers = nil
+	sinksMu.Unlock()
+
+	return closeAll(closers)
+}
+
+// SetLevel changes the minimum level of the logger without recreating it
+func SetLevel(levelName string) {
+	level.Set(parseLogLevel(levelName))
+}
+
+// newSinkHandler opens the sink's destination and wraps it in a handler of the sink's format
+func newSinkHandler(sink SinkConfig, loggerConfig LoggerConfig) (slog.Handler, io.Closer, error) {
+	var writer io.Writer
+	var closer io.Closer
+
+	switch strings.ToLower(sink.Type) {
+	case "", SinkStdout:
+		writer = os.Stdout
+		if loggerConfig.Output != nil {
+			writer = loggerConfig.Output
+		}
+	case SinkStderr:
+		writer = os.Stderr
+	case SinkFile:
+		fileWriter, err := newRotatingFile(sink)
+		if err != nil {
+			return nil, nil, err
+		}
+		writer, closer = fileWriter, fileWriter
+	default:
+		return nil, nil, fmt.Errorf("unknown log sink type %q", sink.Type)
+	}
+
+	var sinkLevel slog.Leveler = level
+	if sink.Level != "" {
+		sinkLevel = parseLogLevel(sink.Level)
+	}
+	options := &slog.HandlerOptions{
+		Level:     sinkLevel,
+		AddSource: loggerConfig.AddSource,
+	}
+
+	format := sink.Format
+	if format == "" {
+		format = loggerConfig.Format
+	}
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return slog.NewJSONHandler(writer, options), closer, nil
+	case FormatText:
+		return slog.NewTextHandler(writer, options), closer, nil
+	case FormatPretty:
+		return NewPrettyHandler(writer, options), closer, nil
+	default:
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+func closeAll(closers []io.Closer) error {
+	var errs []error
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // parseLogLevel converts a string level to slog.Level
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// initTestLogger runs InitLogger and restores the previous logger and level when the test ends
+func initTestLogger(t *testing.T, loggerConfig LoggerConfig) error {
+	t.Helper()
+	previous, previousDefault, previousLevel := Logger, slog.Default(), level.Level()
+	t.Cleanup(func() {
+		Close()
+		Logger = previous
+		slog.SetDefault(previousDefault)
+		level.Set(previousLevel)
+	})
+	return InitLogger(loggerConfig)
+}
+
+func TestInitLoggerFormats(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		addSource bool
+		want      *regexp.Regexp
+	}{
+		{name: "default", want: regexp.MustCompile(`^\{"time":"[^"]+","level":"INFO","msg":"Customer found","customer_id":42,"request_id":"req-42"\}\n$`)},
+		{name: "json", format: "JSON", want: regexp.MustCompile(`^\{"time":.*"msg":"Customer found".*\}\n$`)},
+		{name: "text", format: FormatText, want: regexp.MustCompile(`^time=\S+ level=INFO msg="Customer found" customer_id=42 request_id=req-42\n$`)},
+		{name: "pretty", format: FormatPretty, want: regexp.MustCompile(`^\d{2}:\d{2}:\d{2}\.\d{3} INF Customer found customer_id=42 request_id=req-42\n$`)},
+		{name: "pretty with source", format: FormatPretty, addSource: true, want: regexp.MustCompile(`^\S+ INF logger_test\.go:\d+ Customer found`)},
+		{name: "json with source", format: FormatJSON, addSource: true, want: regexp.MustCompile(`"source":\{"function":"[^"]+","file":"[^"]+logger_test\.go","line":\d+\}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := initTestLogger(t, LoggerConfig{Level: "info", Format: tt.format, AddSource: tt.addSource, Output: &out}); err != nil {
+				t.Fatalf("InitLogger() error = %v", err)
+			}
+
+			Logger.Debug("not logged")
+			Logger.Info("Customer found", "customer_id", 42, "request_id", "req-42")
+
+			if !tt.want.MatchString(out.String()) {
+				t.Errorf("logged %q, want a match of %s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestInitLoggerSinks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "banking.log")
+	var console bytes.Buffer
+
+	err := initTestLogger(t, LoggerConfig{
+		Level:  "info",
+		Format: FormatText,
+		Output: &console,
+		Sinks: []SinkConfig{
+			{Type: SinkStdout, Level: "warn"},
+			{Type: SinkFile, Format: FormatJSON, Path: path},
+		},
+	})
+	if err != nil {
+		t.Fatalf("InitLogger() error = %v", err)
+	}
+
+	Logger.Info("Customer found")
+	Logger.Warn("Slow query")
+	// The file sink follows the logger level, the console keeps its own
+	SetLevel("debug")
+	Logger.Debug("Query plan")
+	SetLevel("error")
+	Logger.Warn("Pool exhausted")
+
+	if err := Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := console.String(); strings.Count(got, "\n") != 2 || !strings.Contains(got, `level=WARN msg="Slow query"`) || !strings.Contains(got, `msg="Pool exhausted"`) {
+		t.Errorf("console sink logged %q, want the two warnings as text", got)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the file sink: %v", err)
+	}
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decoding file record %q: %v", line, err)
+		}
+		messages = append(messages, record["msg"].(string))
+	}
+	if want := "Customer found,Slow query,Query plan"; strings.Join(messages, ",") != want {
+		t.Errorf("file sink logged %v, want %s", messages, want)
+	}
+}
+
+func TestInitLoggerRejectsInvalidSinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		config LoggerConfig
+	}{
+		{name: "unknown format", config: LoggerConfig{Format: "xml"}},
+		{name: "unknown sink format", config: LoggerConfig{Sinks: []SinkConfig{{Type: SinkStdout, Format: "logfmt"}}}},
+		{name: "unknown sink", config: LoggerConfig{Sinks: []SinkConfig{{Type: "syslog"}}}},
+		{name: "file without path", config: LoggerConfig{Sinks: []SinkConfig{{Type: SinkFile}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := Logger
+			if err := initTestLogger(t, tt.config); err == nil {
+				t.Error("InitLogger() accepted an invalid sink")
+			}
+			if Logger != previous {
+				t.Error("InitLogger() replaced the logger although it failed")
+			}
+		})
+	}
+}
+
+func TestRotatingFileRotatesOnInterval(t *testing.T) {
+	dir := t.TempDir()
+	file, err := newRotatingFile(SinkConfig{Path: filepath.Join(dir, "banking.log"), RotateInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("newRotatingFile() error = %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("reading the log directory: %v", err)
+		}
+		if len(entries) > 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("the log file was not rotated after its interval")
+}
@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ANSI colors used by the pretty handler
+const (
+	colorReset  = "\033[0m"
+	colorDim    = "\033[2m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorCyan   = "\033[36m"
+)
+
+// PrettyHandler writes one human readable line per record, e.g.
+// 15:04:05.000 INF Customer found customer_id=42 request_id=abc
+// Colors are only used when writing to a terminal.
+type PrettyHandler struct {
+	options *slog.HandlerOptions
+	writer  io.Writer
+	mu      *sync.Mutex
+	color   bool
+	// attrs holds the already formatted attributes added with WithAttrs
+	attrs []byte
+	// prefix is the dotted group path added with WithGroup
+	prefix string
+}
+
+// NewPrettyHandler creates a console handler for local development
+func NewPrettyHandler(writer io.Writer, options *slog.HandlerOptions) *PrettyHandler {
+	if options == nil {
+		options = &slog.HandlerOptions{}
+	}
+	return &PrettyHandler{
+		options: options,
+		writer:  writer,
+		mu:      &sync.Mutex{},
+		color:   isTerminal(writer),
+	}
+}
+
+func isTerminal(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd())
+}
+
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.options.Level != nil {
+		minLevel = h.options.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, record slog.Record) error {
+	buf := &bytes.Buffer{}
+
+	if !record.Time.IsZero() {
+		h.colorize(buf, colorDim, record.Time.Format("15:04:05.000"))
+		buf.WriteByte(' ')
+	}
+	levelName, levelColor := prettyLevel(record.Level)
+	h.colorize(buf, levelColor, levelName)
+	buf.WriteByte(' ')
+
+	if h.options.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		h.colorize(buf, colorDim, fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line))
+		buf.WriteByte(' ')
+	}
+
+	buf.WriteString(record.Message)
+	buf.Write(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		h.appendAttr(buf, h.prefix, attr)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.writer.Write(buf.Bytes())
+	return err
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	buf := bytes.NewBuffer(append([]byte(nil), h.attrs...))
+	for _, attr := range attrs {
+		h.appendAttr(buf, h.prefix, attr)
+	}
+	clone := *h
+	clone.attrs = buf.Bytes()
+	return &clone
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// appendAttr writes " key=value", flattening groups into dotted keys
+func (h *PrettyHandler) appendAttr(buf *bytes.Buffer, prefix string, attr slog.Attr) {
+	if h.options.ReplaceAttr != nil && attr.Value.Kind() != slog.KindGroup {
+		attr = h.options.ReplaceAttr(nil, attr)
+	}
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix += attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			h.appendAttr(buf, groupPrefix, groupAttr)
+		}
+		return
+	}
+
+	buf.WriteByte(' ')
+	h.colorize(buf, colorCyan, prefix+attr.Key)
+	buf.WriteByte('=')
+	buf.WriteString(prettyValue(attr.Value))
+}
+
+func (h *PrettyHandler) colorize(buf *bytes.Buffer, color, text string) {
+	if !h.color {
+		buf.WriteString(text)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(text)
+	buf.WriteString(colorReset)
+}
+
+func prettyLevel(level slog.Level) (string, string) {
+	switch {
+	case level >= slog.LevelError:
+		return "ERR", colorRed
+	case level >= slog.LevelWarn:
+		return "WRN", colorYellow
+	case level >= slog.LevelInfo:
+		return "INF", colorGreen
+	default:
+		return "DBG", colorBlue
+	}
+}
+
+// prettyValue formats a value, quoting strings that contain spaces
+func prettyValue(value slog.Value) string {
+	switch value.Kind() {
+	case slog.KindString:
+		s := value.String()
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			return fmt.Sprintf("%q", s)
+		}
+		return s
+	case slog.KindTime:
+		return value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return fmt.Sprintf("%q", err.Error())
+		}
+		return fmt.Sprintf("%+v", value.Any())
+	default:
+		return value.String()
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// rotatingFile is a log file rotated by size through lumberjack and, optionally, on a fixed interval
+type rotatingFile struct {
+	*lumberjack.Logger
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newRotatingFile opens the file sink described by sink
+func newRotatingFile(sink SinkConfig) (*rotatingFile, error) {
+	if sink.Path == "" {
+		return nil, errors.New("file log sink requires a path")
+	}
+	if err := os.MkdirAll(filepath.Dir(sink.Path), 0o755); err != nil {
+		return nil, err
+	}
+
+	file := &rotatingFile{
+		Logger: &lumberjack.Logger{
+			Filename:   sink.Path,
+			MaxSize:    sink.MaxSizeMB,
+			MaxBackups: sink.MaxBackups,
+			MaxAge:     sink.MaxAgeDays,
+			Compress:   sink.Compress,
+			LocalTime:  true,
+		},
+		stop: make(chan struct{}),
+	}
+
+	if sink.RotateInterval > 0 {
+		go file.rotateEvery(sink.RotateInterval)
+	}
+
+	return file, nil
+}
+
+// rotateEvery starts a new file at every interval until the sink is closed
+func (f *rotatingFile) rotateEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.Rotate(); err != nil {
+				// The logger cannot log its own failures, stderr is the last resort
+				_, _ = os.Stderr.WriteString("failed to rotate log file " + f.Filename + ": " + err.Error() + "\n")
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// Close stops interval rotation and closes the current file
+func (f *rotatingFile) Close() error {
+	f.stopOnce.Do(func() { close(f.stop) })
+	return f.Logger.Close()
+}
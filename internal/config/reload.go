@@ -0,0 +1,283 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/config/logger"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadablePaths lists the configuration paths that may change while the application is running.
+// A change to any other path causes the whole reload to be rejected.
+var reloadablePaths = []string{
+	"server.log_level",
+	"logging.redaction",
+	"logging.body",
+	"authorization",
+	"rate_limit.default",
+	"rate_limit.routes",
+	"rate_limit.pre_auth",
+	"features",
+}
+
+var (
+	current   atomic.Pointer[AppConfiguration]
+	watchOnce sync.Once
+
+	reloadMu     sync.Mutex
+	listeners    = map[int]func(config *AppConfiguration) error{}
+	nextListener int
+)
+
+// ConfigChange describes a single setting that differs between two configurations
+type ConfigChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+// Current returns the most recently applied configuration
+func Current() *AppConfiguration {
+	return current.Load()
+}
+
+// FeatureEnabled reports whether the named feature flag is switched on in the current configuration
+func FeatureEnabled(name string) bool {
+	config := Current()
+	if config == nil {
+		return false
+	}
+	return config.Features[name]
+}
+
+// OnReload registers a callback that applies a reloaded configuration. A callback returning an error rejects the
+// reload: every callback is then invoked again with the configuration in effect, which Current keeps returning.
+// Callbacks must therefore accept the same configuration more than once. Calling the returned function unregisters it.
+func OnReload(fn func(config *AppConfiguration) error) (unregister func()) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	id := nextListener
+	nextListener++
+	listeners[id] = fn
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			reloadMu.Lock()
+			defer reloadMu.Unlock()
+			delete(listeners, id)
+		})
+	}
+}
+
+// Watch reloads the configuration loaded by LoadConfig whenever its file changes or the process receives SIGHUP,
+// for the lifetime of the process. Only long running commands such as serve call it, calling it again does nothing.
+func Watch() {
+	watchOnce.Do(watchConfig)
+}
+
+// watchConfig reloads the configuration when the file changes on disk or the process receives SIGHUP.
+// Both triggers are handled by a single goroutine, viper is not safe for concurrent use. The directory is watched
+// rather than the file, so editors replacing the file and Kubernetes swapping a ConfigMap symlink are noticed.
+func watchConfig() {
+	triggers := make(chan string, 1)
+	trigger := func(name string) {
+		select {
+		case triggers <- name:
+		default:
+			// A reload is already pending, it reads the latest file
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			trigger("SIGHUP")
+		}
+	}()
+
+	configFile := filepath.Clean(viper.ConfigFileUsed())
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(filepath.Dir(configFile))
+	}
+	if err != nil {
+		logger.Logger.Error("Not watching config file, reload with SIGHUP", slog.String("file", configFile), slog.Any("error", err))
+	} else {
+		go func() {
+			realFile, _ := filepath.EvalSymlinks(configFile)
+			for event := range watcher.Events {
+				currentFile, _ := filepath.EvalSymlinks(configFile)
+				written := filepath.Clean(event.Name) == configFile && event.Has(fsnotify.Write|fsnotify.Create)
+				if written || (currentFile != "" && currentFile != realFile) {
+					realFile = currentFile
+					trigger("file change")
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for name := range triggers {
+			reloadConfig(name)
+		}
+	}()
+}
+
+// reloadConfig re-reads the configuration file and applies its whitelisted settings atomically
+func reloadConfig(trigger string) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	previous := current.Load()
+	if previous == nil {
+		logger.Logger.Warn("Config reload skipped, no configuration loaded yet", slog.String("trigger", trigger))
+		return
+	}
+	if err := viper.ReadInConfig(); err != nil {
+		logger.Logger.Error("Failed to re-read config file", slog.String("trigger", trigger), slog.Any("error", err))
+		return
+	}
+	next, err := unmarshalConfig()
+	if err != nil {
+		logger.Logger.Error("Config reload failed", slog.String("trigger", trigger), slog.Any("error", err))
+		return
+	}
+	if err := next.Validate(); err != nil {
+		logger.Logger.Warn("Config reload rejected, invalid settings", slog.String("trigger", trigger), slog.Any("error", err))
+		return
+	}
+
+	changes := diffConfig("", reflect.ValueOf(*previous), reflect.ValueOf(*next))
+	if len(changes) == 0 {
+		return
+	}
+
+	var rejected []string
+	for _, change := range changes {
+		if !isReloadable(change.Path) {
+			rejected = append(rejected, change.Path)
+		}
+	}
+	if len(rejected) > 0 {
+		logger.Logger.Warn("Config reload rejected, non-reloadable settings changed",
+			slog.String("trigger", trigger),
+			slog.Any("fields", rejected),
+		)
+		return
+	}
+
+	if err := notifyListeners(next); err != nil {
+		logger.Logger.Error("Config reload rejected, settings could not be applied", slog.String("trigger", trigger), slog.Any("error", err))
+		// Listeners that applied the new settings go back to those in effect
+		if err := notifyListeners(previous); err != nil {
+			logger.Logger.Error("Failed to restore the previous configuration", slog.String("trigger", trigger), slog.Any("error", err))
+		}
+		return
+	}
+	current.Store(next)
+	logger.SetLevel(next.Server.LoggLevel)
+
+	logger.Logger.Info("Config reloaded", slog.String("trigger", trigger), slog.Any("changes", changes))
+}
+
+// notifyListeners calls the listeners with config in registration order, up to the first one that fails
+func notifyListeners(config *AppConfiguration) error {
+	ids := make([]int, 0, len(listeners))
+	for id := range listeners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		if err := listeners[id](config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isReloadable reports whether path is, or is nested under, a whitelisted path
+func isReloadable(path string) bool {
+	for _, allowed := range reloadablePaths {
+		if path == allowed || strings.HasPrefix(path, allowed+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// diffConfig walks two configuration values and returns the paths of every leaf setting that differs
+func diffConfig(prefix string, previous, next reflect.Value) []ConfigChange {
+	switch previous.Kind() {
+	case reflect.Struct:
+		var changes []ConfigChange
+		for i := 0; i < previous.NumField(); i++ {
+			field := previous.Type().Field(i)
+			changes = append(changes, diffConfig(joinPath(prefix, configKey(field)), previous.Field(i), next.Field(i))...)
+		}
+		return changes
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, key := range append(previous.MapKeys(), next.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var changes []ConfigChange
+		for _, name := range names {
+			oldValue := previous.MapIndex(keys[name])
+			newValue := next.MapIndex(keys[name])
+			if !oldValue.IsValid() || !newValue.IsValid() {
+				changes = append(changes, ConfigChange{Path: joinPath(prefix, name), Old: valueOrNil(oldValue), New: valueOrNil(newValue)})
+				continue
+			}
+			changes = append(changes, diffConfig(joinPath(prefix, name), oldValue, newValue)...)
+		}
+		return changes
+	default:
+		if reflect.DeepEqual(previous.Interface(), next.Interface()) {
+			return nil
+		}
+		return []ConfigChange{{Path: prefix, Old: previous.Interface(), New: next.Interface()}}
+	}
+}
+
+// configKey returns the name viper uses for a struct field
+func configKey(field reflect.StructField) string {
+	if tag := field.Tag.Get("mapstructure"); tag != "" {
+		return strings.Split(tag, ",")[0]
+	}
+	return strings.ToLower(field.Name)
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func valueOrNil(value reflect.Value) any {
+	if !value.IsValid() {
+		return nil
+	}
+	return value.Interface()
+}
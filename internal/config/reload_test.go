@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// baseConfig is the smallest configuration file that passes Validate
+const baseConfig = `
+database:
+  host: localhost
+  port: 5432
+  user: banking
+  dbname: banking
+server:
+  port: 8080
+  log_level: info
+imports:
+  batch_size: 100
+  max_rows: 1000
+statements:
+  max_days: 366
+  branding:
+    color: "#1F4E79"
+features:
+  exports: false
+`
+
+// loadTestConfig loads content as the configuration in effect, with no listeners registered. Everything is
+// restored when the test ends.
+func loadTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeTestConfig(t, path, content)
+
+	previousListeners := listeners
+	listeners = map[int]func(config *AppConfiguration) error{}
+	t.Cleanup(func() {
+		viper.Reset()
+		current.Store(nil)
+		listeners = previousListeners
+	})
+
+	viper.Reset()
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("reading config: %v", err)
+	}
+	config, err := unmarshalConfig()
+	if err != nil {
+		t.Fatalf("unmarshal config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("base configuration is invalid: %v", err)
+	}
+	current.Store(config)
+	return path
+}
+
+func writeTestConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	listenerErr := errors.New("rejected by listener")
+
+	tests := []struct {
+		name string
+		// replace is applied to the base configuration to produce the reloaded file
+		replace     [2]string
+		listenerErr error
+		wantApplied bool
+		// wantNotified are the log levels the listener sees, in order
+		wantNotified []string
+	}{
+		{name: "whitelisted log level", replace: [2]string{"log_level: info", "log_level: debug"}, wantApplied: true, wantNotified: []string{"debug"}},
+		{name: "whitelisted feature flag", replace: [2]string{"exports: false", "exports: true"}, wantApplied: true, wantNotified: []string{"info"}},
+		{name: "rejected database host", replace: [2]string{"host: localhost", "host: db.internal"}},
+		{name: "rejected server port with a whitelisted change", replace: [2]string{"port: 8080\n  log_level: info", "port: 9090\n  log_level: debug"}},
+		{name: "invalid log level", replace: [2]string{"log_level: info", "log_level: loud"}},
+		{name: "unchanged", replace: [2]string{"", ""}},
+		{
+			name:         "listener failure restores the configuration in effect",
+			replace:      [2]string{"log_level: info", "log_level: debug"},
+			listenerErr:  listenerErr,
+			wantNotified: []string{"debug", "info"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := loadTestConfig(t, baseConfig)
+			previous := Current()
+
+			var notified []string
+			OnReload(func(config *AppConfiguration) error {
+				notified = append(notified, config.Server.LoggLevel)
+				if config.Server.LoggLevel != previous.Server.LoggLevel {
+					return tt.listenerErr
+				}
+				return nil
+			})
+
+			writeTestConfig(t, path, strings.Replace(baseConfig, tt.replace[0], tt.replace[1], 1))
+			reloadConfig("test")
+
+			if applied := Current() != previous; applied != tt.wantApplied {
+				t.Errorf("reload applied = %v, want %v", applied, tt.wantApplied)
+			}
+			if strings.Join(notified, ",") != strings.Join(tt.wantNotified, ",") {
+				t.Errorf("listener notified with %v, want %v", notified, tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestReloadConfigWithoutConfiguration(t *testing.T) {
+	loadTestConfig(t, baseConfig)
+	current.Store(nil)
+
+	reloadConfig("test")
+	if Current() != nil {
+		t.Error("reload without a loaded configuration stored one")
+	}
+}
+
+func TestOnReloadUnregister(t *testing.T) {
+	path := loadTestConfig(t, baseConfig)
+
+	calls := 0
+	unregister := OnReload(func(*AppConfiguration) error {
+		calls++
+		return nil
+	})
+	unregister()
+	unregister()
+
+	writeTestConfig(t, path, strings.Replace(baseConfig, "log_level: info", "log_level: debug", 1))
+	reloadConfig("test")
+	if calls != 0 {
+		t.Errorf("unregistered listener called %d times", calls)
+	}
+}
+
+func TestReloadConfigSerialized(t *testing.T) {
+	path := loadTestConfig(t, baseConfig)
+	debugConfig := strings.Replace(baseConfig, "log_level: info", "log_level: debug", 1)
+
+	var running, overlapped atomic.Int32
+	OnReload(func(*AppConfiguration) error {
+		if running.Add(1) > 1 {
+			overlapped.Add(1)
+		}
+		defer running.Add(-1)
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				writeTestConfig(t, path, debugConfig)
+			} else {
+				writeTestConfig(t, path, baseConfig)
+			}
+			reloadConfig("test")
+		}()
+	}
+	wg.Wait()
+
+	if overlapped.Load() > 0 {
+		t.Errorf("listeners ran concurrently %d times", overlapped.Load())
+	}
+	writeTestConfig(t, path, debugConfig)
+	reloadConfig("test")
+	if level := Current().Server.LoggLevel; level != "debug" {
+		t.Errorf("log level after the last reload = %q, want debug", level)
+	}
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/services"
+	"org/gg/banking/internal/statements"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IAccountStatementController defines the interface for the account statement handler
+type IAccountStatementController interface {
+	GetStatement(ctx *gin.Context)
+}
+
+type accountStatementController struct {
+	statementService services.IAccountStatementService
+	renderers        map[string]statements.IRenderer
+}
+
+// NewAccountStatementController creates a controller answering in the formats of renderers
+func NewAccountStatementController(service services.IAccountStatementService, renderers map[string]statements.IRenderer) IAccountStatementController {
+	return &accountStatementController{
+		statementService: service,
+		renderers:        renderers,
+	}
+}
+
+// GetStatement handles the HTTP request to download the statement of an account for the days from and to, as
+// format=pdf, the default, or format=csv
+func (c *accountStatementController) GetStatement(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", statements.FormatPDF)
+	renderer, ok := c.renderers[format]
+	if !ok {
+		formats := make([]string, 0, len(c.renderers))
+		for name := range c.renderers {
+			formats = append(formats, name)
+		}
+		slices.Sort(formats)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of " + strings.Join(formats, ", ")})
+		return
+	}
+
+	request := models.AccountStatementRequest{AccountNumber: ctx.Param("account_number")}
+	var errFrom, errTo error
+	request.From, errFrom = time.Parse(time.DateOnly, ctx.Query("from"))
+	request.To, errTo = time.Parse(time.DateOnly, ctx.Query("to"))
+	if errFrom != nil || errTo != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "from and to are required dates such as 2026-01-31"})
+		return
+	}
+
+	statement, err := c.statementService.Statement(ctx.Request.Context(), request)
+	if err != nil {
+		ctx.Error(fmt.Errorf("building statement: %w", err))
+		return
+	}
+
+	// A statement is small, rendering it whole lets a failure still be answered with an error
+	var document bytes.Buffer
+	if err := renderer.Render(&document, statement); err != nil {
+		ctx.Error(fmt.Errorf("rendering statement: %w", err))
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s-%s.%s", statement.Account.AccountNumber,
+		statement.From.Format(time.DateOnly), statement.To.Format(time.DateOnly), format)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Data(http.StatusOK, renderer.ContentType(), document.Bytes())
+}
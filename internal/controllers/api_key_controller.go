@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IAPIKeyController defines the interface for the API key administration handlers
+type IAPIKeyController interface {
+	GetAPIKeys(ctx *gin.Context)
+	IssueAPIKey(ctx *gin.Context)
+	RotateAPIKey(ctx *gin.Context)
+	RevokeAPIKey(ctx *gin.Context)
+}
+
+type apiKeyController struct {
+	apiKeyService services.IAPIKeyService
+}
+
+func NewAPIKeyController(service services.IAPIKeyService) IAPIKeyController {
+	return &apiKeyController{
+		apiKeyService: service,
+	}
+}
+
+// GetAPIKeys handles the HTTP request to list api keys, secrets are never returned
+func (c *apiKeyController) GetAPIKeys(ctx *gin.Context) {
+	keys, err := c.apiKeyService.List(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(fmt.Errorf("getting api key list: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// IssueAPIKey handles the HTTP request to issue a key, the response is the only place its secret appears
+func (c *apiKeyController) IssueAPIKey(ctx *gin.Context) {
+	var request models.IssueAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	issued, err := c.apiKeyService.Issue(ctx.Request.Context(), request)
+	if err != nil {
+		ctx.Error(fmt.Errorf("issuing api key: %w", err))
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, issued)
+}
+
+// RotateAPIKey handles the HTTP request to replace a key, the old key stays valid for the rotation grace period
+func (c *apiKeyController) RotateAPIKey(ctx *gin.Context) {
+	id, ok := apiKeyID(ctx)
+	if !ok {
+		return
+	}
+
+	issued, err := c.apiKeyService.Rotate(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(fmt.Errorf("rotating api key: %w", err))
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, issued)
+}
+
+// RevokeAPIKey handles the HTTP request to revoke a key
+func (c *apiKeyController) RevokeAPIKey(ctx *gin.Context) {
+	id, ok := apiKeyID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.apiKeyService.Revoke(ctx.Request.Context(), id); err != nil {
+		ctx.Error(fmt.Errorf("revoking api key: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "API key successfully revoked"})
+}
+
+// apiKeyID parses the id path parameter, answering 400 when it is not a number
+func apiKeyID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "API key id must be a number"})
+		return 0, false
+	}
+	return id, true
+}
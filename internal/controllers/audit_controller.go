@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IAuditController defines the interface for the read-only audit log handlers
+type IAuditController interface {
+	GetAuditLog(ctx *gin.Context)
+}
+
+type auditController struct {
+	auditService services.IAuditService
+}
+
+func NewAuditController(service services.IAuditService) IAuditController {
+	return &auditController{
+		auditService: service,
+	}
+}
+
+// GetAuditLog handles the HTTP request to list audit entries, filtered by the query parameters
+// actor, action, entity_type, entity_id, request_id, from and to (RFC 3339), before_id and limit
+func (c *auditController) GetAuditLog(ctx *gin.Context) {
+	filter := models.AuditFilter{
+		Actor:      ctx.Query("actor"),
+		Action:     ctx.Query("action"),
+		EntityType: ctx.Query("entity_type"),
+		EntityID:   ctx.Query("entity_id"),
+		RequestID:  ctx.Query("request_id"),
+	}
+
+	var err error
+	if filter.From, err = timeQuery(ctx, "from"); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.To, err = timeQuery(ctx, "to"); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.BeforeID, err = intQuery(ctx, "before_id"); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := intQuery(ctx, "limit")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Limit = int(limit)
+
+	entries, err := c.auditService.Find(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.Error(fmt.Errorf("getting audit log: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
+
+// timeQuery parses an optional RFC 3339 query parameter
+func timeQuery(ctx *gin.Context, name string) (time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return parsed, nil
+}
+
+// intQuery parses an optional positive integer query parameter
+func intQuery(ctx *gin.Context, name string) (int64, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return parsed, nil
+}
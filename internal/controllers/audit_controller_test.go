@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditServiceStub records the filter it was called with
+type auditServiceStub struct {
+	filter *models.AuditFilter
+}
+
+func (s *auditServiceStub) Find(_ context.Context, filter models.AuditFilter) ([]models.AuditEntryDTO, error) {
+	s.filter = &filter
+	return []models.AuditEntryDTO{}, nil
+}
+
+func TestGetAuditLogFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantFilter models.AuditFilter
+		wantStatus int
+	}{
+		{name: "no filter", wantStatus: http.StatusOK},
+		{
+			name:  "every filter",
+			query: "?actor=user-1&action=delete&entity_type=customer&entity_id=john.doe@example.com&request_id=req-42&from=2026-02-01T00:00:00Z&to=2026-02-02T00:00:00%2B02:00&before_id=500&limit=50",
+			wantFilter: models.AuditFilter{
+				Actor:      "user-1",
+				Action:     "delete",
+				EntityType: "customer",
+				EntityID:   "john.doe@example.com",
+				RequestID:  "req-42",
+				From:       time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+				To:         time.Date(2026, 2, 1, 22, 0, 0, 0, time.UTC),
+				BeforeID:   500,
+				Limit:      50,
+			},
+			wantStatus: http.StatusOK,
+		},
+		{name: "date without time", query: "?from=2026-02-01", wantStatus: http.StatusBadRequest},
+		{name: "invalid to", query: "?to=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "zero limit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "negative before id", query: "?before_id=-1", wantStatus: http.StatusBadRequest},
+		{name: "non-numeric limit", query: "?limit=all", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &auditServiceStub{}
+			router := gin.New()
+			router.GET("/audit", NewAuditController(service).GetAuditLog)
+
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/audit"+tt.query, nil))
+
+			if response.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.Code, tt.wantStatus, response.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if service.filter != nil {
+					t.Error("the audit log was read for an invalid filter")
+				}
+				return
+			}
+			// Compare instants, the parsed times keep the offset of the query
+			got := *service.filter
+			if !got.To.Equal(tt.wantFilter.To) {
+				t.Errorf("to = %s, want %s", got.To, tt.wantFilter.To)
+			}
+			got.To = tt.wantFilter.To
+			if !reflect.DeepEqual(got, tt.wantFilter) {
+				t.Errorf("filter = %+v, want %+v", got, tt.wantFilter)
+			}
+		})
+	}
+}
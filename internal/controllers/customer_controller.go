@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"org/gg/banking/internal/models"
 	"org/gg/banking/internal/services"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,9 +28,31 @@ func NewCustomerController(service services.ICustomerService) ICustomerControlle
 	}
 }
 
-// GetCustomers handles the HTTP request to fetch all customers
+// GetCustomers handles the HTTP request to fetch all customers. With expand=accounts it fetches a page of customers
+// with their accounts, selected by limit and offset.
 func (c *customerController) GetCustomers(ctx *gin.Context) {
-	customers, err := c.customerService.FindAll()
+	var customers []models.CustomerDTO
+	var err error
+	switch ctx.Query("expand") {
+	case "":
+		customers, err = c.customerService.FindAll(ctx.Request.Context())
+	case "accounts":
+		limit, limitErr := intQuery(ctx, "limit")
+		if limitErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": limitErr.Error()})
+			return
+		}
+		offset, offsetErr := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+		if offsetErr != nil || offset < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		page := models.CustomerPage{Limit: int(limit), Offset: offset}
+		customers, err = c.customerService.FindAllWithAccounts(ctx.Request.Context(), page)
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "expand must be accounts"})
+		return
+	}
 	if err != nil {
 		ctx.Error(fmt.Errorf("getting customer list: %w", err))
 		return
@@ -45,7 +68,7 @@ func (c *customerController) GetCustomerByEmail(ctx *gin.Context) {
 		return
 	}
 
-	customer, err := c.customerService.FindCustomerWithAccounts(email)
+	customer, err := c.customerService.FindCustomerWithAccounts(ctx.Request.Context(), email)
 	if err != nil {
 		ctx.Error(fmt.Errorf("getting customer by email: %w", err))
 		return
@@ -62,7 +85,7 @@ func (c *customerController) CreateCustomer(ctx *gin.Context) {
 		return
 	}
 
-	createdCustomer, err := c.customerService.CreateCustomer(customer)
+	createdCustomer, err := c.customerService.CreateCustomer(ctx.Request.Context(), customer)
 	if err != nil {
 		ctx.Error(fmt.Errorf("creating customer: %w", err))
 		return
@@ -79,7 +102,7 @@ func (c *customerController) DeleteCustomerByEmail(ctx *gin.Context) {
 		return
 	}
 
-	err := c.customerService.DeleteCustomerByEmail(email)
+	err := c.customerService.DeleteCustomerByEmail(ctx.Request.Context(), email)
 	if err != nil {
 		ctx.Error(fmt.Errorf("deleting customer by email: %w", err))
 		return
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"org/gg/banking/internal/exports"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ICustomerExportController defines the interface for the bulk customer export handler
+type ICustomerExportController interface {
+	ExportCustomers(ctx *gin.Context)
+}
+
+type customerExportController struct {
+	exportService services.ICustomerExportService
+}
+
+func NewCustomerExportController(service services.ICustomerExportService) ICustomerExportController {
+	return &customerExportController{
+		exportService: service,
+	}
+}
+
+// ExportCustomers handles the HTTP request to download customers as CSV, NDJSON or XLSX, chosen with the Accept
+// header. The query parameters expand, limit, offset and columns select the rows and the columns.
+func (c *customerExportController) ExportCustomers(ctx *gin.Context) {
+	contentType := ctx.NegotiateFormat(exports.ContentTypes...)
+	if contentType == "" {
+		ctx.JSON(http.StatusNotAcceptable, gin.H{"error": "Accept must allow " + strings.Join(exports.ContentTypes, ", ")})
+		return
+	}
+
+	request := models.CustomerExportRequest{Expand: ctx.Query("expand")}
+	if columns := ctx.Query("columns"); columns != "" {
+		request.Columns = strings.Split(columns, ",")
+	}
+	limit, err := intQuery(ctx, "limit")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+		return
+	}
+	request.Page = models.CustomerPage{Limit: int(limit), Offset: offset}
+
+	err = c.exportService.Export(ctx.Request.Context(), request, func(columns []string) (exports.IWriter, error) {
+		filename := fmt.Sprintf("customers-%s.%s", time.Now().UTC().Format("20060102"), exports.Extension(contentType))
+		ctx.Header("Content-Type", contentType)
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		ctx.Status(http.StatusOK)
+		return exports.NewWriter(contentType, ctx.Writer, columns)
+	})
+	if err != nil {
+		if !ctx.Writer.Written() {
+			// Nothing was sent yet, the problem details replace the file
+			ctx.Writer.Header().Del("Content-Type")
+			ctx.Writer.Header().Del("Content-Disposition")
+		}
+		ctx.Error(fmt.Errorf("exporting customers: %w", err))
+	}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"org/gg/banking/internal/imports"
+	"org/gg/banking/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ICustomerImportController defines the interface for the bulk customer import handler
+type ICustomerImportController interface {
+	ImportCustomers(ctx *gin.Context)
+}
+
+type customerImportController struct {
+	importService services.ICustomerImportService
+}
+
+func NewCustomerImportController(service services.ICustomerImportService) ICustomerImportController {
+	return &customerImportController{
+		importService: service,
+	}
+}
+
+// ImportCustomers handles the HTTP request to import customers from a CSV or NDJSON body, read as it arrives.
+// With dry_run=true the rows are only validated and checked against the existing customers.
+func (c *customerImportController) ImportCustomers(ctx *gin.Context) {
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	reader, err := imports.NewReader(ctx.GetHeader("Content-Type"), ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": fmt.Sprintf("Content-Type must be %s or %s", imports.ContentTypeCSV, imports.ContentTypeNDJSON),
+		})
+		return
+	}
+
+	report, err := c.importService.Import(ctx.Request.Context(), reader, dryRun)
+	if err != nil {
+		ctx.Error(fmt.Errorf("importing customers: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
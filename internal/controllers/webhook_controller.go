@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IWebhookController defines the interface for the webhook subscription handlers
+type IWebhookController interface {
+	GetWebhooks(ctx *gin.Context)
+	CreateWebhook(ctx *gin.Context)
+	DeleteWebhook(ctx *gin.Context)
+	GetWebhookDeliveries(ctx *gin.Context)
+	RetryWebhookDelivery(ctx *gin.Context)
+}
+
+type webhookController struct {
+	webhookService services.IWebhookService
+}
+
+func NewWebhookController(service services.IWebhookService) IWebhookController {
+	return &webhookController{
+		webhookService: service,
+	}
+}
+
+// GetWebhooks handles the HTTP request to list subscriptions, secrets are never returned
+func (c *webhookController) GetWebhooks(ctx *gin.Context) {
+	subscriptions, err := c.webhookService.List(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(fmt.Errorf("getting webhook list: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscriptions)
+}
+
+// CreateWebhook handles the HTTP request to register a subscription, the response is the only place its secret appears
+func (c *webhookController) CreateWebhook(ctx *gin.Context) {
+	var request models.CreateWebhookSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	created, err := c.webhookService.Create(ctx.Request.Context(), request)
+	if err != nil {
+		ctx.Error(fmt.Errorf("creating webhook: %w", err))
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, created)
+}
+
+// DeleteWebhook handles the HTTP request to delete a subscription together with its delivery history
+func (c *webhookController) DeleteWebhook(ctx *gin.Context) {
+	id, ok := idParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	if err := c.webhookService.Delete(ctx.Request.Context(), id); err != nil {
+		ctx.Error(fmt.Errorf("deleting webhook: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook successfully deleted"})
+}
+
+// GetWebhookDeliveries handles the HTTP request to list the deliveries of a subscription, filtered by the
+// query parameters status, before_id and limit
+func (c *webhookController) GetWebhookDeliveries(ctx *gin.Context) {
+	id, ok := idParam(ctx, "id")
+	if !ok {
+		return
+	}
+
+	filter := models.WebhookDeliveryFilter{
+		SubscriptionID: id,
+		Status:         ctx.Query("status"),
+	}
+	var err error
+	if filter.BeforeID, err = intQuery(ctx, "before_id"); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, err := intQuery(ctx, "limit")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Limit = int(limit)
+
+	deliveries, err := c.webhookService.Deliveries(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.Error(fmt.Errorf("getting webhook deliveries: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// RetryWebhookDelivery handles the HTTP request to queue a dead delivery again
+func (c *webhookController) RetryWebhookDelivery(ctx *gin.Context) {
+	id, ok := idParam(ctx, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := idParam(ctx, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := c.webhookService.Redeliver(ctx.Request.Context(), id, deliveryID)
+	if err != nil {
+		ctx.Error(fmt.Errorf("retrying webhook delivery: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, delivery)
+}
+
+// idParam parses a numeric path parameter, answering 400 when it is not a number
+func idParam(ctx *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param(name), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be a number", name)})
+		return 0, false
+	}
+	return id, true
+}
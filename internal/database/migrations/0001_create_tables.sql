@@ -1,5 +1,8 @@
+-- Databases initialized by the former docker-entrypoint script already have these tables,
+-- IF NOT EXISTS lets them adopt the migrations as is
+
 -- Create customers table
-CREATE TABLE customers
+CREATE TABLE IF NOT EXISTS customers
 (
     id         SERIAL PRIMARY KEY,
     first_name VARCHAR(100)        NOT NULL,
@@ -9,7 +12,7 @@ CREATE TABLE customers
 );
 
 -- Create accounts table
-CREATE TABLE accounts
+CREATE TABLE IF NOT EXISTS accounts
 (
     id                  SERIAL PRIMARY KEY,
     customer_id         INTEGER            NOT NULL,
@@ -23,4 +26,4 @@ CREATE TABLE accounts
 );
 
 -- Create index on foreign key for performance
-CREATE INDEX idx_accounts_customer_id ON accounts (customer_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_accounts_customer_id ON accounts (customer_id);
\ No newline at end of file
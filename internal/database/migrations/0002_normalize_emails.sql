@@ -0,0 +1,5 @@
+-- Emails are stored in lower case, see models.NormalizeEmail, so lookups by email can stay exact matches.
+-- Customers whose emails only differ in case must be merged before this migration can run.
+UPDATE customers SET email = lower(email) WHERE email <> lower(email);
+
+ALTER TABLE customers ADD CONSTRAINT customers_email_lower_case CHECK (email = lower(email));
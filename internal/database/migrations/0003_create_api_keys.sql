@@ -0,0 +1,15 @@
+-- Create api_keys table, only a hash of each secret is stored
+CREATE TABLE api_keys
+(
+    id           SERIAL PRIMARY KEY,
+    prefix       VARCHAR(16) UNIQUE NOT NULL,
+    secret_hash  CHAR(64)           NOT NULL,
+    name         VARCHAR(100)       NOT NULL,
+    owner_email  VARCHAR(100),
+    scopes       TEXT[]             NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMP          NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at   TIMESTAMP,
+    rotated_from INTEGER REFERENCES api_keys (id)
+);
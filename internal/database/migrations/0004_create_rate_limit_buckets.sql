@@ -0,0 +1,11 @@
+-- Token buckets of the postgres rate limit store, shared by every instance.
+-- Unlogged, losing buckets on a crash only resets the limits.
+CREATE UNLOGGED TABLE rate_limit_buckets
+(
+    key        TEXT             PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    allowed    BOOLEAN          NOT NULL,
+    updated_at TIMESTAMPTZ      NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
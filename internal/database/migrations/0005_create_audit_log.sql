@@ -0,0 +1,38 @@
+-- Create audit_log table, an append-only record of every data change
+CREATE TABLE audit_log
+(
+    id          BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor       VARCHAR(255) NOT NULL,
+    action      VARCHAR(20)  NOT NULL,
+    entity_type VARCHAR(50)  NOT NULL,
+    entity_id   VARCHAR(100) NOT NULL,
+    before      JSONB,
+    after       JSONB,
+    request_id  VARCHAR(128),
+    client_ip   VARCHAR(45)
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log (entity_type, entity_id);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor);
+CREATE INDEX idx_audit_log_occurred_at ON audit_log (occurred_at);
+
+-- Reject any change to recorded entries
+CREATE FUNCTION audit_log_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_or_delete
+    BEFORE UPDATE OR DELETE
+    ON audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE
+    ON audit_log
+    FOR EACH STATEMENT
+EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,9 @@
+-- Chain audit_log entries: each entry stores the SHA-256 of its content and of the previous entry's hash,
+-- so altering, removing or reordering a recorded entry breaks every later link.
+-- Entries recorded before this migration keep NULL hashes and precede the chain.
+ALTER TABLE audit_log
+    ADD COLUMN prev_hash CHAR(64),
+    ADD COLUMN hash      CHAR(64);
+
+-- A fork, two entries claiming the same predecessor, is rejected outright
+CREATE UNIQUE INDEX idx_audit_log_prev_hash ON audit_log (prev_hash);
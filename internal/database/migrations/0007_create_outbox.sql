@@ -0,0 +1,20 @@
+-- Create outbox table, domain events written in the transaction of the change and published by the relay
+CREATE TABLE outbox
+(
+    id              BIGSERIAL PRIMARY KEY,
+    event_id        UUID         NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    event_type      VARCHAR(50)  NOT NULL,
+    aggregate_type  VARCHAR(50)  NOT NULL,
+    aggregate_id    VARCHAR(100) NOT NULL,
+    payload         JSONB        NOT NULL,
+    occurred_at     TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    request_id      VARCHAR(128),
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error      TEXT,
+    published_at    TIMESTAMPTZ
+);
+
+-- The relay only looks for pending events that are due
+CREATE INDEX idx_outbox_pending ON outbox (next_attempt_at, id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published_at ON outbox (published_at) WHERE published_at IS NOT NULL;
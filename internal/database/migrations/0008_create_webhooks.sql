@@ -0,0 +1,38 @@
+-- Create webhook_subscriptions table, partner endpoints receiving domain events.
+-- The secret is kept in clear because every delivery is signed with it.
+CREATE TABLE webhook_subscriptions
+(
+    id          BIGSERIAL PRIMARY KEY,
+    url         VARCHAR(2048) NOT NULL,
+    event_types TEXT[]        NOT NULL,
+    secret      VARCHAR(100)  NOT NULL,
+    description VARCHAR(255)  NOT NULL DEFAULT '',
+    owner_email VARCHAR(100),
+    created_by  VARCHAR(255)  NOT NULL,
+    created_at  TIMESTAMPTZ   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_subscriptions_owner_email ON webhook_subscriptions (owner_email);
+
+-- Create webhook_deliveries table, one row per event and subscription with its delivery state:
+-- pending until delivered, or dead once every attempt failed
+CREATE TABLE webhook_deliveries
+(
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  BIGINT      NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id         UUID        NOT NULL,
+    event_type       VARCHAR(50) NOT NULL,
+    payload          JSONB       NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts         INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INTEGER,
+    last_error       TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at     TIMESTAMPTZ,
+    -- The outbox relay delivers at least once, an event is only queued once per subscription
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at, id) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id);
@@ -0,0 +1,3 @@
+-- Record the customer each outbox event is about, webhook subscriptions owned by a customer only receive their events.
+-- Events recorded before have no customer and only reach subscriptions without an owner.
+ALTER TABLE outbox ADD COLUMN customer_email VARCHAR(100);
@@ -0,0 +1,18 @@
+-- Create account_movements table, the ledger of every change to an account balance.
+-- The balance of an account is the sum of its movements, statements are built from them.
+CREATE TABLE account_movements
+(
+    id          BIGSERIAL PRIMARY KEY,
+    account_id  INTEGER        NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    amount      DECIMAL(15, 2) NOT NULL,
+    description TEXT           NOT NULL,
+    occurred_at TIMESTAMPTZ    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_account_movements_account ON account_movements (account_id, occurred_at, id);
+
+-- Balances were only ever set when an account was opened, so that is the one movement of existing accounts
+INSERT INTO account_movements (account_id, amount, description, occurred_at)
+SELECT id, balance, 'Opening deposit', created_at
+FROM accounts
+WHERE balance <> 0;
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"org/gg/banking/internal/config"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// connectTimeout bounds how long Connect waits for the database to answer the initial ping
+const connectTimeout = 5 * time.Second
+
+// dsnValueEscaper escapes a value placed between single quotes in a lib/pq connection string
+var dsnValueEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// Connect opens a connection pool to Postgres and verifies it is reachable
+func Connect(ctx context.Context, dbConfig config.DatabaseConfiguration) (*sql.DB, error) {
+	// Create connection string from config
+	driverName := "postgres"
+	connectionString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.DBName)
+	if dbConfig.ApplicationName != "" {
+		connectionString += fmt.Sprintf(" application_name='%s'", dsnValueEscaper.Replace(dbConfig.ApplicationName))
+	}
+
+	// Open database connection
+	db, err := sql.Open(driverName, connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// Ping the database to verify connection
+	pingCtx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+	if err := db.PingContext(pingCtx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"org/gg/banking/internal/models"
+)
+
+type fanoutPublisher struct {
+	publishers []IEventPublisher
+}
+
+// NewFanoutPublisher creates a publisher handing every event to each of publishers. An event is only confirmed
+// once all of them accepted it, so a failure retries the event for every publisher, which must therefore be idempotent.
+func NewFanoutPublisher(publishers ...IEventPublisher) IEventPublisher {
+	if len(publishers) == 1 {
+		return publishers[0]
+	}
+	return &fanoutPublisher{publishers: publishers}
+}
+
+func (p *fanoutPublisher) Publish(ctx context.Context, event models.Event) error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"org/gg/banking/internal/models"
+)
+
+type logPublisher struct {
+	logger *slog.Logger
+}
+
+// NewLogPublisher creates a publisher that writes every event to the log, for development and as a placeholder
+// until a message broker is wired in
+func NewLogPublisher(logger *slog.Logger) IEventPublisher {
+	return &logPublisher{logger: logger}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, event models.Event) error {
+	p.logger.InfoContext(ctx, "Domain event published",
+		slog.String("event_id", event.ID),
+		slog.String("event_type", event.Type),
+		slog.String("aggregate_type", event.AggregateType),
+		slog.String("aggregate_id", event.AggregateID),
+		slog.String("event_request_id", event.RequestID),
+		// The payload carries personal data, only its size is logged
+		slog.Int("payload_bytes", len(event.Payload)),
+	)
+	return nil
+}
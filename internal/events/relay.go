@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/metrics"
+	"org/gg/banking/internal/models"
+	"time"
+)
+
+// purgeInterval is how often published events past the retention are deleted
+const purgeInterval = time.Hour
+
+// IEventPublisher delivers domain events to other systems. Publish must be safe to call again with an event
+// it already delivered, the relay retries whenever it cannot confirm a delivery.
+type IEventPublisher interface {
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// IOutboxStore is the outbox the relay drains, implemented by the outbox repository
+type IOutboxStore interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, outboxID int64) error
+	MarkFailed(ctx context.Context, outboxID int64, reason string, retryAt time.Time) error
+	Purge(ctx context.Context, publishedBefore time.Time) (int64, error)
+}
+
+// Relay publishes the events written to the outbox. An event is only marked published after the publisher
+// accepted it, so delivery is at least once: a crash between the two publishes it again. Failed deliveries are
+// retried with exponential backoff until they succeed.
+type Relay struct {
+	store     IOutboxStore
+	publisher IEventPublisher
+	config    config.EventRelayConfiguration
+	logger    *slog.Logger
+}
+
+func NewRelay(store IOutboxStore, publisher IEventPublisher, cfg config.EventRelayConfiguration, logger *slog.Logger) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		config:    cfg,
+		logger:    logger,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+
+	for {
+		// Drain full batches without waiting for the next tick
+		for r.publishBatch(ctx) == r.config.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		if r.config.Retention > 0 && time.Since(lastPurge) >= purgeInterval {
+			r.purge(ctx)
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishBatch publishes one batch of due events and returns how many were claimed
+func (r *Relay) publishBatch(ctx context.Context) int {
+	claimed, err := r.store.Claim(ctx, r.config.BatchSize, r.config.Lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "Failed to claim outbox events", slog.Any("error", err))
+		}
+		return 0
+	}
+
+	for _, outboxEvent := range claimed {
+		if ctx.Err() != nil {
+			// Unpublished claims become due again when their lease expires
+			return 0
+		}
+		r.publish(ctx, outboxEvent)
+	}
+	return len(claimed)
+}
+
+func (r *Relay) publish(ctx context.Context, outboxEvent models.OutboxEvent) {
+	event := outboxEvent.Event
+	attributes := []any{
+		slog.String("event_id", event.ID),
+		slog.String("event_type", event.Type),
+		slog.Int("attempt", outboxEvent.Attempts),
+	}
+
+	if err := r.publisher.Publish(ctx, event); err != nil {
+		metrics.EventPublishFailures.WithLabelValues(event.Type).Inc()
+		retryAt := time.Now().Add(Backoff(r.config.RetryInitial, r.config.RetryMax, outboxEvent.Attempts))
+		r.logger.WarnContext(ctx, "Failed to publish event, will retry", append(attributes, slog.Time("retry_at", retryAt), slog.Any("error", err))...)
+		if err := r.store.MarkFailed(ctx, outboxEvent.OutboxID, err.Error(), retryAt); err != nil {
+			r.logger.ErrorContext(ctx, "Failed to record event delivery failure", append(attributes, slog.Any("error", err))...)
+		}
+		return
+	}
+
+	metrics.EventsPublished.WithLabelValues(event.Type).Inc()
+	if err := r.store.MarkPublished(ctx, outboxEvent.OutboxID); err != nil {
+		r.logger.ErrorContext(ctx, "Failed to mark event published, it will be published again", append(attributes, slog.Any("error", err))...)
+	}
+}
+
+// Backoff returns the delay before retrying after the given number of attempts.
+// It doubles with every attempt, from initial up to maxDelay.
+func Backoff(initial, maxDelay time.Duration, attempts int) time.Duration {
+	delay := initial
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	deleted, err := r.store.Purge(ctx, time.Now().Add(-r.config.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.ErrorContext(ctx, "Failed to purge published outbox events", slog.Any("error", err))
+		}
+		return
+	}
+	if deleted > 0 {
+		r.logger.InfoContext(ctx, "Purged published outbox events", slog.Int64("deleted", deleted))
+	}
+}
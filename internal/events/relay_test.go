@@ -0,0 +1,294 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/models"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// outboxStub is an in-memory outbox with the claim and lease rules of the outbox repository
+type outboxStub struct {
+	mu        sync.Mutex
+	events    []*outboxEntry
+	markErr   error
+	purgedAt  []time.Time
+	claimErrs int
+}
+
+type outboxEntry struct {
+	event     models.OutboxEvent
+	dueAt     time.Time
+	published bool
+	lastError string
+}
+
+func newOutboxStub(eventTypes ...string) *outboxStub {
+	store := &outboxStub{}
+	for i, eventType := range eventTypes {
+		store.events = append(store.events, &outboxEntry{event: models.OutboxEvent{
+			OutboxID: int64(i + 1),
+			Event:    models.Event{ID: eventType + "-id", Type: eventType},
+		}})
+	}
+	return store
+}
+
+func (s *outboxStub) Claim(_ context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimErrs > 0 {
+		s.claimErrs--
+		return nil, errors.New("connection refused")
+	}
+
+	now := time.Now()
+	var claimed []models.OutboxEvent
+	for _, entry := range s.events {
+		if len(claimed) == limit {
+			break
+		}
+		if entry.published || entry.dueAt.After(now) {
+			continue
+		}
+		entry.event.Attempts++
+		entry.dueAt = now.Add(lease)
+		claimed = append(claimed, entry.event)
+	}
+	return claimed, nil
+}
+
+func (s *outboxStub) MarkPublished(_ context.Context, outboxID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.markErr != nil {
+		return s.markErr
+	}
+	entry := s.events[outboxID-1]
+	entry.published, entry.lastError = true, ""
+	return nil
+}
+
+func (s *outboxStub) MarkFailed(_ context.Context, outboxID int64, reason string, retryAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.events[outboxID-1]
+	entry.lastError, entry.dueAt = reason, retryAt
+	return nil
+}
+
+func (s *outboxStub) Purge(_ context.Context, publishedBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgedAt = append(s.purgedAt, publishedBefore)
+	return 0, nil
+}
+
+func (s *outboxStub) entry(outboxID int64) outboxEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.events[outboxID-1]
+}
+
+// recordingPublisher records the published event types and fails those listed in failing
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []string
+	failing   map[string]bool
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing[event.Type] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event.Type)
+	return nil
+}
+
+func (p *recordingPublisher) publishedTypes() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.published)
+}
+
+func testRelayConfig() config.EventRelayConfiguration {
+	return config.EventRelayConfiguration{
+		PollInterval: time.Hour,
+		BatchSize:    2,
+		Lease:        time.Minute,
+		RetryInitial: time.Second,
+		RetryMax:     time.Minute,
+	}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Second},
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 6, want: 32 * time.Second},
+		{attempts: 7, want: time.Minute},
+		{attempts: 1000, want: time.Minute},
+	}
+	for _, tt := range tests {
+		if got := Backoff(time.Second, time.Minute, tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRelayPublishBatch(t *testing.T) {
+	store := newOutboxStub("CustomerCreated", "AccountOpened", "CustomerDeleted")
+	publisher := &recordingPublisher{failing: map[string]bool{"AccountOpened": true}}
+	relay := NewRelay(store, publisher, testRelayConfig(), discardLogger())
+	ctx := context.Background()
+
+	if claimed := relay.publishBatch(ctx); claimed != 2 {
+		t.Fatalf("publishBatch() claimed %d, want the batch size 2", claimed)
+	}
+	if got := publisher.publishedTypes(); !slices.Equal(got, []string{"CustomerCreated"}) {
+		t.Errorf("published %v, want [CustomerCreated]", got)
+	}
+	if entry := store.entry(1); !entry.published {
+		t.Error("the delivered event was not marked published")
+	}
+
+	// The failed event is scheduled after the backoff of its first attempt and keeps the error
+	failed := store.entry(2)
+	if failed.published || failed.lastError != "broker unavailable" {
+		t.Errorf("failed event published %v with error %q, want unpublished with the publisher error", failed.published, failed.lastError)
+	}
+	if retryIn := time.Until(failed.dueAt); retryIn <= 0 || retryIn > time.Second {
+		t.Errorf("failed event retried in %s, want within the initial backoff of 1s", retryIn)
+	}
+
+	// The next batch only holds the event that is due, the failed one waits for its retry
+	if claimed := relay.publishBatch(ctx); claimed != 1 {
+		t.Fatalf("second publishBatch() claimed %d, want 1", claimed)
+	}
+	if got := publisher.publishedTypes(); !slices.Equal(got, []string{"CustomerCreated", "CustomerDeleted"}) {
+		t.Errorf("published %v, want [CustomerCreated CustomerDeleted]", got)
+	}
+	if claimed := relay.publishBatch(ctx); claimed != 0 {
+		t.Errorf("third publishBatch() claimed %d, want nothing due", claimed)
+	}
+}
+
+func TestRelayRetriesWithGrowingBackoff(t *testing.T) {
+	store := newOutboxStub("AccountOpened")
+	publisher := &recordingPublisher{failing: map[string]bool{"AccountOpened": true}}
+	relay := NewRelay(store, publisher, testRelayConfig(), discardLogger())
+	ctx := context.Background()
+
+	for attempt := 1; attempt <= 4; attempt++ {
+		// Pretend the retry is due
+		store.mu.Lock()
+		store.events[0].dueAt = time.Time{}
+		store.mu.Unlock()
+
+		if claimed := relay.publishBatch(ctx); claimed != 1 {
+			t.Fatalf("attempt %d claimed %d, want 1", attempt, claimed)
+		}
+		entry := store.entry(1)
+		want := Backoff(time.Second, time.Minute, attempt)
+		if retryIn := time.Until(entry.dueAt); retryIn <= want-time.Second || retryIn > want {
+			t.Errorf("attempt %d retried in %s, want %s", attempt, retryIn, want)
+		}
+	}
+
+	publisher.mu.Lock()
+	publisher.failing = nil
+	publisher.mu.Unlock()
+	store.mu.Lock()
+	store.events[0].dueAt = time.Time{}
+	store.mu.Unlock()
+	relay.publishBatch(ctx)
+	if entry := store.entry(1); !entry.published || entry.event.Attempts != 5 {
+		t.Errorf("event published %v after %d attempts, want published on the 5th", entry.published, entry.event.Attempts)
+	}
+}
+
+func TestRelayRedeliversUnconfirmedEvents(t *testing.T) {
+	store := newOutboxStub("CustomerCreated")
+	store.markErr = errors.New("connection reset")
+	publisher := &recordingPublisher{}
+	cfg := testRelayConfig()
+	cfg.Lease = 0
+	relay := NewRelay(store, publisher, cfg, discardLogger())
+	ctx := context.Background()
+
+	// The event was delivered but not marked, it is delivered again once its lease expires
+	relay.publishBatch(ctx)
+	store.mu.Lock()
+	store.markErr = nil
+	store.mu.Unlock()
+	relay.publishBatch(ctx)
+
+	if got := publisher.publishedTypes(); !slices.Equal(got, []string{"CustomerCreated", "CustomerCreated"}) {
+		t.Errorf("published %v, want the unconfirmed event delivered twice", got)
+	}
+	if entry := store.entry(1); !entry.published {
+		t.Error("event not marked published after the second delivery")
+	}
+	if claimed := relay.publishBatch(ctx); claimed != 0 {
+		t.Errorf("publishBatch() claimed %d after the confirmed delivery, want 0", claimed)
+	}
+}
+
+func TestRelayRun(t *testing.T) {
+	store := newOutboxStub("E1", "E2", "E3", "E4", "E5")
+	store.claimErrs = 1
+	publisher := &recordingPublisher{}
+	cfg := testRelayConfig()
+	cfg.PollInterval = 10 * time.Millisecond
+	cfg.Retention = 24 * time.Hour
+	relay := NewRelay(store, publisher, cfg, discardLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	// The first claim fails, the next tick drains every full batch and the remainder
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publisher.publishedTypes()) < 5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after the context was cancelled")
+	}
+
+	if got := publisher.publishedTypes(); !slices.Equal(got, []string{"E1", "E2", "E3", "E4", "E5"}) {
+		t.Errorf("published %v, want every event once in outbox order", got)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.purgedAt) != 1 {
+		t.Fatalf("purged %d times, want once per purge interval", len(store.purgedAt))
+	}
+	if age := time.Since(store.purgedAt[0]); age < cfg.Retention || age > cfg.Retention+time.Minute {
+		t.Errorf("purged events published before %s, want the retention of %s", store.purgedAt[0], cfg.Retention)
+	}
+}
@@ -0,0 +1,178 @@
+package exports
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Content types of the export formats
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypeXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// ContentTypes lists the export formats, CSV first as the default
+var ContentTypes = []string{ContentTypeCSV, ContentTypeNDJSON, ContentTypeXLSX}
+
+// ErrUnsupportedFormat is returned for content types other than the export formats
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// IWriter writes the records of an export as they are produced
+type IWriter interface {
+	// Write writes one record, its values in the order of the columns. Values are strings, float64, time.Time or
+	// nil for an empty value.
+	Write(values []any) error
+	// Close writes whatever the format needs after the last record and flushes, it does not close the destination
+	Close() error
+}
+
+// NewWriter returns a writer of contentType to w, the header naming columns is written right away
+func NewWriter(contentType string, w io.Writer, columns []string) (IWriter, error) {
+	switch contentType {
+	case ContentTypeCSV:
+		return newCSVWriter(w, columns)
+	case ContentTypeNDJSON:
+		return newNDJSONWriter(w, columns)
+	case ContentTypeXLSX:
+		return newXLSXWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, contentType)
+	}
+}
+
+// Extension returns the file extension of contentType, without the dot
+func Extension(contentType string) string {
+	switch contentType {
+	case ContentTypeNDJSON:
+		return "ndjson"
+	case ContentTypeXLSX:
+		return "xlsx"
+	default:
+		return "csv"
+	}
+}
+
+// ContentTypeOf returns the content type of a format name or file extension: csv, ndjson or xlsx
+func ContentTypeOf(format string) (string, error) {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "csv":
+		return ContentTypeCSV, nil
+	case "ndjson", "jsonl":
+		return ContentTypeNDJSON, nil
+	case "xlsx":
+		return ContentTypeXLSX, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// formatTime renders times the same way in every format
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	writer := &csvWriter{writer: csv.NewWriter(w), record: make([]string, len(columns))}
+	if err := writer.writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *csvWriter) Write(values []any) error {
+	for i, value := range values {
+		switch value := value.(type) {
+		case nil:
+			w.record[i] = ""
+		case string:
+			w.record[i] = neutralizeFormula(value)
+		case float64:
+			w.record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+		case time.Time:
+			w.record[i] = formatTime(value)
+		default:
+			w.record[i] = fmt.Sprint(value)
+		}
+	}
+	return w.writer.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// neutralizeFormula keeps spreadsheet applications from evaluating a value as a formula when the CSV file is opened,
+// by prefixing it with a quote. Phone numbers such as +30 690 123 4567 are left alone.
+func neutralizeFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '@', '\t', '\r':
+		return "'" + value
+	case '+', '-':
+		if strings.Trim(value, "0123456789 +-().") != "" {
+			return "'" + value
+		}
+	}
+	return value
+}
+
+type ndjsonWriter struct {
+	writer *bufio.Writer
+	// keys holds the JSON encoded column names
+	keys [][]byte
+}
+
+func newNDJSONWriter(w io.Writer, columns []string) (*ndjsonWriter, error) {
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return &ndjsonWriter{writer: bufio.NewWriter(w), keys: keys}, nil
+}
+
+// Write writes the record as one JSON object, its members in the order of the columns
+func (w *ndjsonWriter) Write(values []any) error {
+	w.writer.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			w.writer.WriteByte(',')
+		}
+		if t, ok := value.(time.Time); ok {
+			value = formatTime(t)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		w.writer.Write(w.keys[i])
+		w.writer.WriteByte(':')
+		w.writer.Write(encoded)
+	}
+	// bufio keeps the first write error and returns it from every later write
+	_, err := w.writer.WriteString("}\n")
+	return err
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.writer.Flush()
+}
@@ -0,0 +1,113 @@
+package exports
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNeutralizeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "John", want: "John"},
+		{value: "=HYPERLINK(\"http://example.com\")", want: "'=HYPERLINK(\"http://example.com\")"},
+		{value: "@SUM(A1:A2)", want: "'@SUM(A1:A2)"},
+		{value: "\t=1+1", want: "'\t=1+1"},
+		{value: "\r=1+1", want: "'\r=1+1"},
+		{value: "+30 690 123 4567", want: "+30 690 123 4567"},
+		{value: "+1 (555) 123-4567", want: "+1 (555) 123-4567"},
+		{value: "-1500.50", want: "-1500.50"},
+		{value: "+1+cmd|' /C calc'!A0", want: "'+1+cmd|' /C calc'!A0"},
+		{value: "-2+3+A1", want: "'-2+3+A1"},
+		{value: "john=doe", want: "john=doe"},
+	}
+	for _, tt := range tests {
+		if got := neutralizeFormula(tt.value); got != tt.want {
+			t.Errorf("neutralizeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestContentTypeOf(t *testing.T) {
+	tests := []struct {
+		format        string
+		want          string
+		wantExtension string
+		wantErr       bool
+	}{
+		{format: "csv", want: ContentTypeCSV, wantExtension: "csv"},
+		{format: ".CSV", want: ContentTypeCSV, wantExtension: "csv"},
+		{format: "ndjson", want: ContentTypeNDJSON, wantExtension: "ndjson"},
+		{format: "jsonl", want: ContentTypeNDJSON, wantExtension: "ndjson"},
+		{format: "xlsx", want: ContentTypeXLSX, wantExtension: "xlsx"},
+		{format: "json", wantErr: true},
+		{format: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ContentTypeOf(tt.format)
+		if tt.wantErr != errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("ContentTypeOf(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ContentTypeOf(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+		if !tt.wantErr && Extension(got) != tt.wantExtension {
+			t.Errorf("Extension(%q) = %q, want %q", got, Extension(got), tt.wantExtension)
+		}
+	}
+}
+
+func TestWriters(t *testing.T) {
+	createdAt := time.Date(2026, 10, 19, 12, 30, 0, 0, time.FixedZone("EEST", 3*60*60))
+	records := [][]any{
+		{"john.doe@example.com", 1500.5, createdAt},
+		{"=cmd", nil, createdAt},
+	}
+
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{
+			contentType: ContentTypeCSV,
+			want: "email,balance,created_at\n" +
+				"john.doe@example.com,1500.5,2026-10-19T09:30:00Z\n" +
+				"'=cmd,,2026-10-19T09:30:00Z\n",
+		},
+		{
+			contentType: ContentTypeNDJSON,
+			want: `{"email":"john.doe@example.com","balance":1500.5,"created_at":"2026-10-19T09:30:00Z"}` + "\n" +
+				`{"email":"=cmd","balance":null,"created_at":"2026-10-19T09:30:00Z"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			var out bytes.Buffer
+			writer, err := NewWriter(tt.contentType, &out, []string{"email", "balance", "created_at"})
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			for _, values := range records {
+				if err := writer.Write(values); err != nil {
+					t.Fatalf("Write(%v): %v", values, err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWriterUnsupportedFormat(t *testing.T) {
+	if _, err := NewWriter("application/json", &bytes.Buffer{}, []string{"email"}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("NewWriter(application/json) error = %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
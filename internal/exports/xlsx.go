@@ -0,0 +1,147 @@
+package exports
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// maxXLSXRows is the number of rows a worksheet can hold, the header included
+const maxXLSXRows = 1 << 20
+
+// ErrTooManyRows is returned when an XLSX export does not fit in one worksheet
+var ErrTooManyRows = errors.New("export exceeds the rows of an XLSX worksheet")
+
+// xlsxParts are the parts of the workbook besides the worksheet, which is streamed. The header row uses the bold
+// cell format 1 of the styles.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	{"xl/styles.xml", xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`</styleSheet>`},
+}
+
+// xlsxWriter streams a workbook with a single worksheet. Strings are written inline rather than to a shared string
+// table, so nothing but the current row is held in memory. Times are written as text like in the other formats.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	rows    int
+	// reference is reused to build cell references such as AB12
+	reference []byte
+}
+
+func newXLSXWriter(w io.Writer, columns []string) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	// The worksheet is written first and last closed, the zip format allows parts in any order
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &xlsxWriter{archive: archive, sheet: bufio.NewWriter(sheet)}
+	writer.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	if err := writer.writeRow(header, ` s="1"`); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *xlsxWriter) Write(values []any) error {
+	if w.rows == maxXLSXRows {
+		return ErrTooManyRows
+	}
+	return w.writeRow(values, "")
+}
+
+// writeRow writes a row of cells with the given style attribute
+func (w *xlsxWriter) writeRow(values []any, style string) error {
+	w.rows++
+	row := strconv.Itoa(w.rows)
+	w.sheet.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		w.reference = append(appendColumnName(w.reference[:0], i), row...)
+		w.sheet.WriteString(`<c r="`)
+		w.sheet.Write(w.reference)
+		w.sheet.WriteString(`"` + style)
+
+		switch value := value.(type) {
+		case float64:
+			w.sheet.WriteString(`><v>` + strconv.FormatFloat(value, 'f', -1, 64) + `</v></c>`)
+		case time.Time:
+			w.writeText(formatTime(value))
+		case string:
+			w.writeText(value)
+		default:
+			return errors.New("unsupported value in XLSX export")
+		}
+	}
+	// bufio keeps the first write error and returns it from every later write
+	_, err := w.sheet.WriteString(`</row>`)
+	return err
+}
+
+// writeText ends an open cell element with an inline string
+func (w *xlsxWriter) writeText(text string) {
+	w.sheet.WriteString(` t="inlineStr"><is><t xml:space="preserve">`)
+	// Characters XML cannot hold are replaced rather than failing the export
+	xml.EscapeText(w.sheet, []byte(text))
+	w.sheet.WriteString(`</t></is></c>`)
+}
+
+func (w *xlsxWriter) Close() error {
+	w.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+
+	for _, part := range xlsxParts {
+		writer, err := w.archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(writer, part.content); err != nil {
+			return err
+		}
+	}
+	return w.archive.Close()
+}
+
+// appendColumnName appends the spreadsheet name of the zero based column index: A, B, ..., Z, AA, AB and so on
+func appendColumnName(dst []byte, index int) []byte {
+	if index >= 26 {
+		dst = appendColumnName(dst, index/26-1)
+	}
+	return append(dst, byte('A'+index%26))
+}
@@ -0,0 +1,151 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendColumnName(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{index: 0, want: "A"},
+		{index: 25, want: "Z"},
+		{index: 26, want: "AA"},
+		{index: 27, want: "AB"},
+		{index: 51, want: "AZ"},
+		{index: 52, want: "BA"},
+		{index: 701, want: "ZZ"},
+		{index: 702, want: "AAA"},
+		{index: 16383, want: "XFD"},
+	}
+	for _, tt := range tests {
+		if got := string(appendColumnName(nil, tt.index)); got != tt.want {
+			t.Errorf("appendColumnName(%d) = %s, want %s", tt.index, got, tt.want)
+		}
+	}
+}
+
+// readXLSX returns the parts of a workbook by name
+func readXLSX(t *testing.T, workbook []byte) map[string]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatalf("opening workbook: %v", err)
+	}
+	parts := make(map[string]string, len(archive.File))
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", file.Name, err)
+		}
+		parts[file.Name] = string(content)
+	}
+	return parts
+}
+
+func TestXLSXWriter(t *testing.T) {
+	const sheetStart = xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		`<row r="1"><c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">email</t></is></c>` +
+		`<c r="B1" s="1" t="inlineStr"><is><t xml:space="preserve">balance</t></is></c></row>`
+
+	tests := []struct {
+		name   string
+		values [][]any
+		want   string
+	}{
+		{name: "header only", want: ""},
+		{
+			name:   "number and escaped text",
+			values: [][]any{{"<john&doe>@example.com", 1234.5}},
+			want: `<row r="2"><c r="A2" t="inlineStr"><is><t xml:space="preserve">&lt;john&amp;doe&gt;@example.com</t></is></c>` +
+				`<c r="B2"><v>1234.5</v></c></row>`,
+		},
+		{
+			name:   "empty values are skipped",
+			values: [][]any{{nil, 0.0}, {"=1+1", nil}},
+			want: `<row r="2"><c r="B2"><v>0</v></c></row>` +
+				`<row r="3"><c r="A3" t="inlineStr"><is><t xml:space="preserve">=1+1</t></is></c></row>`,
+		},
+		{
+			name:   "time as text",
+			values: [][]any{{time.Date(2026, 10, 19, 12, 30, 0, 0, time.FixedZone("EEST", 3*60*60)), nil}},
+			want:   `<row r="2"><c r="A2" t="inlineStr"><is><t xml:space="preserve">2026-10-19T09:30:00Z</t></is></c></row>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			writer, err := NewWriter(ContentTypeXLSX, &out, []string{"email", "balance"})
+			if err != nil {
+				t.Fatalf("NewWriter: %v", err)
+			}
+			for _, values := range tt.values {
+				if err := writer.Write(values); err != nil {
+					t.Fatalf("Write(%v): %v", values, err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			parts := readXLSX(t, out.Bytes())
+			for _, part := range xlsxParts {
+				if parts[part.name] != part.content {
+					t.Errorf("part %s = %q, want %q", part.name, parts[part.name], part.content)
+				}
+			}
+			sheet := parts["xl/worksheets/sheet1.xml"]
+			if want := sheetStart + tt.want + `</sheetData></worksheet>`; sheet != want {
+				t.Errorf("worksheet = %s, want %s", sheet, want)
+			}
+			decoder := xml.NewDecoder(strings.NewReader(sheet))
+			for {
+				if _, err := decoder.Token(); err != nil {
+					if !errors.Is(err, io.EOF) {
+						t.Errorf("worksheet is not well formed XML: %v", err)
+					}
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestXLSXWriterUnsupportedValue(t *testing.T) {
+	writer, err := NewWriter(ContentTypeXLSX, io.Discard, []string{"id"})
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := writer.Write([]any{42}); err == nil {
+		t.Error("Write(int) succeeded, want an error")
+	}
+}
+
+func TestXLSXWriterTooManyRows(t *testing.T) {
+	writer, err := newXLSXWriter(io.Discard, []string{"email"})
+	if err != nil {
+		t.Fatalf("newXLSXWriter: %v", err)
+	}
+	// The header and all but one data row were written
+	writer.rows = maxXLSXRows - 1
+
+	if err := writer.Write([]any{"john.doe@example.com"}); err != nil {
+		t.Fatalf("Write of the last row: %v", err)
+	}
+	if err := writer.Write([]any{"jane.smith@example.com"}); !errors.Is(err, ErrTooManyRows) {
+		t.Errorf("Write past the last row error = %v, want %v", err, ErrTooManyRows)
+	}
+}
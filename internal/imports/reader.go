@@ -0,0 +1,198 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"org/gg/banking/internal/models"
+	"slices"
+	"strings"
+)
+
+// Content types accepted for customer imports
+const (
+	ContentTypeCSV    = "text/csv"
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// maxLineBytes bounds a single NDJSON line, so a file without newlines cannot be buffered whole
+const maxLineBytes = 64 * 1024
+
+// ErrUnsupportedFormat is returned for content types other than CSV and NDJSON
+var ErrUnsupportedFormat = errors.New("unsupported import format")
+
+// Row is one customer read from an import. Err is set instead when the line holds no valid record, reading can
+// still go on with the next line.
+type Row struct {
+	// Line is where the record starts in the file, counting from 1
+	Line     int
+	Customer models.CustomerDTO
+	Err      error
+}
+
+// IReader streams the rows of an import
+type IReader interface {
+	// Read returns the next row, io.EOF after the last one, or an error when the file cannot be read any further
+	Read() (Row, error)
+}
+
+// NewReader returns a reader for the format of contentType. Nothing is read until the first call to Read.
+func NewReader(contentType string, r io.Reader) (IReader, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, contentType)
+	}
+
+	switch mediaType {
+	case ContentTypeCSV:
+		return newCSVReader(r), nil
+	case ContentTypeNDJSON:
+		return newNDJSONReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, mediaType)
+	}
+}
+
+// csvColumns are the columns a CSV import may have, its header names them in any order
+var csvColumns = []string{"first_name", "last_name", "email", "phone"}
+
+// csvRequiredColumns must be present in the header of a CSV import
+var csvRequiredColumns = []string{"first_name", "last_name", "email"}
+
+// csvReader reads customers from CSV with a header line
+type csvReader struct {
+	reader *csv.Reader
+	// columns holds the position of each known column, read from the header on first use
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	reader := csv.NewReader(r)
+	// Records with a wrong number of fields fail on their own instead of stopping the import
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return &csvReader{reader: reader}
+}
+
+func (r *csvReader) Read() (Row, error) {
+	if r.columns == nil {
+		if err := r.readHeader(); err != nil {
+			return Row{}, err
+		}
+	}
+
+	record, err := r.reader.Read()
+	if err != nil {
+		return Row{}, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	if len(record) != len(r.columns) {
+		return Row{Line: line, Err: fmt.Errorf("expected %d fields, got %d", len(r.columns), len(record))}, nil
+	}
+	field := func(column string) string {
+		if i, ok := r.columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	return Row{Line: line, Customer: models.CustomerDTO{
+		FirstName: field("first_name"),
+		LastName:  field("last_name"),
+		Email:     field("email"),
+		Phone:     field("phone"),
+	}}, nil
+}
+
+func (r *csvReader) readHeader() error {
+	header, err := r.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return errors.New("the header line is missing")
+	}
+	if err != nil {
+		return err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheet applications often start UTF-8 files with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(csvColumns, name) {
+			return fmt.Errorf("unknown column %q, columns are %s", name, strings.Join(csvColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return fmt.Errorf("column %q appears twice", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range csvRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("column %q is required", name)
+		}
+	}
+
+	r.columns = columns
+	return nil
+}
+
+// ndjsonCustomer is one line of an NDJSON import
+type ndjsonCustomer struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+}
+
+// ndjsonReader reads customers from newline delimited JSON objects, blank lines are ignored
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineBytes)
+	return &ndjsonReader{scanner: scanner}
+}
+
+func (r *ndjsonReader) Read() (Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var customer ndjsonCustomer
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&customer); err != nil {
+			return Row{Line: r.line, Err: fmt.Errorf("invalid JSON: %v", err)}, nil
+		}
+		if decoder.More() {
+			return Row{Line: r.line, Err: errors.New("invalid JSON: more than one value on the line")}, nil
+		}
+		return Row{Line: r.line, Customer: models.CustomerDTO{
+			FirstName: strings.TrimSpace(customer.FirstName),
+			LastName:  strings.TrimSpace(customer.LastName),
+			Email:     strings.TrimSpace(customer.Email),
+			Phone:     strings.TrimSpace(customer.Phone),
+		}}, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Row{}, fmt.Errorf("line %d is longer than %d bytes", r.line+1, maxLineBytes)
+		}
+		return Row{}, err
+	}
+	return Row{}, io.EOF
+}
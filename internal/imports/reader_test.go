@@ -0,0 +1,199 @@
+package imports
+
+import (
+	"errors"
+	"io"
+	"org/gg/banking/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// result is a row as compared by the tests, with its error as text
+type result struct {
+	line     int
+	customer models.CustomerDTO
+	err      string
+}
+
+// readAll reads rows until io.EOF or an error the reader cannot go on from
+func readAll(t *testing.T, reader IReader) ([]result, error) {
+	t.Helper()
+	var results []result
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return results, nil
+		}
+		if err != nil {
+			return results, err
+		}
+		got := result{line: row.Line, customer: row.Customer}
+		if row.Err != nil {
+			got.err = row.Err.Error()
+		}
+		results = append(results, got)
+	}
+}
+
+func compareResults(t *testing.T, got, want []result) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows %+v, want %d rows %+v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	tests := []struct {
+		contentType string
+		wantErr     bool
+	}{
+		{contentType: "text/csv"},
+		{contentType: "text/csv; charset=utf-8"},
+		{contentType: "application/x-ndjson"},
+		{contentType: "APPLICATION/X-NDJSON"},
+		{contentType: "application/json", wantErr: true},
+		{contentType: "", wantErr: true},
+		{contentType: "text/csv; charset", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			_, err := NewReader(tt.contentType, strings.NewReader(""))
+			if tt.wantErr != errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("NewReader(%q) error = %v, wantErr %v", tt.contentType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	john := models.CustomerDTO{FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Phone: "555-1234"}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []result
+		wantErr string
+	}{
+		{
+			name:  "all columns",
+			input: "first_name,last_name,email,phone\nJohn,Doe,john.doe@example.com,555-1234\n",
+			want:  []result{{line: 2, customer: john}},
+		},
+		{
+			name:  "reordered header with byte order mark and padding",
+			input: "\ufeff Email ,PHONE,last_name,first_name\r\n john.doe@example.com ,555-1234,Doe,John\r\n",
+			want:  []result{{line: 2, customer: john}},
+		},
+		{
+			name:  "without the optional phone",
+			input: "first_name,last_name,email\nJohn,Doe,john.doe@example.com\n",
+			want:  []result{{line: 2, customer: models.CustomerDTO{FirstName: "John", LastName: "Doe", Email: "john.doe@example.com"}}},
+		},
+		{
+			name:  "wrong number of fields does not stop the import",
+			input: "first_name,last_name,email,phone\nJohn,Doe\nJohn,Doe,john.doe@example.com,555-1234\n",
+			want: []result{
+				{line: 2, err: "expected 4 fields, got 2"},
+				{line: 3, customer: john},
+			},
+		},
+		{
+			name:  "quoted field spanning lines",
+			input: "first_name,last_name,email\n\"Jo\nhn\",Doe,john.doe@example.com\nJane,Smith,jane.smith@example.com\n",
+			want: []result{
+				{line: 2, customer: models.CustomerDTO{FirstName: "Jo\nhn", LastName: "Doe", Email: "john.doe@example.com"}},
+				{line: 4, customer: models.CustomerDTO{FirstName: "Jane", LastName: "Smith", Email: "jane.smith@example.com"}},
+			},
+		},
+		{name: "header only", input: "first_name,last_name,email\n"},
+		{name: "empty", input: "", wantErr: "the header line is missing"},
+		{name: "unknown column", input: "first_name,last_name,email,age\n", wantErr: `unknown column "age", columns are first_name, last_name, email, phone`},
+		{name: "duplicate column", input: "first_name,last_name,email,Email\n", wantErr: `column "email" appears twice`},
+		{name: "missing required column", input: "first_name,email\n", wantErr: `column "last_name" is required`},
+		{
+			name:    "malformed quoting",
+			input:   "first_name,last_name,email\nJohn,\"Doe,john.doe@example.com\n",
+			wantErr: "extraneous or missing \" in quoted-field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(ContentTypeCSV, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			got, err := readAll(t, reader)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Read() error = %v, want %q", err, tt.wantErr)
+			}
+			compareResults(t, got, tt.want)
+		})
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	john := models.CustomerDTO{FirstName: "John", LastName: "Doe", Email: "john.doe@example.com", Phone: "555-1234"}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []result
+		wantErr string
+	}{
+		{
+			name:  "trimmed values",
+			input: `{"first_name":" John ","last_name":"Doe","email":"john.doe@example.com","phone":"555-1234"}`,
+			want:  []result{{line: 1, customer: john}},
+		},
+		{
+			name:  "blank lines are skipped but counted",
+			input: "\n  \n" + `{"first_name":"John","last_name":"Doe","email":"john.doe@example.com","phone":"555-1234"}` + "\r\n\n",
+			want:  []result{{line: 3, customer: john}},
+		},
+		{
+			name: "invalid lines do not stop the import",
+			input: `{"first_name":"John"` + "\n" +
+				`{"first_name":"John","age":42}` + "\n" +
+				`{"first_name":"John"} {"first_name":"Jane"}` + "\n" +
+				`{"first_name":"John","last_name":"Doe","email":"john.doe@example.com","phone":"555-1234"}`,
+			want: []result{
+				{line: 1, err: "invalid JSON: unexpected EOF"},
+				{line: 2, err: `invalid JSON: json: unknown field "age"`},
+				{line: 3, err: "invalid JSON: more than one value on the line"},
+				{line: 4, customer: john},
+			},
+		},
+		{name: "empty", input: ""},
+		{
+			name:    "line over the limit",
+			input:   `{"first_name":"John"}` + "\n" + `{"first_name":"` + strings.Repeat("a", maxLineBytes) + `"}`,
+			want:    []result{{line: 1, customer: models.CustomerDTO{FirstName: "John"}}},
+			wantErr: "line 2 is longer than 65536 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewReader(ContentTypeNDJSON, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			got, err := readAll(t, reader)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("Read() error = %v, want %q", err, tt.wantErr)
+			}
+			compareResults(t, got, tt.want)
+		})
+	}
+}
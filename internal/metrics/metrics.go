@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exposed by the application
+const namespace = "banking"
+
+// Registry holds the application metrics. A dedicated registry keeps the exposition independent of
+// packages that register on the Prometheus default registry.
+var Registry = prometheus.NewRegistry()
+
+// HTTP metrics, labelled by route template rather than the raw path to keep cardinality bounded
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+)
+
+// RateLimitedRequests counts requests rejected by the rate limiter, labelled by rule rather than caller
+var RateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "http",
+	Name:      "rate_limited_total",
+	Help:      "HTTP requests rejected by the rate limiter by rule.",
+}, []string{"rule"})
+
+// DBQueryDuration measures repository operations, such as customers.FindAll, by outcome
+var DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Subsystem: "db",
+	Name:      "query_duration_seconds",
+	Help:      "Repository query latency by operation and outcome.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"operation", "outcome"})
+
+// Business counters
+var (
+	CustomersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "customers_created_total",
+		Help:      "Customers created.",
+	})
+
+	AccountsOpened = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "accounts_opened_total",
+		Help:      "Accounts opened.",
+	})
+
+	TransfersPosted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_posted_total",
+		Help:      "Transfers posted between accounts.",
+	})
+
+	CustomerImportRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "imports",
+		Name:      "rows_total",
+		Help:      "Rows of customer imports by status: created, skipped or failed. Dry runs are not counted.",
+	}, []string{"status"})
+)
+
+// Outbox relay metrics, labelled by event type
+var (
+	EventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "published_total",
+		Help:      "Domain events published from the outbox by event type.",
+	}, []string{"type"})
+
+	EventPublishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "events",
+		Name:      "publish_failures_total",
+		Help:      "Failed domain event deliveries, each retried later, by event type.",
+	}, []string{"type"})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "deliveries_total",
+		Help:      "Webhook delivery attempts by outcome: delivered, failed (retried later) or dead.",
+	}, []string{"outcome"})
+)
+
+// Cache metrics, labelled by cache name
+var (
+	CacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Lookups answered from an in-process cache.",
+	}, []string{"cache"})
+
+	CacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Lookups that were not cached or expired, concurrent misses for the same key are loaded once.",
+	}, []string{"cache"})
+
+	CacheEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "Entries evicted to make room for new ones.",
+	}, []string{"cache"})
+)
+
+var (
+	dbStatsMu        sync.Mutex
+	dbStatsCollector prometheus.Collector
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		HTTPRequestsInFlight,
+		RateLimitedRequests,
+		DBQueryDuration,
+		CustomersCreated,
+		AccountsOpened,
+		TransfersPosted,
+		CustomerImportRows,
+		EventsPublished,
+		EventPublishFailures,
+		WebhookDeliveries,
+		CacheHits,
+		CacheMisses,
+		CacheEvictions,
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db, replacing a previously registered pool
+func RegisterDB(db *sql.DB) {
+	dbStatsMu.Lock()
+	defer dbStatsMu.Unlock()
+
+	if dbStatsCollector != nil {
+		Registry.Unregister(dbStatsCollector)
+	}
+	dbStatsCollector = collectors.NewDBStatsCollector(db, namespace)
+	Registry.MustRegister(dbStatsCollector)
+}
+
+// ObserveQuery records the latency of a repository operation
+func ObserveQuery(operation string, duration time.Duration, failed bool) {
+	outcome := "success"
+	if failed {
+		outcome = "error"
+	}
+	DBQueryDuration.WithLabelValues(operation, outcome).Observe(duration.Seconds())
+}
+
+// Handler serves the registry in the Prometheus text exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the value of the sample exposed for name and labels, formatted as in the exposition
+// (e.g. route="/customers",status="200"), or -1 when there is no such sample
+func scrape(t *testing.T, name, labels string) float64 {
+	t.Helper()
+	response := httptest.NewRecorder()
+	Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if response.Code != http.StatusOK {
+		t.Fatalf("scraping metrics: status %d", response.Code)
+	}
+
+	sample := name
+	if labels != "" {
+		sample += "{" + labels + "}"
+	}
+	for _, line := range strings.Split(response.Body.String(), "\n") {
+		value, found := strings.CutPrefix(line, sample+" ")
+		if !found {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatalf("parsing sample %q: %v", line, err)
+		}
+		return parsed
+	}
+	return -1
+}
+
+func TestObserveQuery(t *testing.T) {
+	ObserveQuery("test.Observe", 3*time.Millisecond, false)
+	ObserveQuery("test.Observe", time.Second, true)
+	ObserveQuery("test.Observe", 2*time.Second, true)
+
+	tests := []struct {
+		labels string
+		want   float64
+	}{
+		{labels: `operation="test.Observe",outcome="success"`, want: 1},
+		{labels: `operation="test.Observe",outcome="error"`, want: 2},
+	}
+	for _, tt := range tests {
+		if got := scrape(t, "banking_db_query_duration_seconds_count", tt.labels); got != tt.want {
+			t.Errorf("query count {%s} = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+	if got := scrape(t, "banking_db_query_duration_seconds_bucket", `operation="test.Observe",outcome="success",le="0.005"`); got != 1 {
+		t.Errorf("queries under 5ms = %v, want 1", got)
+	}
+	if got := scrape(t, "banking_db_query_duration_seconds_sum", `operation="test.Observe",outcome="error"`); got != 3 {
+		t.Errorf("failed query seconds = %v, want 3", got)
+	}
+}
+
+func TestHandlerExposesTheRegistry(t *testing.T) {
+	response := httptest.NewRecorder()
+	Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if contentType := response.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Content-Type = %q, want the text exposition format", contentType)
+	}
+	for _, name := range []string{"banking_http_requests_in_flight", "banking_customers_created_total", "banking_accounts_opened_total", "banking_transfers_posted_total", "go_goroutines"} {
+		if !regexp.MustCompile(`(?m)^# TYPE ` + name + ` `).MatchString(response.Body.String()) {
+			t.Errorf("exposition has no %s", name)
+		}
+	}
+}
+
+// stubDriver opens no connections, the pool statistics of a database that was never used are all zero
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return nil, errors.New("not connected") }
+
+func init() {
+	sql.Register("metrics-stub", stubDriver{})
+}
+
+func TestRegisterDBReplacesThePool(t *testing.T) {
+	for range 2 {
+		db, err := sql.Open("metrics-stub", "")
+		if err != nil {
+			t.Fatalf("sql.Open: %v", err)
+		}
+		db.SetMaxOpenConns(7)
+		defer db.Close()
+
+		// A second pool must replace the first instead of failing to register duplicate metrics
+		RegisterDB(db)
+	}
+
+	if got := scrape(t, "go_sql_max_open_connections", `db_name="banking"`); got != 7 {
+		t.Errorf("max open connections = %v, want 7", got)
+	}
+	if got := scrape(t, "go_sql_in_use_connections", `db_name="banking"`); got != 0 {
+		t.Errorf("in use connections = %v, want 0", got)
+	}
+}
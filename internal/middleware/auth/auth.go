@@ -0,0 +1,75 @@
+package auth
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/middleware/errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrincipalKey is the gin.Context key holding the authenticated auth.Principal
+const PrincipalKey = "principal"
+
+// APIKeyHeader carries the API key of partner integrations
+const APIKeyHeader = "X-API-Key"
+
+// AuthMiddleware requires a valid API key or bearer token and stores its principal in both the gin and the request
+// context. An X-API-Key header takes precedence, API keys are not accepted when apiKeys is nil.
+// Failures are reported as 401 problem details by the error middleware.
+func AuthMiddleware(verifier auth.ITokenVerifier, apiKeys auth.IAPIKeyAuthenticator, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var principal auth.Principal
+		if key := c.GetHeader(APIKeyHeader); key != "" && apiKeys != nil {
+			var err error
+			principal, err = apiKeys.Authenticate(c.Request.Context(), key)
+			if err != nil {
+				if !stderrors.Is(err, auth.ErrInvalidAPIKey) {
+					_ = c.Error(err)
+					c.Abort()
+					return
+				}
+				logger.InfoContext(c, "Rejected api key", slog.Any("error", err))
+				unauthorized(c, `Bearer`, "Invalid or expired API key")
+				return
+			}
+		} else {
+			token, found := bearerToken(c.GetHeader("Authorization"))
+			if !found {
+				unauthorized(c, `Bearer`, "Missing bearer token or API key")
+				return
+			}
+
+			var err error
+			principal, err = verifier.Verify(c.Request.Context(), token)
+			if err != nil {
+				logger.InfoContext(c, "Rejected bearer token", slog.Any("error", err))
+				unauthorized(c, `Bearer error="invalid_token"`, "Invalid or expired token")
+				return
+			}
+		}
+
+		c.Set(PrincipalKey, principal)
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer <token>" header
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized challenges the client as required by RFC 6750 and aborts the chain
+func unauthorized(c *gin.Context, challenge, message string) {
+	c.Header("WWW-Authenticate", challenge)
+	_ = c.Error(errors.UnauthorizedError(message))
+	c.Abort()
+}
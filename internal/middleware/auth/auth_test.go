@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/middleware/errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeVerifier accepts the token "valid-token" only
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(_ context.Context, token string) (auth.Principal, error) {
+	if token != "valid-token" {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+	return auth.Principal{Subject: "user-1"}, nil
+}
+
+// fakeAPIKeys accepts the key "bk_valid" only and fails with a storage error for "bk_broken"
+type fakeAPIKeys struct{}
+
+func (fakeAPIKeys) Authenticate(_ context.Context, key string) (auth.Principal, error) {
+	switch key {
+	case "bk_valid":
+		return auth.Principal{Subject: auth.APIKeySubjectPrefix + "1"}, nil
+	case "bk_broken":
+		return auth.Principal{}, stderrors.New("connection refused")
+	default:
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	newRouter := func(apiKeys auth.IAPIKeyAuthenticator) *gin.Engine {
+		router := gin.New()
+		router.Use(errors.ErrorHandlerMiddleware(logger), AuthMiddleware(fakeVerifier{}, apiKeys, logger))
+		router.GET("/whoami", func(c *gin.Context) {
+			principal, ok := auth.PrincipalFromContext(c.Request.Context())
+			if !ok || c.MustGet(PrincipalKey).(auth.Principal).Subject != principal.Subject {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			c.String(http.StatusOK, principal.Subject)
+		})
+		return router
+	}
+
+	tests := []struct {
+		name          string
+		apiKeys       auth.IAPIKeyAuthenticator
+		authorization string
+		apiKey        string
+		wantStatus    int
+		wantSubject   string
+		wantChallenge string
+	}{
+		{name: "bearer token", authorization: "Bearer valid-token", wantStatus: http.StatusOK, wantSubject: "user-1"},
+		{name: "scheme case insensitive", authorization: "bearer valid-token", wantStatus: http.StatusOK, wantSubject: "user-1"},
+		{name: "surrounding spaces", authorization: "Bearer   valid-token ", wantStatus: http.StatusOK, wantSubject: "user-1"},
+		{name: "missing header", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{name: "other scheme", authorization: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{name: "scheme without token", authorization: "Bearer", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{name: "empty token", authorization: "Bearer  ", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{name: "token without scheme", authorization: "valid-token", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{name: "invalid token", authorization: "Bearer forged", wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer error="invalid_token"`},
+		{name: "api key", apiKeys: fakeAPIKeys{}, apiKey: "bk_valid", wantStatus: http.StatusOK, wantSubject: "api-key:1"},
+		{name: "api key takes precedence over a bearer token", apiKeys: fakeAPIKeys{}, apiKey: "bk_valid",
+			authorization: "Bearer valid-token", wantStatus: http.StatusOK, wantSubject: "api-key:1"},
+		{name: "invalid api key does not fall back to the bearer token", apiKeys: fakeAPIKeys{}, apiKey: "bk_revoked",
+			authorization: "Bearer valid-token", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+		{name: "api key storage failure", apiKeys: fakeAPIKeys{}, apiKey: "bk_broken", wantStatus: http.StatusInternalServerError},
+		{name: "api keys disabled", apiKey: "bk_valid", authorization: "Bearer valid-token", wantStatus: http.StatusOK, wantSubject: "user-1"},
+		{name: "api keys disabled without token", apiKey: "bk_valid", wantStatus: http.StatusUnauthorized, wantChallenge: "Bearer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.apiKey != "" {
+				request.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			response := httptest.NewRecorder()
+			newRouter(tt.apiKeys).ServeHTTP(response, request)
+
+			if response.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", response.Code, tt.wantStatus, response.Body)
+			}
+			if tt.wantSubject != "" && response.Body.String() != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", response.Body, tt.wantSubject)
+			}
+			if got := response.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
@@ -44,3 +44,7 @@ func InternalServerError(message string) AppError {
 func ConflictError(message string) AppError {
 	return NewAppError(http.StatusConflict, "CONFLICT", message)
 }
+
+func TooManyRequestsError(message string) AppError {
+	return NewAppError(http.StatusTooManyRequests, "TOO_MANY_REQUESTS", message)
+}
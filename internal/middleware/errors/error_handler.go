@@ -4,51 +4,73 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
 	"net/http"
+	"org/gg/banking/internal/config/logger"
+	"runtime/debug"
 )
 
 // ErrorHandlerMiddleware combines panic recovery and error handling
-func ErrorHandlerMiddleware() gin.HandlerFunc {
+func ErrorHandlerMiddleware(logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		defer recoverFromPanics(c)
+		defer recoverFromPanics(c, logger)
 		c.Next()
-		handleErrors(c)
+		handleErrors(c, logger)
 	}
 }
 
 // recoverFromPanics handles any panics during request processing
-func recoverFromPanics(c *gin.Context) {
+func recoverFromPanics(c *gin.Context, logger *slog.Logger) {
 	if err := recover(); err != nil {
-		log.Println("Recovered from panic:", err)
+		logger.ErrorContext(c, "Recovered from panic",
+			slog.Any("panic", err),
+			slog.String("stack", string(debug.Stack())),
+		)
 		renderErrorResponse(c, http.StatusInternalServerError, "Internal Server Error", fmt.Sprintf("%v", err))
 		c.Abort()
 	}
 }
 
 // handleErrors processes errors added via ctx.Error()
-func handleErrors(c *gin.Context) {
+func handleErrors(c *gin.Context, logger *slog.Logger) {
 	if len(c.Errors) == 0 {
 		return
 	}
 
 	err := c.Errors.Last().Err
-	log.Printf("Error: %v", err)
 
 	// Check for custom error types
 	var appErr AppError
 	if errors.As(err, &appErr) {
+		// Client errors are expected, only server errors need attention
+		level := slog.LevelWarn
+		if appErr.StatusCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.Log(c, level, "Request failed",
+			slog.Int("status", appErr.StatusCode),
+			slog.String("code", appErr.Code),
+			slog.Any("error", err),
+		)
+
 		// Use the status code from our custom error
 		renderErrorResponse(c, appErr.StatusCode, http.StatusText(appErr.StatusCode), appErr.Message)
 		return
 	}
 
+	logger.ErrorContext(c, "Request failed", slog.Int("status", http.StatusInternalServerError), slog.Any("error", err))
+
 	// Default error handling
 	renderErrorResponse(c, http.StatusInternalServerError, "Internal Server Error", err.Error())
 }
 
 // renderErrorResponse creates consistent error responses with complete problem details
 func renderErrorResponse(c *gin.Context, status int, title, detail string, options ...func(*ProblemDetails)) {
+	if c.Writer.Written() {
+		// A streamed response, such as an export, has already started and can only be cut short
+		return
+	}
+
 	problemDetails := ProblemDetails{
 		Status: status,
 		Title:  title,
@@ -74,5 +96,9 @@ func renderErrorResponse(c *gin.Context, status int, title, detail string, optio
 		problemDetails.Instance = c.Request.URL.Path
 	}
 
+	if problemDetails.RequestID == "" {
+		problemDetails.RequestID = logger.RequestID(c.Request.Context())
+	}
+
 	c.JSON(status, problemDetails)
 }
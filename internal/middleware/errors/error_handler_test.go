@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorHandlerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		wantStatus int
+		wantDetail string
+		wantLevel  string
+		wantMsg    string
+	}{
+		{
+			name:       "client error",
+			handler:    func(c *gin.Context) { _ = c.Error(NotFoundError("customer not found")) },
+			wantStatus: http.StatusNotFound,
+			wantDetail: "customer not found",
+			wantLevel:  "WARN",
+			wantMsg:    "Request failed",
+		},
+		{
+			name:       "server error",
+			handler:    func(c *gin.Context) { _ = c.Error(InternalServerError("database unavailable")) },
+			wantStatus: http.StatusInternalServerError,
+			wantDetail: "database unavailable",
+			wantLevel:  "ERROR",
+			wantMsg:    "Request failed",
+		},
+		{
+			name:       "unexpected error",
+			handler:    func(c *gin.Context) { _ = c.Error(errors.New("connection reset")) },
+			wantStatus: http.StatusInternalServerError,
+			wantDetail: "connection reset",
+			wantLevel:  "ERROR",
+			wantMsg:    "Request failed",
+		},
+		{
+			name:       "panic",
+			handler:    func(c *gin.Context) { panic("nil map") },
+			wantStatus: http.StatusInternalServerError,
+			wantDetail: "nil map",
+			wantLevel:  "ERROR",
+			wantMsg:    "Recovered from panic",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			router := gin.New()
+			router.Use(ErrorHandlerMiddleware(slog.New(slog.NewJSONHandler(&logs, nil))))
+			router.GET("/customers", tt.handler)
+
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/customers", nil))
+
+			var problem ProblemDetails
+			if err := json.Unmarshal(response.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decoding problem details %q: %v", response.Body.String(), err)
+			}
+			if response.Code != tt.wantStatus || problem.Status != tt.wantStatus || problem.Detail != tt.wantDetail {
+				t.Errorf("response %d %+v, want %d with detail %q", response.Code, problem, tt.wantStatus, tt.wantDetail)
+			}
+			if problem.Instance != "/customers" {
+				t.Errorf("problem instance = %q, want the request path", problem.Instance)
+			}
+
+			// Every failure is logged once, as a structured record at a level matching its status
+			var record map[string]any
+			if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+				t.Fatalf("decoding log record %q: %v", logs.String(), err)
+			}
+			if record["level"] != tt.wantLevel || record["msg"] != tt.wantMsg {
+				t.Errorf("logged %v %q, want %s %q", record["level"], record["msg"], tt.wantLevel, tt.wantMsg)
+			}
+			if tt.wantMsg == "Recovered from panic" && record["stack"] == nil {
+				t.Error("panic logged without its stack")
+			}
+		})
+	}
+}
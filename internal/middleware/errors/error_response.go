@@ -7,4 +7,6 @@ type ProblemDetails struct {
 	Status   int    `json:"status"`
 	Detail   string `json:"detail,omitempty"`
 	Instance string `json:"instance,omitempty"`
+	// RequestID is an extension member correlating the problem with the request logs
+	RequestID string `json:"request_id,omitempty"`
 }
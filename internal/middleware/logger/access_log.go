@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"org/gg/banking/internal/auth"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access log formats
+const (
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+)
+
+// clfTimeFormat is the timestamp layout of the Common Log Format
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogMiddleware emits one line per request once it has completed.
+// The json format is written through the structured logger so it reaches every configured sink and carries the request id,
+// the Common and Combined Log Formats are written as plain lines to output.
+func AccessLogMiddleware(logger *slog.Logger, redactor *Redactor, format string, output io.Writer) gin.HandlerFunc {
+	format = strings.ToLower(format)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		entry := accessLogEntry{
+			start:     start,
+			clientIP:  c.ClientIP(),
+			method:    c.Request.Method,
+			uri:       loggedURI(c, redactor),
+			proto:     c.Request.Proto,
+			status:    c.Writer.Status(),
+			bytes:     max(c.Writer.Size(), 0), // gin reports -1 when nothing was written
+			referer:   c.Request.Referer(),
+			userAgent: c.Request.UserAgent(),
+		}
+		if principal, ok := auth.PrincipalFromContext(c.Request.Context()); ok {
+			entry.user = principal.Subject
+		}
+
+		switch format {
+		case AccessLogCommon:
+			fmt.Fprintln(output, entry.common())
+		case AccessLogCombined:
+			fmt.Fprintln(output, entry.combined())
+		default:
+			// The request id, route template and latency are added by the context aware log handler
+			logger.InfoContext(c, "ACCESS",
+				slog.String("client_ip", entry.clientIP),
+				slog.String("user", entry.user),
+				slog.String("method", entry.method),
+				slog.String("uri", entry.uri),
+				slog.String("proto", entry.proto),
+				slog.Int("status", entry.status),
+				slog.Int("bytes", entry.bytes),
+				slog.String("referer", entry.referer),
+				slog.String("user_agent", entry.userAgent),
+			)
+		}
+	}
+}
+
+// loggedURI is the request URI with the path replaced by its route template, see loggedPath, and the query string
+// redacted
+func loggedURI(c *gin.Context, redactor *Redactor) string {
+	uri := loggedPath(c)
+	if query := redactor.Query(c.Request.URL.RawQuery); query != "" {
+		uri += "?" + query
+	}
+	return uri
+}
+
+type accessLogEntry struct {
+	start     time.Time
+	clientIP  string
+	user      string
+	method    string
+	uri       string
+	proto     string
+	status    int
+	bytes     int
+	referer   string
+	userAgent string
+}
+
+// common formats the entry as host ident authuser [date] "request" status bytes
+func (e accessLogEntry) common() string {
+	bytes := "-"
+	if e.bytes > 0 {
+		bytes = fmt.Sprint(e.bytes)
+	}
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`,
+		dashIfEmpty(e.clientIP), dashIfEmpty(strings.ReplaceAll(e.user, " ", "_")), e.start.Format(clfTimeFormat), e.method, e.uri, e.proto, e.status, bytes)
+}
+
+// combined appends the referer and user agent to the Common Log Format
+func (e accessLogEntry) combined() string {
+	return fmt.Sprintf(`%s "%s" "%s"`, e.common(), quoteEscape(dashIfEmpty(e.referer)), quoteEscape(dashIfEmpty(e.userAgent)))
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// quoteEscape keeps client supplied values from breaking out of their quoted field
+func quoteEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"math/rand/v2"
+	"org/gg/banking/internal/config"
+	"strings"
+	"sync/atomic"
+)
+
+// defaultMaxBodyBytes applies when the configuration does not set a capture limit
+const defaultMaxBodyBytes = 4096
+
+// BodyCapture decides which bodies are captured for logging and how much of them is kept.
+// Its settings can be replaced at runtime with Update.
+type BodyCapture struct {
+	settings atomic.Pointer[config.BodyCaptureConfiguration]
+}
+
+// NewBodyCapture creates a BodyCapture from the configuration
+func NewBodyCapture(captureConfig config.BodyCaptureConfiguration) *BodyCapture {
+	capture := &BodyCapture{}
+	capture.Update(captureConfig)
+	return capture
+}
+
+// Update atomically replaces the capture settings
+func (b *BodyCapture) Update(captureConfig config.BodyCaptureConfiguration) {
+	if captureConfig.MaxBytes <= 0 {
+		captureConfig.MaxBytes = defaultMaxBodyBytes
+	}
+	b.settings.Store(&captureConfig)
+}
+
+// Enabled reports whether verbose request and response logging is switched on
+func (b *BodyCapture) Enabled() bool {
+	return b.settings.Load().Enabled
+}
+
+// MaxBytes returns the maximum number of body bytes kept per direction
+func (b *BodyCapture) MaxBytes() int {
+	return b.settings.Load().MaxBytes
+}
+
+// Sampled decides whether the bodies of a successful request are logged
+func (b *BodyCapture) Sampled() bool {
+	percent := b.settings.Load().SamplePercent
+	return percent >= 100 || rand.IntN(100) < percent
+}
+
+// Skips reports whether bodies of the given content type must not be captured
+func (b *BodyCapture) Skips(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range b.settings.Load().SkipContentTypes {
+		if prefix != "" && strings.HasPrefix(contentType, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
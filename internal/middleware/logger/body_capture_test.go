@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/config"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyCaptureSkips(t *testing.T) {
+	capture := NewBodyCapture(config.BodyCaptureConfiguration{
+		SkipContentTypes: []string{"multipart/form-data", "Text/Event-Stream", ""},
+	})
+
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{contentType: "multipart/form-data; boundary=x", want: true},
+		{contentType: "text/event-stream", want: true},
+		{contentType: "TEXT/EVENT-STREAM", want: true},
+		{contentType: "application/json", want: false},
+		{contentType: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := capture.Skips(tt.contentType); got != tt.want {
+			t.Errorf("Skips(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestBodyCaptureSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       config.BodyCaptureConfiguration
+		wantMaxBytes int
+		wantSampled  bool
+	}{
+		{name: "defaults", config: config.BodyCaptureConfiguration{}, wantMaxBytes: defaultMaxBodyBytes, wantSampled: false},
+		{name: "negative limit", config: config.BodyCaptureConfiguration{MaxBytes: -1, SamplePercent: 100}, wantMaxBytes: defaultMaxBodyBytes, wantSampled: true},
+		{name: "configured", config: config.BodyCaptureConfiguration{MaxBytes: 16, SamplePercent: 150}, wantMaxBytes: 16, wantSampled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := NewBodyCapture(tt.config)
+			if got := capture.MaxBytes(); got != tt.wantMaxBytes {
+				t.Errorf("MaxBytes() = %d, want %d", got, tt.wantMaxBytes)
+			}
+			if got := capture.Sampled(); got != tt.wantSampled {
+				t.Errorf("Sampled() = %v, want %v", got, tt.wantSampled)
+			}
+		})
+	}
+}
+
+func TestHTTPLoggerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redactor, err := NewRedactor(config.RedactionConfiguration{
+		BodyFields: []config.BodyFieldRedaction{{Path: "$.email", Mask: "email"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		capture          config.BodyCaptureConfiguration
+		requestBody      string
+		contentType      string
+		responseBody     string
+		status           int
+		wantRequestBody  any
+		wantResponseBody any
+	}{
+		{
+			name:             "redacted",
+			capture:          config.BodyCaptureConfiguration{Enabled: true, MaxBytes: 64, SamplePercent: 100},
+			requestBody:      `{"email":"john.doe@example.com"}`,
+			contentType:      "application/json",
+			responseBody:     `{"email":"john.doe@example.com"}`,
+			status:           http.StatusCreated,
+			wantRequestBody:  map[string]any{"email": "j***@example.com"},
+			wantResponseBody: map[string]any{"email": "j***@example.com"},
+		},
+		{
+			name:             "over the limit",
+			capture:          config.BodyCaptureConfiguration{Enabled: true, MaxBytes: 8, SamplePercent: 100},
+			requestBody:      `{"email":"john.doe@example.com"}`,
+			contentType:      "application/json",
+			responseBody:     `{"email":"john.doe@example.com"}`,
+			status:           http.StatusOK,
+			wantRequestBody:  "<body over 8 bytes omitted>",
+			wantResponseBody: "<body over 8 bytes omitted>",
+		},
+		{
+			name:             "skipped content type",
+			capture:          config.BodyCaptureConfiguration{Enabled: true, MaxBytes: 64, SamplePercent: 100, SkipContentTypes: []string{"text/csv"}},
+			requestBody:      "email\njohn.doe@example.com\n",
+			contentType:      "text/csv",
+			responseBody:     `{"email":"john.doe@example.com"}`,
+			status:           http.StatusOK,
+			wantRequestBody:  "<text/csv body not captured>",
+			wantResponseBody: map[string]any{"email": "j***@example.com"},
+		},
+		{
+			name:             "not sampled success",
+			capture:          config.BodyCaptureConfiguration{Enabled: true, MaxBytes: 64, SamplePercent: 0},
+			requestBody:      `{"email":"john.doe@example.com"}`,
+			contentType:      "application/json",
+			responseBody:     `{"email":"john.doe@example.com"}`,
+			status:           http.StatusOK,
+			wantRequestBody:  notSampledBody,
+			wantResponseBody: notSampledBody,
+		},
+		{
+			name:             "not sampled failure",
+			capture:          config.BodyCaptureConfiguration{Enabled: true, MaxBytes: 64, SamplePercent: 0},
+			requestBody:      `{"email":"john.doe@example.com"}`,
+			contentType:      "application/json",
+			responseBody:     `{"error":"conflict"}`,
+			status:           http.StatusConflict,
+			wantRequestBody:  notSampledBody,
+			wantResponseBody: map[string]any{"error": "conflict"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			var received string
+			router := gin.New()
+			router.Use(HTTPLoggerMiddleware(logger, redactor, NewBodyCapture(tt.capture)))
+			router.POST("/api/v1/customers/:email", func(c *gin.Context) {
+				body, err := io.ReadAll(c.Request.Body)
+				if err != nil {
+					t.Errorf("reading request body: %v", err)
+				}
+				received = string(body)
+				c.Data(tt.status, "application/json", []byte(tt.responseBody))
+			})
+
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/customers/john.doe@example.com", strings.NewReader(tt.requestBody))
+			request.Header.Set("Content-Type", tt.contentType)
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			if received != tt.requestBody {
+				t.Errorf("handler received %q, want the full body %q", received, tt.requestBody)
+			}
+			if response.Body.String() != tt.responseBody {
+				t.Errorf("client received %q, want the full body %q", response.Body.String(), tt.responseBody)
+			}
+
+			records := decodeRecords(t, &logs)
+			if len(records) != 2 {
+				t.Fatalf("got %d log records, want 2: %s", len(records), logs.String())
+			}
+			for i, want := range []any{tt.wantRequestBody, tt.wantResponseBody} {
+				if got := records[i]["body"]; !jsonEqual(got, want) {
+					t.Errorf("%s body = %#v, want %#v", records[i]["msg"], got, want)
+				}
+				if got := records[i]["path"]; got != "/api/v1/customers/:email" {
+					t.Errorf("%s path = %v, want the route template", records[i]["msg"], got)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPLoggerMiddlewareDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redactor, err := NewRedactor(config.RedactionConfiguration{})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	var logs bytes.Buffer
+	router := gin.New()
+	router.Use(HTTPLoggerMiddleware(slog.New(slog.NewJSONHandler(&logs, nil)), redactor, NewBodyCapture(config.BodyCaptureConfiguration{})))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if logs.Len() != 0 {
+		t.Errorf("disabled middleware logged %s", logs.String())
+	}
+}
+
+func decodeRecords(t *testing.T, logs *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	decoder := json.NewDecoder(logs)
+	for decoder.More() {
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("decoding log record: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// jsonEqual compares a decoded log value with the value that was logged
+func jsonEqual(got, want any) bool {
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	return bytes.Equal(gotJSON, wantJSON)
+}
@@ -3,96 +3,194 @@ package logger
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
-	"org/gg/banking/internal/config/logger"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ResponseBodyWriter is a custom ResponseWriter that captures the response body
+// Placeholders logged instead of a body
+const (
+	omittedBody    = "<omitted>"
+	notSampledBody = "<not sampled>"
+	emptyBody      = "<empty body>"
+)
+
+// ResponseBodyWriter is a custom ResponseWriter that captures up to a limited number of response body bytes
 type ResponseBodyWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body      *bytes.Buffer
+	capture   *BodyCapture
+	limit     int
+	truncated bool
+	skipped   bool
+	inspected bool
+}
+
+func newResponseBodyWriter(writer gin.ResponseWriter, capture *BodyCapture) *ResponseBodyWriter {
+	return &ResponseBodyWriter{
+		ResponseWriter: writer,
+		body:           &bytes.Buffer{},
+		capture:        capture,
+		limit:          capture.MaxBytes(),
+	}
 }
 
 // Write captures the response and writes it to the original writer
 func (r *ResponseBodyWriter) Write(b []byte) (int, error) {
-	r.body.Write(b)
+	r.record(b)
 	return r.ResponseWriter.Write(b)
 }
 
-// HTTPLoggerMiddleware logs both incoming requests and outgoing responses
-func HTTPLoggerMiddleware() gin.HandlerFunc {
+// WriteString captures the response and writes it to the original writer
+func (r *ResponseBodyWriter) WriteString(s string) (int, error) {
+	r.record([]byte(s))
+	return r.ResponseWriter.WriteString(s)
+}
+
+// record keeps the beginning of the body until the limit is reached.
+// Binary and streaming content types, decided by the first write, are not kept at all.
+func (r *ResponseBodyWriter) record(b []byte) {
+	if !r.inspected {
+		r.inspected = true
+		r.skipped = r.capture.Skips(r.Header().Get("Content-Type"))
+	}
+	if r.skipped || r.truncated {
+		return
+	}
+
+	remaining := r.limit - r.body.Len()
+	if len(b) > remaining {
+		b = b[:remaining]
+		r.truncated = true
+	}
+	r.body.Write(b)
+}
+
+// replayBody serves the captured prefix of a request body followed by the unread remainder
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// HTTPLoggerMiddleware logs both incoming requests and outgoing responses,
+// masking sensitive headers and body fields with the given redactor and bounding captured bodies.
+// It does nothing unless verbose logging is enabled in the body capture settings.
+func HTTPLoggerMiddleware(logger *slog.Logger, redactor *Redactor, capture *BodyCapture) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if !capture.Enabled() {
+			c.Next()
+			return
+		}
+
+		logBodies := !redactor.SkipBody(c.Request.Method, c.FullPath())
+		sampled := logBodies && capture.Sampled()
+
 		// Before request
-		logIncomingRequest(c)
+		logIncomingRequest(c, logger, redactor, capture, logBodies, sampled)
 
 		// Create a custom ResponseWriter to capture the response
-		rbw := &ResponseBodyWriter{
-			ResponseWriter: c.Writer,
-			body:           &bytes.Buffer{},
+		var rbw *ResponseBodyWriter
+		if logBodies {
+			rbw = newResponseBodyWriter(c.Writer, capture)
+			c.Writer = rbw
 		}
-		c.Writer = rbw
 
 		// Process the request
 		c.Next()
 
 		// After request - log the response
-		logOutgoingResponse(c, rbw)
+		logOutgoingResponse(c, logger, rbw, redactor, sampled)
 	}
 }
 
 // logIncomingRequest logs the details of an incoming HTTP request
-func logIncomingRequest(c *gin.Context) {
-	// Save the request body so it can be read multiple times
-	bodyValue := captureRequestBody(c)
+func logIncomingRequest(c *gin.Context, logger *slog.Logger, redactor *Redactor, capture *BodyCapture, logBody, sampled bool) {
+	var bodyValue any
+	switch {
+	case !logBody:
+		bodyValue = omittedBody
+	case !sampled:
+		bodyValue = notSampledBody
+	default:
+		// Save the request body so it can be read multiple times
+		bodyValue = captureRequestBody(c, logger, redactor, capture)
+	}
 
-	logger.Logger.InfoContext(
+	logger.InfoContext(
 		c,
 		"INCOMING REQUEST",
 		slog.String("method", c.Request.Method),
-		slog.String("path", c.Request.URL.Path),
-		slog.String("query", c.Request.URL.RawQuery),
-		slog.Any("headers", c.Request.Header),
+		slog.String("path", loggedPath(c)),
+		slog.String("query", redactor.Query(c.Request.URL.RawQuery)),
+		slog.Any("headers", redactor.Headers(c.Request.Header)),
 		slog.Any("body", bodyValue), // Use slog.Any to handle JSON objects properly
 	)
 }
 
-// captureRequestBody reads the request body and restores it for subsequent handlers
-func captureRequestBody(c *gin.Context) any {
-	var bodyBytes []byte
-	if c.Request.Body != nil {
-		var err error
-		bodyBytes, err = io.ReadAll(c.Request.Body)
+// captureRequestBody reads at most the configured number of bytes of the request body
+// and restores the full body for subsequent handlers without buffering the rest
+func captureRequestBody(c *gin.Context, logger *slog.Logger, redactor *Redactor, capture *BodyCapture) any {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return ""
+	}
 
-		if err != nil {
-			logger.Logger.ErrorContext(c, "Error reading request body", slog.Any("error", err))
-			return nil
-		}
-		// Restore the request body for subsequent handlers
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	contentType := c.GetHeader("Content-Type")
+	if capture.Skips(contentType) {
+		return fmt.Sprintf("<%s body not captured>", contentType)
+	}
+
+	limit := capture.MaxBytes()
+	bodyBytes, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+	if err != nil {
+		logger.ErrorContext(c, "Error reading request body", slog.Any("error", err))
+		return nil
+	}
+	// Restore the request body for subsequent handlers
+	c.Request.Body = replayBody{
+		Reader: io.MultiReader(bytes.NewReader(bodyBytes), c.Request.Body),
+		Closer: c.Request.Body,
 	}
 
-	return parseBody(bodyBytes, c.GetHeader("Content-Type"))
+	truncated := len(bodyBytes) > limit
+	if truncated {
+		bodyBytes = bodyBytes[:limit]
+	}
+
+	return formatBody(bodyBytes, contentType, truncated, redactor)
 }
 
-// parseBody converts raw bytes to structured data based on content type
-func parseBody(bodyBytes []byte, contentType string) any {
+// formatBody converts captured bytes to structured data based on content type and masks sensitive fields.
+// Only complete JSON bodies can be masked, any other body is only logged as text when no field needs masking.
+func formatBody(bodyBytes []byte, contentType string, truncated bool, redactor *Redactor) any {
 	// Handle empty bodies
 	if len(bodyBytes) == 0 {
 		return ""
 	}
 
+	isJSON := strings.Contains(contentType, "application/json")
+	if truncated {
+		if redactor.HasBodyRules() {
+			return fmt.Sprintf("<body over %d bytes omitted>", len(bodyBytes))
+		}
+		return string(bodyBytes) + "... (truncated)"
+	}
+
 	// If Content-Type is JSON, parse it as JSON
-	if strings.Contains(contentType, "application/json") {
+	if isJSON {
 		jsonData, success := parseJSON(bodyBytes)
 		if success {
-			return jsonData
+			return redactor.Body(jsonData)
 		}
 	}
 
+	if redactor.HasBodyRules() {
+		return fmt.Sprintf("<unredactable %s body omitted>", dashIfEmpty(contentType))
+	}
+
 	// Default case: return as string
 	return string(bodyBytes)
 }
@@ -106,59 +204,51 @@ func parseJSON(data []byte) (any, bool) {
 	return string(data), false
 }
 
-// truncateString cuts a string if it exceeds maxLength
-func truncateString(s string, maxLength int) string {
-	if len(s) > maxLength {
-		return s[:maxLength] + "... (truncated)"
+// loggedPath returns the route template of the request, such as /api/v1/customers/:email, so path parameters
+// holding personal data are not logged. Requests matching no route are logged with their path as received.
+func loggedPath(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
 	}
-	return s
+	return c.Request.URL.Path
 }
 
-// logOutgoingResponse logs the details of the HTTP response
-func logOutgoingResponse(c *gin.Context, rbw *ResponseBodyWriter) {
+// logOutgoingResponse logs the details of the HTTP response.
+// Bodies of failed requests are logged even when the request was not sampled.
+func logOutgoingResponse(c *gin.Context, logger *slog.Logger, rbw *ResponseBodyWriter, redactor *Redactor, sampled bool) {
 	// Get status code and size
-	statusCode := rbw.Status()
-	responseSize := rbw.body.Len()
+	statusCode := c.Writer.Status()
+	responseSize := max(c.Writer.Size(), 0) // gin reports -1 when nothing was written
 
 	// Get response headers
-	responseHeaders := rbw.Header()
+	responseHeaders := c.Writer.Header()
 
 	// Process the response body
 	contentType := responseHeaders.Get("Content-Type")
 	var bodyValue any
 
-	// Only process non-empty bodies
-	if responseSize > 0 {
-		responseBody := rbw.body.Bytes()
-		bodyValue = processResponseBody(responseBody, contentType)
-	} else {
-		bodyValue = "<empty body>"
+	switch {
+	case rbw == nil:
+		bodyValue = omittedBody
+	case !sampled && statusCode < http.StatusBadRequest:
+		bodyValue = notSampledBody
+	case rbw.skipped:
+		bodyValue = fmt.Sprintf("<%s body not captured>", contentType)
+	case rbw.body.Len() > 0:
+		bodyValue = formatBody(rbw.body.Bytes(), contentType, rbw.truncated, redactor)
+	default:
+		bodyValue = emptyBody
 	}
 
 	// Log the response
-	logger.Logger.InfoContext(
+	logger.InfoContext(
 		c,
 		"OUTGOING RESPONSE",
 		slog.Int("status", statusCode),
 		slog.Int("size", responseSize),
-		slog.Any("headers", responseHeaders),
+		slog.Any("headers", redactor.Headers(responseHeaders)),
 		slog.Any("body", bodyValue),
-		slog.String("path", c.Request.URL.Path),
+		slog.String("path", loggedPath(c)),
 		slog.String("method", c.Request.Method),
 	)
 }
-
-// processResponseBody handles formatting response body based on content type
-func processResponseBody(responseBody []byte, contentType string) any {
-	// If content type is JSON, parse it as JSON
-	if strings.Contains(contentType, "application/json") {
-		jsonData, success := parseJSON(responseBody)
-		if success {
-			return jsonData
-		}
-	}
-
-	// For non-JSON content types, convert to string and truncate if needed
-	bodyStr := string(responseBody)
-	return truncateString(bodyStr, 1000)
-}
@@ -0,0 +1,272 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"org/gg/banking/internal/config"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const redactedValue = "[REDACTED]"
+
+// unparseableQuery replaces a query string whose parameters cannot be told apart, so none of them can be masked
+const unparseableQuery = "<unparseable query omitted>"
+
+// alwaysRedactedHeaders are masked regardless of configuration
+var alwaysRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Redactor masks sensitive headers, query parameters and JSON body fields before they are logged.
+// Its rules can be replaced at runtime with Update.
+type Redactor struct {
+	rules atomic.Pointer[redactionRules]
+}
+
+// redactionRules is the compiled, immutable form of config.RedactionConfiguration
+type redactionRules struct {
+	headers        map[string]bool
+	bodyFields     []bodyFieldRule
+	skipBodyRoutes map[string]bool
+	// queryParams maps lower case parameter names to their mask
+	queryParams map[string]maskFunc
+}
+
+type bodyFieldRule struct {
+	path []pathSegment
+	mask maskFunc
+}
+
+// pathSegment is one step of a JSON path: a field name, an array index or the [*] wildcard
+type pathSegment struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+type maskFunc func(value any) any
+
+var maskFormats = map[string]maskFunc{
+	"full":    maskFull,
+	"partial": maskPartial,
+	"email":   maskEmail,
+	"last4":   maskLast4,
+}
+
+// NewRedactor compiles the redaction configuration
+func NewRedactor(redactionConfig config.RedactionConfiguration) (*Redactor, error) {
+	redactor := &Redactor{}
+	if err := redactor.Update(redactionConfig); err != nil {
+		return nil, err
+	}
+	return redactor, nil
+}
+
+// Update atomically replaces the redaction rules. The previous rules stay active if the configuration is invalid.
+func (r *Redactor) Update(redactionConfig config.RedactionConfiguration) error {
+	rules := &redactionRules{
+		headers:        map[string]bool{},
+		skipBodyRoutes: map[string]bool{},
+		queryParams:    map[string]maskFunc{},
+	}
+
+	for _, header := range append(alwaysRedactedHeaders, redactionConfig.Headers...) {
+		rules.headers[http.CanonicalHeaderKey(header)] = true
+	}
+
+	for _, field := range redactionConfig.BodyFields {
+		path, err := parseJSONPath(field.Path)
+		if err != nil {
+			return err
+		}
+		mask, ok := maskFormats[strings.ToLower(field.Mask)]
+		if !ok {
+			return fmt.Errorf("unknown mask format %q for path %s", field.Mask, field.Path)
+		}
+		rules.bodyFields = append(rules.bodyFields, bodyFieldRule{path: path, mask: mask})
+	}
+
+	for _, route := range redactionConfig.SkipBodyRoutes {
+		rules.skipBodyRoutes[strings.TrimSpace(route)] = true
+	}
+
+	for _, param := range redactionConfig.QueryParams {
+		mask, ok := maskFormats[strings.ToLower(param.Mask)]
+		if !ok {
+			return fmt.Errorf("unknown mask format %q for query parameter %s", param.Mask, param.Name)
+		}
+		rules.queryParams[strings.ToLower(param.Name)] = mask
+	}
+
+	r.rules.Store(rules)
+	return nil
+}
+
+// Headers returns a copy of the headers with denied values replaced
+func (r *Redactor) Headers(headers http.Header) http.Header {
+	rules := r.rules.Load()
+	redacted := make(http.Header, len(headers))
+	for name, values := range headers {
+		if rules.headers[http.CanonicalHeaderKey(name)] {
+			redacted[name] = []string{redactedValue}
+			continue
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+// Query returns the raw query string with the values of the configured parameters masked, the other parameters are
+// kept as they were sent
+func (r *Redactor) Query(rawQuery string) string {
+	rules := r.rules.Load()
+	if rawQuery == "" || len(rules.queryParams) == 0 {
+		return rawQuery
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, value, _ := strings.Cut(param, "=")
+		unescapedName, err := url.QueryUnescape(name)
+		if err != nil {
+			return unparseableQuery
+		}
+		mask, ok := rules.queryParams[strings.ToLower(unescapedName)]
+		if !ok {
+			continue
+		}
+		unescapedValue, err := url.QueryUnescape(value)
+		if err != nil {
+			return unparseableQuery
+		}
+		params[i] = name + "=" + fmt.Sprint(mask(unescapedValue))
+	}
+	return strings.Join(params, "&")
+}
+
+// Body masks the configured fields of a parsed JSON body in place and returns it
+func (r *Redactor) Body(body any) any {
+	for _, rule := range r.rules.Load().bodyFields {
+		body = applyMask(body, rule.path, rule.mask)
+	}
+	return body
+}
+
+// HasBodyRules reports whether any JSON body field is masked
+func (r *Redactor) HasBodyRules() bool {
+	return len(r.rules.Load().bodyFields) > 0
+}
+
+// SkipBody reports whether bodies of the given route must not be logged at all
+func (r *Redactor) SkipBody(method, route string) bool {
+	rules := r.rules.Load()
+	return rules.skipBodyRoutes[method+" "+route] || rules.skipBodyRoutes[route]
+}
+
+// applyMask walks the path and replaces every matching value with its masked form
+func applyMask(node any, path []pathSegment, mask maskFunc) any {
+	if len(path) == 0 {
+		return mask(node)
+	}
+
+	segment, rest := path[0], path[1:]
+	switch typed := node.(type) {
+	case map[string]any:
+		if segment.isIndex || segment.wildcard {
+			return node
+		}
+		if value, ok := typed[segment.field]; ok {
+			typed[segment.field] = applyMask(value, rest, mask)
+		}
+	case []any:
+		if segment.wildcard {
+			for i := range typed {
+				typed[i] = applyMask(typed[i], rest, mask)
+			}
+		} else if segment.isIndex && segment.index < len(typed) {
+			typed[segment.index] = applyMask(typed[segment.index], rest, mask)
+		}
+	}
+
+	return node
+}
+
+// parseJSONPath parses the subset of JSON path used for redaction: $, .field, [n] and [*]
+func parseJSONPath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSON path %q must start with $", path)
+	}
+
+	var segments []pathSegment
+	remaining := path[1:]
+	for remaining != "" {
+		switch remaining[0] {
+		case '.':
+			end := strings.IndexAny(remaining[1:], ".[")
+			if end == -1 {
+				end = len(remaining) - 1
+			}
+			field := remaining[1 : end+1]
+			if field == "" {
+				return nil, fmt.Errorf("JSON path %q has an empty field name", path)
+			}
+			segments = append(segments, pathSegment{field: field})
+			remaining = remaining[end+1:]
+		case '[':
+			end := strings.IndexByte(remaining, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("JSON path %q has an unterminated [", path)
+			}
+			selector := remaining[1:end]
+			if selector == "*" {
+				segments = append(segments, pathSegment{wildcard: true})
+			} else {
+				index, err := strconv.Atoi(selector)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("JSON path %q has an invalid index %q", path, selector)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+			remaining = remaining[end+1:]
+		default:
+			return nil, fmt.Errorf("JSON path %q is invalid near %q", path, remaining)
+		}
+	}
+
+	return segments, nil
+}
+
+// maskFull hides the value entirely
+func maskFull(any) any {
+	return redactedValue
+}
+
+// maskPartial keeps the first and last two characters, e.g. 555-1234 becomes 55****34
+func maskPartial(value any) any {
+	s := fmt.Sprint(value)
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:2] + strings.Repeat("*", len(s)-4) + s[len(s)-2:]
+}
+
+// maskEmail keeps the first character of the local part and the domain, e.g. j***@example.com
+func maskEmail(value any) any {
+	s := fmt.Sprint(value)
+	at := strings.LastIndexByte(s, '@')
+	if at <= 0 {
+		return maskPartial(s)
+	}
+	return s[:1] + "***" + s[at:]
+}
+
+// maskLast4 keeps only the last four characters, e.g. ****7890
+func maskLast4(value any) any {
+	s := fmt.Sprint(value)
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
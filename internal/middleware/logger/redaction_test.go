@@ -0,0 +1,331 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/config"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMasks(t *testing.T) {
+	tests := []struct {
+		name  string
+		mask  maskFunc
+		value any
+		want  any
+	}{
+		{name: "full", mask: maskFull, value: "secret", want: redactedValue},
+		{name: "partial", mask: maskPartial, value: "555-1234", want: "55****34"},
+		{name: "partial short", mask: maskPartial, value: "1234", want: "****"},
+		{name: "email", mask: maskEmail, value: "john.doe@example.com", want: "j***@example.com"},
+		{name: "email without local part", mask: maskEmail, value: "@example.com", want: "@e********om"},
+		{name: "email without at", mask: maskEmail, value: "johndoe", want: "jo***oe"},
+		{name: "last4", mask: maskLast4, value: "ACC-1234567890", want: "**********7890"},
+		{name: "last4 short", mask: maskLast4, value: "123", want: "***"},
+		{name: "last4 number", mask: maskLast4, value: 123456.78, want: "*****6.78"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mask(tt.value); got != tt.want {
+				t.Errorf("mask(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathSegment
+		wantErr bool
+	}{
+		{path: "$", want: nil},
+		{path: "$.email", want: []pathSegment{{field: "email"}}},
+		{path: "$.accounts[*].balance", want: []pathSegment{{field: "accounts"}, {wildcard: true}, {field: "balance"}}},
+		{path: "$.accounts[2]", want: []pathSegment{{field: "accounts"}, {index: 2, isIndex: true}}},
+		{path: "$[0].email", want: []pathSegment{{index: 0, isIndex: true}, {field: "email"}}},
+		{path: "email", wantErr: true},
+		{path: "$.", wantErr: true},
+		{path: "$.accounts[", wantErr: true},
+		{path: "$.accounts[-1]", wantErr: true},
+		{path: "$.accounts[x]", wantErr: true},
+		{path: "$email", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJSONPath(%q) = %+v, want %+v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorBody(t *testing.T) {
+	redactor, err := NewRedactor(config.RedactionConfiguration{
+		BodyFields: []config.BodyFieldRedaction{
+			{Path: "$.email", Mask: "email"},
+			{Path: "$.phone", Mask: "PARTIAL"},
+			{Path: "$.accounts[*].account_number", Mask: "last4"},
+			{Path: "$.accounts[0].balance", Mask: "full"},
+			{Path: "$[*].email", Mask: "email"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "object",
+			body: `{"email":"john.doe@example.com","phone":"555-1234","first_name":"John"}`,
+			want: `{"email":"j***@example.com","first_name":"John","phone":"55****34"}`,
+		},
+		{
+			name: "nested array",
+			body: `{"accounts":[{"account_number":"ACC-10001","balance":6000},{"account_number":"ACC-10002","balance":25000}]}`,
+			want: `{"accounts":[{"account_number":"*****0001","balance":"[REDACTED]"},{"account_number":"*****0002","balance":25000}]}`,
+		},
+		{
+			name: "top level array",
+			body: `[{"email":"jane.smith@example.com"},{"email":"gg@gmail.com"}]`,
+			want: `[{"email":"j***@example.com"},{"email":"g***@gmail.com"}]`,
+		},
+		{
+			name: "missing fields",
+			body: `{"first_name":"John","accounts":"none"}`,
+			want: `{"accounts":"none","first_name":"John"}`,
+		},
+		{
+			name: "scalar",
+			body: `"john.doe@example.com"`,
+			want: `"john.doe@example.com"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body any
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			got, err := json.Marshal(redactor.Body(body))
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Body(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorHeaders(t *testing.T) {
+	redactor, err := NewRedactor(config.RedactionConfiguration{Headers: []string{"x-api-key"}})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	headers := http.Header{
+		"Authorization": {"Bearer token"},
+		"Cookie":        {"session=1"},
+		"X-Api-Key":     {"bk_secret"},
+		"Content-Type":  {"application/json"},
+	}
+	want := http.Header{
+		"Authorization": {redactedValue},
+		"Cookie":        {redactedValue},
+		"X-Api-Key":     {redactedValue},
+		"Content-Type":  {"application/json"},
+	}
+	if got := redactor.Headers(headers); !reflect.DeepEqual(got, want) {
+		t.Errorf("Headers() = %v, want %v", got, want)
+	}
+	if headers.Get("Authorization") != "Bearer token" {
+		t.Error("Headers() modified its argument")
+	}
+}
+
+func TestRedactorSkipBody(t *testing.T) {
+	redactor, err := NewRedactor(config.RedactionConfiguration{
+		SkipBodyRoutes: []string{"POST /api/v1/customers/", " /api/v1/api-keys/ "},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		route  string
+		want   bool
+	}{
+		{method: http.MethodPost, route: "/api/v1/customers/", want: true},
+		{method: http.MethodGet, route: "/api/v1/customers/", want: false},
+		{method: http.MethodGet, route: "/api/v1/api-keys/", want: true},
+		{method: http.MethodPost, route: "/api/v1/api-keys/", want: true},
+		{method: http.MethodGet, route: "/api/v1/customers/:email", want: false},
+	}
+	for _, tt := range tests {
+		if got := redactor.SkipBody(tt.method, tt.route); got != tt.want {
+			t.Errorf("SkipBody(%s, %s) = %v, want %v", tt.method, tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestRedactorUpdateKeepsPreviousRules(t *testing.T) {
+	redactor, err := NewRedactor(config.RedactionConfiguration{
+		BodyFields: []config.BodyFieldRedaction{{Path: "$.email", Mask: "full"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	invalid := []config.RedactionConfiguration{
+		{BodyFields: []config.BodyFieldRedaction{{Path: "email", Mask: "full"}}},
+		{BodyFields: []config.BodyFieldRedaction{{Path: "$.email", Mask: "hash"}}},
+		{QueryParams: []config.QueryParamRedaction{{Name: "actor", Mask: "hash"}}},
+	}
+	for _, redactionConfig := range invalid {
+		if err := redactor.Update(redactionConfig); err == nil {
+			t.Errorf("Update(%+v) succeeded, want an error", redactionConfig)
+		}
+	}
+
+	body := redactor.Body(map[string]any{"email": "john.doe@example.com"})
+	if got := body.(map[string]any)["email"]; got != redactedValue {
+		t.Errorf("email = %v after rejected updates, want %v", got, redactedValue)
+	}
+}
+
+func TestFormatBody(t *testing.T) {
+	withRules, err := NewRedactor(config.RedactionConfiguration{
+		BodyFields: []config.BodyFieldRedaction{{Path: "$.email", Mask: "email"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	withoutRules, err := NewRedactor(config.RedactionConfiguration{})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		redactor    *Redactor
+		body        string
+		contentType string
+		truncated   bool
+		want        any
+	}{
+		{name: "empty", redactor: withRules, body: "", contentType: "application/json", want: ""},
+		{name: "json", redactor: withRules, body: `{"email":"john.doe@example.com"}`, contentType: "application/json; charset=utf-8",
+			want: map[string]any{"email": "j***@example.com"}},
+		{name: "truncated with rules", redactor: withRules, body: `{"email":"jo`, contentType: "application/json", truncated: true,
+			want: "<body over 12 bytes omitted>"},
+		{name: "truncated text with rules", redactor: withRules, body: "john.doe", contentType: "text/plain", truncated: true,
+			want: "<body over 8 bytes omitted>"},
+		{name: "truncated without rules", redactor: withoutRules, body: `{"email":"jo`, contentType: "application/json", truncated: true,
+			want: `{"email":"jo... (truncated)`},
+		{name: "invalid json with rules", redactor: withRules, body: `{"email":`, contentType: "application/json",
+			want: "<unredactable application/json body omitted>"},
+		{name: "form with rules", redactor: withRules, body: "email=john.doe%40example.com", contentType: "application/x-www-form-urlencoded",
+			want: "<unredactable application/x-www-form-urlencoded body omitted>"},
+		{name: "no content type with rules", redactor: withRules, body: "john.doe@example.com", contentType: "",
+			want: "<unredactable - body omitted>"},
+		{name: "text without rules", redactor: withoutRules, body: "hello", contentType: "text/plain", want: "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatBody([]byte(tt.body), tt.contentType, tt.truncated, tt.redactor)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("formatBody() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorQuery(t *testing.T) {
+	withRules, err := NewRedactor(config.RedactionConfiguration{
+		QueryParams: []config.QueryParamRedaction{
+			{Name: "actor", Mask: "email"},
+			{Name: "Entity_ID", Mask: "partial"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	withoutRules, err := NewRedactor(config.RedactionConfiguration{})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		redactor *Redactor
+		query    string
+		want     string
+	}{
+		{name: "empty", redactor: withRules, query: "", want: ""},
+		{name: "masked", redactor: withRules, query: "actor=john.doe@example.com&limit=10", want: "actor=j***@example.com&limit=10"},
+		{name: "escaped value", redactor: withRules, query: "actor=john.doe%40example.com", want: "actor=j***@example.com"},
+		{name: "name case insensitive", redactor: withRules, query: "ACTOR=john.doe@example.com", want: "ACTOR=j***@example.com"},
+		{name: "escaped name", redactor: withRules, query: "entity%5Fid=555-1234", want: "entity%5Fid=55****34"},
+		{name: "repeated", redactor: withRules, query: "actor=a@x.io&actor=b@y.io", want: "actor=a***@x.io&actor=b***@y.io"},
+		{name: "without value", redactor: withRules, query: "actor&offset=20", want: "actor=&offset=20"},
+		{name: "other params kept", redactor: withRules, query: "limit=10&offset=20", want: "limit=10&offset=20"},
+		{name: "invalid escape", redactor: withRules, query: "actor=%zz", want: unparseableQuery},
+		{name: "invalid escape in name", redactor: withRules, query: "%zz=1", want: unparseableQuery},
+		{name: "without rules", redactor: withoutRules, query: "actor=john.doe@example.com", want: "actor=john.doe@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.redactor.Query(tt.query); got != tt.want {
+				t.Errorf("Query(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggedPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	redactor, err := NewRedactor(config.RedactionConfiguration{
+		QueryParams: []config.QueryParamRedaction{{Name: "actor", Mask: "email"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRedactor: %v", err)
+	}
+	router := gin.New()
+	var got string
+	router.GET("/api/v1/customers/:email", func(c *gin.Context) {
+		got = loggedURI(c, redactor)
+	})
+	router.NoRoute(func(c *gin.Context) {
+		got = loggedURI(c, redactor)
+	})
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "/api/v1/customers/john.doe@example.com?expand=accounts", want: "/api/v1/customers/:email?expand=accounts"},
+		{target: "/api/v1/customers/john.doe@example.com", want: "/api/v1/customers/:email"},
+		{target: "/unknown", want: "/unknown"},
+		{target: "/api/v1/audit?actor=john.doe@example.com&limit=5", want: "/api/v1/audit?actor=j***@example.com&limit=5"},
+	}
+	for _, tt := range tests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if got != tt.want {
+			t.Errorf("loggedURI(%s) = %s, want %s", tt.target, got, tt.want)
+		}
+	}
+}
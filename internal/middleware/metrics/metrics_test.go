@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/metrics"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrape returns the value of a sample of the application registry, or -1 when it is not exposed
+func scrape(t *testing.T, sample string) float64 {
+	t.Helper()
+	response := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(response.Body.String(), "\n") {
+		if value, found := strings.CutPrefix(line, sample+" "); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("parsing sample %q: %v", line, err)
+			}
+			return parsed
+		}
+	}
+	return -1
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var inFlight float64
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/test/customers/:email", func(c *gin.Context) {
+		inFlight = scrape(t, "banking_http_requests_in_flight")
+		c.Status(http.StatusOK)
+	})
+	router.DELETE("/test/customers/:email", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{method: http.MethodGet, path: "/test/customers/john.doe@example.com"},
+		{method: http.MethodGet, path: "/test/customers/jane.doe@example.com"},
+		{method: http.MethodDelete, path: "/test/customers/john.doe@example.com"},
+		{method: http.MethodGet, path: "/test/wp-admin.php"},
+	}
+	for _, request := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(request.method, request.path, nil))
+	}
+
+	// Requests are labelled by route template, so each customer does not add a series
+	tests := []struct {
+		sample string
+		want   float64
+	}{
+		{sample: `banking_http_requests_total{method="GET",route="/test/customers/:email",status="200"}`, want: 2},
+		{sample: `banking_http_requests_total{method="DELETE",route="/test/customers/:email",status="404"}`, want: 1},
+		{sample: `banking_http_requests_total{method="GET",route="unmatched",status="404"}`, want: 1},
+		{sample: `banking_http_request_duration_seconds_count{method="GET",route="/test/customers/:email",status="200"}`, want: 2},
+		{sample: `banking_http_requests_in_flight`, want: 0},
+	}
+	for _, tt := range tests {
+		if got := scrape(t, tt.sample); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.sample, got, tt.want)
+		}
+	}
+	if inFlight != 1 {
+		t.Errorf("requests in flight while handling = %v, want 1", inFlight)
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/metrics"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware takes a token from the caller's bucket for the matched route and rejects the request with
+// 429 problem details when the bucket is empty. It must run after authentication to count per API key or principal.
+// Requests are let through when the store fails, an outage of the limiter must not take the API down.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, limited := limiter.Rule(c.Request.Method + " " + c.FullPath())
+		if !limited {
+			c.Next()
+			return
+		}
+		limit(c, limiter, rule, subject(c, rule.Key), logger)
+	}
+}
+
+// PreAuthRateLimitMiddleware counts every request per client ip before it is authenticated, so callers guessing
+// tokens or API keys are throttled as well. It runs before the auth middleware, RateLimitMiddleware after it.
+func PreAuthRateLimitMiddleware(limiter *ratelimit.Limiter, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, limited := limiter.PreAuth()
+		if !limited {
+			c.Next()
+			return
+		}
+		limit(c, limiter, rule, "ip:"+c.ClientIP(), logger)
+	}
+}
+
+// limit takes a token from the bucket of subject under rule and continues or rejects the request
+func limit(c *gin.Context, limiter *ratelimit.Limiter, rule ratelimit.Rule, subject string, logger *slog.Logger) {
+	decision, err := limiter.Allow(c.Request.Context(), rule, subject)
+	if err != nil {
+		logger.ErrorContext(c, "Rate limiter failed, allowing request", slog.String("rule", rule.Name), slog.Any("error", err))
+		c.Next()
+		return
+	}
+
+	// Set before authentication, these are replaced by the headers of the route rule when there is one
+	c.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	c.Header("RateLimit-Reset", seconds(decision.Reset))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", rule.Requests, seconds(rule.Period)))
+
+	if !decision.Allowed {
+		metrics.RateLimitedRequests.WithLabelValues(rule.Name).Inc()
+		c.Header("Retry-After", seconds(decision.RetryAfter))
+		_ = c.Error(errors.TooManyRequestsError(fmt.Sprintf("Rate limit of %d requests per %s exceeded", rule.Requests, rule.Period)))
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// subject identifies the caller for the key of a rule, falling back to the client ip for anonymous callers
+func subject(c *gin.Context, key string) string {
+	principal, authenticated := auth.PrincipalFromContext(c.Request.Context())
+	switch {
+	case key == ratelimit.KeyPrincipal && authenticated && principal.Subject != "":
+		return "principal:" + principal.Subject
+	case key == ratelimit.KeyAPIKey && authenticated && strings.HasPrefix(principal.Subject, auth.APIKeySubjectPrefix):
+		return principal.Subject
+	default:
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// seconds formats a duration as whole seconds, rounded up so clients never retry too early
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPreAuthRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := ratelimit.NewLimiter(config.RateLimitConfiguration{
+		Default: config.RateLimitRule{Requests: 100, Period: time.Minute, Key: ratelimit.KeyPrincipal},
+		PreAuth: config.RateLimitRule{Requests: 60, Period: time.Minute, Burst: 2},
+	}, ratelimit.NewMemoryStore())
+
+	// Rejects every request like the auth middleware does for a wrong token
+	rejectAll := func(c *gin.Context) {
+		_ = c.Error(errors.UnauthorizedError("invalid token"))
+		c.Abort()
+	}
+
+	router := gin.New()
+	router.Use(errors.ErrorHandlerMiddleware(logger))
+	group := router.Group("/api/v1", PreAuthRateLimitMiddleware(limiter, logger), rejectAll, RateLimitMiddleware(limiter, logger))
+	group.GET("/customers/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		remoteAddr string
+		wantStatus int
+	}{
+		{remoteAddr: "192.0.2.1:1000", wantStatus: http.StatusUnauthorized},
+		{remoteAddr: "192.0.2.1:1001", wantStatus: http.StatusUnauthorized},
+		{remoteAddr: "192.0.2.1:1002", wantStatus: http.StatusTooManyRequests},
+		{remoteAddr: "192.0.2.2:1000", wantStatus: http.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/customers/", nil)
+		request.RemoteAddr = tt.remoteAddr
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		if response.Code != tt.wantStatus {
+			t.Errorf("request %d from %s: status %d, want %d", i+1, tt.remoteAddr, response.Code, tt.wantStatus)
+		}
+		if tt.wantStatus == http.StatusTooManyRequests && response.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: throttled without Retry-After", i+1)
+		}
+	}
+}
+
+func TestRateLimitMiddlewareSkipsUnlimitedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	limiter := ratelimit.NewLimiter(config.RateLimitConfiguration{
+		Default: config.RateLimitRule{Requests: 1, Period: time.Hour},
+		Routes:  []config.RateLimitRule{{Route: "GET /health", Requests: 0}},
+	}, ratelimit.NewMemoryStore())
+
+	router := gin.New()
+	router.Use(errors.ErrorHandlerMiddleware(logger), RateLimitMiddleware(limiter, logger))
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/limited", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{path: "/health", wantStatus: http.StatusOK},
+		{path: "/health", wantStatus: http.StatusOK},
+		{path: "/limited", wantStatus: http.StatusOK},
+		{path: "/limited", wantStatus: http.StatusTooManyRequests},
+	}
+	for i, tt := range tests {
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if response.Code != tt.wantStatus {
+			t.Errorf("request %d to %s: status %d, want %d", i+1, tt.path, response.Code, tt.wantStatus)
+		}
+	}
+}
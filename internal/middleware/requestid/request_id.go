@@ -0,0 +1,67 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"org/gg/banking/internal/config/logger"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// HeaderName carries the request id on both requests and responses
+	HeaderName = "X-Request-ID"
+	// traceParentHeader is the W3C trace context header, its trace id is used when no request id is sent
+	traceParentHeader = "traceparent"
+)
+
+var (
+	// validRequestID keeps inbound ids safe to echo in headers, logs and SQL comments
+	validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+	// validTraceParent matches version-traceid-parentid-flags as defined by W3C Trace Context
+	validTraceParent = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+)
+
+// RequestIDMiddleware assigns every request an id, stores it in the request context for logging
+// and echoes it in the X-Request-ID response header
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := resolveRequestID(c)
+
+		ctx := logger.WithRequest(c.Request.Context(), id, c.FullPath(), c.ClientIP(), time.Now())
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(HeaderName, id)
+
+		c.Next()
+	}
+}
+
+// resolveRequestID prefers a valid inbound X-Request-ID, then the trace id of the request span or of a valid traceparent,
+// and otherwise generates a new id
+func resolveRequestID(c *gin.Context) string {
+	if id := c.GetHeader(HeaderName); validRequestID.MatchString(id) {
+		return id
+	}
+
+	if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+
+	if match := validTraceParent.FindStringSubmatch(c.GetHeader(traceParentHeader)); match != nil {
+		if match[1] != "00000000000000000000000000000000" {
+			return match[1]
+		}
+	}
+
+	return newRequestID()
+}
+
+// newRequestID returns 16 random bytes as hex, the same shape as a W3C trace id
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package requestid
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/middleware/errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var generatedID = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		// want is the expected id, empty when a new one must be generated
+		want string
+	}{
+		{name: "inbound id", headers: map[string]string{HeaderName: "req-42.a_b"}, want: "req-42.a_b"},
+		{name: "inbound id wins over traceparent", headers: map[string]string{
+			HeaderName:        "req-42",
+			traceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		}, want: "req-42"},
+		{name: "trace id of traceparent", headers: map[string]string{
+			traceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		}, want: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "invalid inbound id falls back to traceparent", headers: map[string]string{
+			HeaderName:        "req 42",
+			traceParentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		}, want: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{name: "none"},
+		{name: "id closing a SQL comment", headers: map[string]string{HeaderName: "x*/ DROP TABLE customers"}},
+		{name: "id too long", headers: map[string]string{HeaderName: strings.Repeat("a", 129)}},
+		{name: "zero trace id", headers: map[string]string{traceParentHeader: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"}},
+		{name: "malformed traceparent", headers: map[string]string{traceParentHeader: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen *logger.RequestContext
+			router := gin.New()
+			router.Use(RequestIDMiddleware(), errors.ErrorHandlerMiddleware(slog.New(slog.NewTextHandler(io.Discard, nil))))
+			router.GET("/customers/:email", func(c *gin.Context) {
+				seen = logger.RequestFromContext(c.Request.Context())
+				_ = c.Error(errors.NotFoundError("customer not found"))
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/customers/john.doe@example.com", nil)
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			id := response.Header().Get(HeaderName)
+			if tt.want != "" && id != tt.want {
+				t.Errorf("%s = %q, want %q", HeaderName, id, tt.want)
+			}
+			if tt.want == "" && !generatedID.MatchString(id) {
+				t.Errorf("%s = %q, want a generated id", HeaderName, id)
+			}
+
+			if seen == nil {
+				t.Fatal("handler context carries no request")
+			}
+			if seen.ID != id || seen.Route != "/customers/:email" {
+				t.Errorf("request context id %q route %q, want %q and the route template", seen.ID, seen.Route, id)
+			}
+
+			var problem errors.ProblemDetails
+			if err := json.Unmarshal(response.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decoding problem details %q: %v", response.Body.String(), err)
+			}
+			if problem.RequestID != id {
+				t.Errorf("problem details request_id = %q, want %q", problem.RequestID, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// AccountMovement is one change to the balance of an account, positive for money in
+type AccountMovement struct {
+	ID          int64
+	AccountID   int64
+	Amount      float64
+	Description string
+	OccurredAt  time.Time
+}
+
+// AccountStatementLine is a movement of a statement together with the balance after it
+type AccountStatementLine struct {
+	AccountMovement
+	Balance float64
+}
+
+// AccountStatement lists the movements of an account over a period, between the balance at its start and at its end
+type AccountStatement struct {
+	Customer Customer
+	Account  Account
+	// From and To are the first and the last day of the period, both included
+	From           time.Time
+	To             time.Time
+	OpeningBalance float64
+	Lines          []AccountStatementLine
+	ClosingBalance float64
+	GeneratedAt    time.Time
+}
+
+// AccountStatementRequest selects the account and the days of a statement
+type AccountStatementRequest struct {
+	AccountNumber string
+	// From and To are dates, their time of day is ignored
+	From time.Time
+	To   time.Time
+}
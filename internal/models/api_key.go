@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// APIKey is a partner credential. Only the SHA-256 hash of its secret is stored.
+type APIKey struct {
+	ID          int64
+	Prefix      string
+	SecretHash  string
+	Name        string
+	OwnerEmail  sql.NullString
+	Scopes      []string
+	CreatedAt   time.Time
+	ExpiresAt   sql.NullTime
+	LastUsedAt  sql.NullTime
+	RevokedAt   sql.NullTime
+	RotatedFrom sql.NullInt64
+}
+
+// Active reports whether the key is neither revoked nor expired at the given time
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt.Valid {
+		return false
+	}
+	return !k.ExpiresAt.Valid || now.Before(k.ExpiresAt.Time)
+}
+
+type APIKeyDTO struct {
+	ID          int64      `json:"id"`
+	Prefix      string     `json:"prefix"`
+	Name        string     `json:"name"`
+	OwnerEmail  string     `json:"owner_email,omitempty"`
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RotatedFrom *int64     `json:"rotated_from,omitempty"`
+}
+
+// IssueAPIKeyRequest describes a key to issue. Scopes are the roles the key acts with.
+type IssueAPIKeyRequest struct {
+	Name       string     `json:"name" binding:"required,max=100"`
+	OwnerEmail string     `json:"owner_email" binding:"omitempty,email"`
+	Scopes     []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// IssuedAPIKeyDTO is returned once when a key is issued or rotated, it is the only time the secret is shown
+type IssuedAPIKeyDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
+
+// ToAPIKeyDTO Convert from DB model to response model, leaving out the secret hash
+func (k APIKey) ToAPIKeyDTO() APIKeyDTO {
+	dto := APIKeyDTO{
+		ID:         k.ID,
+		Prefix:     k.Prefix,
+		Name:       k.Name,
+		OwnerEmail: k.OwnerEmail.String,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+	}
+	if k.ExpiresAt.Valid {
+		dto.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		dto.LastUsedAt = &k.LastUsedAt.Time
+	}
+	if k.RevokedAt.Valid {
+		dto.RevokedAt = &k.RevokedAt.Time
+	}
+	if k.RotatedFrom.Valid {
+		dto.RotatedFrom = &k.RotatedFrom.Int64
+	}
+	return dto
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited changes
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// Audited entities
+const (
+	AuditEntityCustomer = "customer"
+	AuditEntityAccount  = "account"
+	AuditEntityAPIKey   = "api_key"
+	AuditEntityWebhook  = "webhook"
+)
+
+// AuditEntry records who changed which entity and how. Entries are never updated or deleted.
+type AuditEntry struct {
+	ID         int64
+	OccurredAt time.Time
+	// Actor is the subject of the principal, or system for unattended changes
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	// Before and After are JSON snapshots of the entity, Before is empty for creations and After for deletions
+	Before    json.RawMessage
+	After     json.RawMessage
+	RequestID string
+	ClientIP  string
+	// PrevHash and Hash chain the entry to its predecessor, both are empty for entries recorded before the chain
+	PrevHash string
+	Hash     string
+}
+
+type AuditEntryDTO struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	ClientIP   string          `json:"client_ip,omitempty"`
+	PrevHash   string          `json:"prev_hash,omitempty"`
+	Hash       string          `json:"hash,omitempty"`
+}
+
+// AuditFilter selects audit entries, empty fields match every entry. Entries are returned newest first.
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   string
+	RequestID  string
+	From       time.Time
+	To         time.Time
+	// BeforeID continues a listing after the last entry of the previous page
+	BeforeID int64
+	Limit    int
+}
+
+// ToAuditEntryDTO Convert from DB model to response model
+func (e AuditEntry) ToAuditEntryDTO() AuditEntryDTO {
+	return AuditEntryDTO{
+		ID:         e.ID,
+		OccurredAt: e.OccurredAt,
+		Actor:      e.Actor,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		Before:     e.Before,
+		After:      e.After,
+		RequestID:  e.RequestID,
+		ClientIP:   e.ClientIP,
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+}
@@ -1,5 +1,13 @@
 package models
 
+import "strings"
+
+// NormalizeEmail returns the form in which emails are stored, looked up and compared. Addresses are treated as case
+// insensitive, so John.Doe@Example.com and john.doe@example.com are the same customer.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(email)
+}
+
 // Customer represents a customer in the banking system
 type Customer struct {
 	ID        int64  `json:"id"`
@@ -9,6 +17,27 @@ type Customer struct {
 	Phone     string `json:"phone"`
 }
 
+// CustomerPage selects Limit customers, ordered by id, after skipping the first Offset
+type CustomerPage struct {
+	Limit  int
+	Offset int
+}
+
+// CustomerWithAccounts is a customer loaded in one query together with their open accounts
+type CustomerWithAccounts struct {
+	Customer Customer
+	Accounts []Account
+}
+
+// ToCustomerDTO converts the customer and their accounts to CustomerDTO
+func (c CustomerWithAccounts) ToCustomerDTO() CustomerDTO {
+	accountDtos := make([]AccountDTO, 0, len(c.Accounts))
+	for _, account := range c.Accounts {
+		accountDtos = append(accountDtos, account.ToAccountDTO())
+	}
+	return c.Customer.ToCustomerDTO(accountDtos...)
+}
+
 // CustomerDTO represents a customer with their accounts
 type CustomerDTO struct {
 	FirstName string       `json:"first_name"`
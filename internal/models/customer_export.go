@@ -0,0 +1,65 @@
+package models
+
+// Columns of customer exports, account columns are only available when accounts are exported
+var (
+	CustomerExportColumns = []string{"first_name", "last_name", "email", "phone"}
+	AccountExportColumns  = []string{"account_number", "balance", "account_description", "account_created_at", "account_updated_at"}
+)
+
+// CustomerExportFilter selects the rows of a customer export
+type CustomerExportFilter struct {
+	// Email restricts the export to one customer, for callers limited to their own records
+	Email string
+	// WithAccounts exports a row per open account, customers without accounts get a row without account columns
+	WithAccounts bool
+	// Page bounds the customers rather than the rows, every customer is exported when Limit and Offset are zero
+	Page CustomerPage
+}
+
+// CustomerExportRequest is a customer export as asked for by the caller
+type CustomerExportRequest struct {
+	// Expand is empty or accounts, like for listing customers
+	Expand string
+	// Columns selects and orders the exported columns, every available column when empty
+	Columns []string
+	// Page selects customers like limit and offset when listing them, without a default or maximum limit
+	Page CustomerPage
+}
+
+// CustomerExportRow is one exported customer, together with one of their accounts when accounts are exported
+type CustomerExportRow struct {
+	Customer Customer
+	// Account is nil for customers without accounts and when accounts are not exported
+	Account *Account
+}
+
+// Value returns the value of an export column: a string, a float64, a time.Time or nil when the row has no account
+func (r CustomerExportRow) Value(column string) any {
+	switch column {
+	case "first_name":
+		return r.Customer.FirstName
+	case "last_name":
+		return r.Customer.LastName
+	case "email":
+		return r.Customer.Email
+	case "phone":
+		return r.Customer.Phone
+	}
+
+	if r.Account == nil {
+		return nil
+	}
+	switch column {
+	case "account_number":
+		return r.Account.AccountNumber
+	case "balance":
+		return r.Account.Balance
+	case "account_description":
+		return r.Account.AccountDescription
+	case "account_created_at":
+		return r.Account.CreatedAt
+	case "account_updated_at":
+		return r.Account.UpdatedAt
+	}
+	return nil
+}
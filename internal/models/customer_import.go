@@ -0,0 +1,27 @@
+package models
+
+// Outcomes of an imported row
+const (
+	ImportStatusCreated = "created"
+	ImportStatusSkipped = "skipped"
+	ImportStatusFailed  = "failed"
+)
+
+// CustomerImportReport describes what an import did with every row it read. In a dry run created means the row
+// would have been created.
+type CustomerImportReport struct {
+	DryRun  bool                   `json:"dry_run"`
+	Total   int                    `json:"total"`
+	Created int                    `json:"created"`
+	Skipped int                    `json:"skipped"`
+	Failed  int                    `json:"failed"`
+	Rows    []CustomerImportRowDTO `json:"rows"`
+}
+
+// CustomerImportRowDTO is the outcome of one row, identified by its line in the uploaded file
+type CustomerImportRowDTO struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
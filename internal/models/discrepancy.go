@@ -0,0 +1,9 @@
+package models
+
+// Discrepancy describes a data inconsistency found while reconciling customers and accounts
+type Discrepancy struct {
+	Check      string `json:"check"`
+	EntityType string `json:"entity_type"`
+	EntityKey  string `json:"entity_key"`
+	Detail     string `json:"detail"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Domain event types written to the outbox
+const (
+	EventCustomerCreated = "CustomerCreated"
+	EventCustomerDeleted = "CustomerDeleted"
+	EventAccountOpened   = "AccountOpened"
+	EventAccountClosed   = "AccountClosed"
+	// EventFundsTransferred is part of the published contract, no operation of the API posts transfers yet
+	EventFundsTransferred = "FundsTransferred"
+)
+
+// EventTypes lists every domain event type
+var EventTypes = []string{EventCustomerCreated, EventCustomerDeleted, EventAccountOpened, EventAccountClosed, EventFundsTransferred}
+
+// Aggregates events are about
+const (
+	AggregateCustomer = "customer"
+	AggregateAccount  = "account"
+)
+
+// Event is a domain event as handed to publishers. Delivery is at least once, consumers deduplicate on ID.
+type Event struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	AggregateType string `json:"aggregate_type"`
+	// AggregateID is the customer email or the account number
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	RequestID   string          `json:"request_id,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	// CustomerEmail is the customer the event is about, webhook subscriptions owned by another customer do not
+	// receive it. It is empty for events about no single customer and is not published.
+	CustomerEmail string `json:"-"`
+}
+
+// OutboxEvent is an event claimed from the outbox for publishing
+type OutboxEvent struct {
+	OutboxID int64
+	// Attempts counts the deliveries tried so far, including the current one
+	Attempts int
+	Event    Event
+}
+
+// CustomerCreatedPayload is the payload of CustomerCreated, accounts opened with the customer get their own AccountOpened
+type CustomerCreatedPayload struct {
+	CustomerID int64       `json:"customer_id"`
+	Customer   CustomerDTO `json:"customer"`
+}
+
+// CustomerDeletedPayload is the payload of CustomerDeleted, the accounts listed were deleted with the customer
+type CustomerDeletedPayload struct {
+	CustomerID     int64    `json:"customer_id"`
+	Email          string   `json:"email"`
+	AccountNumbers []string `json:"account_numbers"`
+}
+
+// AccountPayload is the payload of AccountOpened and AccountClosed
+type AccountPayload struct {
+	CustomerID int64      `json:"customer_id"`
+	Account    AccountDTO `json:"account"`
+}
+
+// FundsTransferredPayload is the payload of FundsTransferred
+type FundsTransferredPayload struct {
+	FromAccountNumber string    `json:"from_account_number"`
+	ToAccountNumber   string    `json:"to_account_number"`
+	Amount            float64   `json:"amount"`
+	Reference         string    `json:"reference,omitempty"`
+	PostedAt          time.Time `json:"posted_at"`
+}
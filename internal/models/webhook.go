@@ -0,0 +1,139 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// WebhookAllEvents subscribes to every event type
+const WebhookAllEvents = "*"
+
+// Webhook delivery states. Failed attempts keep a delivery pending until it runs out of attempts.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookSubscription is a partner endpoint receiving the domain events of the listed types
+type WebhookSubscription struct {
+	ID         int64
+	URL        string
+	EventTypes []string
+	// Secret signs every delivery, it is only shown when the subscription is created
+	Secret      string
+	Description string
+	OwnerEmail  sql.NullString
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+type WebhookSubscriptionDTO struct {
+	ID          int64     `json:"id"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description,omitempty"`
+	OwnerEmail  string    `json:"owner_email,omitempty"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateWebhookSubscriptionRequest registers an https URL for event types, or * for all of them
+type CreateWebhookSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1"`
+	Description string   `json:"description" binding:"max=255"`
+	OwnerEmail  string   `json:"owner_email" binding:"omitempty,email"`
+}
+
+// CreatedWebhookSubscriptionDTO is returned once when a subscription is created, with the secret verifying signatures
+type CreatedWebhookSubscriptionDTO struct {
+	WebhookSubscriptionDTO
+	Secret string `json:"secret"`
+}
+
+// ToWebhookSubscriptionDTO Convert from DB model to response model, leaving out the secret
+func (s WebhookSubscription) ToWebhookSubscriptionDTO() WebhookSubscriptionDTO {
+	return WebhookSubscriptionDTO{
+		ID:          s.ID,
+		URL:         s.URL,
+		EventTypes:  s.EventTypes,
+		Description: s.Description,
+		OwnerEmail:  s.OwnerEmail.String,
+		CreatedBy:   s.CreatedBy,
+		CreatedAt:   s.CreatedAt,
+	}
+}
+
+// WebhookDelivery is one event queued for one subscription
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        string
+	EventType      string
+	// Payload is the event as posted, the request body
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode sql.NullInt64
+	LastError      sql.NullString
+	CreatedAt      time.Time
+	DeliveredAt    sql.NullTime
+}
+
+// WebhookDispatch is a claimed delivery together with where to post it and how to sign it
+type WebhookDispatch struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+type WebhookDeliveryDTO struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int64     `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveryFilter selects the deliveries of a subscription, newest first
+type WebhookDeliveryFilter struct {
+	SubscriptionID int64
+	// Status is empty for every state
+	Status string
+	// BeforeID continues a listing after the last delivery of the previous page
+	BeforeID int64
+	Limit    int
+}
+
+// ToWebhookDeliveryDTO Convert from DB model to response model
+func (d WebhookDelivery) ToWebhookDeliveryDTO() WebhookDeliveryDTO {
+	dto := WebhookDeliveryDTO{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastError:      d.LastError.String,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.Status == WebhookDeliveryPending {
+		dto.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.LastStatusCode.Valid {
+		dto.LastStatusCode = &d.LastStatusCode.Int64
+	}
+	if d.DeliveredAt.Valid {
+		dto.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return dto
+}
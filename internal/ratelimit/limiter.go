@@ -0,0 +1,197 @@
+package ratelimit
+
+import (
+	"context"
+	"maps"
+	"math"
+	"org/gg/banking/internal/config"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Keys counting requests against a bucket
+const (
+	KeyIP        = "ip"
+	KeyAPIKey    = "api_key"
+	KeyPrincipal = "principal"
+)
+
+// DefaultRule names the bucket of routes without their own rule
+const DefaultRule = "default"
+
+// PreAuthRule names the bucket counting requests per ip before authentication
+const PreAuthRule = "pre_auth"
+
+// sweepInterval is how often idle buckets are removed from the store
+const sweepInterval = time.Minute
+
+// IStore keeps the token buckets
+type IStore interface {
+	// Take refills the bucket of key, which starts full, and removes a token if one is available.
+	// It returns the tokens left and whether the request may proceed.
+	Take(ctx context.Context, key string, capacity, ratePerSecond float64) (tokens float64, allowed bool, err error)
+	// Sweep removes buckets untouched for longer than idle, they have refilled and are equivalent to missing buckets
+	Sweep(ctx context.Context, idle time.Duration) error
+}
+
+// Rule is a compiled rate limit rule
+type Rule struct {
+	// Name is the route of the rule, DefaultRule or PreAuthRule
+	Name     string
+	Requests int
+	Period   time.Duration
+	Burst    int
+	Key      string
+}
+
+// capacity is the size of the bucket
+func (r Rule) capacity() float64 {
+	if r.Burst > 0 {
+		return float64(r.Burst)
+	}
+	return float64(r.Requests)
+}
+
+// rate is the number of tokens added per second
+func (r Rule) rate() float64 {
+	return float64(r.Requests) / r.Period.Seconds()
+}
+
+// refillTime is how long an empty bucket takes to refill completely
+func (r Rule) refillTime() time.Duration {
+	return time.Duration(r.capacity() / r.rate() * float64(time.Second))
+}
+
+// Decision is the outcome of a request against its bucket
+type Decision struct {
+	Allowed bool
+	// Limit is the size of the bucket and Remaining the requests left in it
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, zero when allowed
+	RetryAfter time.Duration
+}
+
+type rules struct {
+	byRoute  map[string]Rule
+	fallback Rule
+	preAuth  Rule
+	// idle is the longest refill time, buckets untouched for longer are full
+	idle time.Duration
+}
+
+// Limiter applies the configured rules to a store. Its rules can be replaced at runtime with Update.
+type Limiter struct {
+	store     IStore
+	rules     atomic.Pointer[rules]
+	lastSweep atomic.Int64
+}
+
+// NewLimiter compiles the rate limit configuration, the configuration must have been validated
+func NewLimiter(rateLimitConfig config.RateLimitConfiguration, store IStore) *Limiter {
+	limiter := &Limiter{store: store}
+	limiter.Update(rateLimitConfig)
+	limiter.lastSweep.Store(time.Now().UnixNano())
+	return limiter
+}
+
+// Update atomically replaces the rules. Buckets are kept, a changed rule applies to them from the next request.
+func (l *Limiter) Update(rateLimitConfig config.RateLimitConfiguration) {
+	defaultKey := strings.ToLower(rateLimitConfig.Default.Key)
+	if defaultKey == "" {
+		defaultKey = KeyIP
+	}
+
+	compiled := &rules{byRoute: map[string]Rule{}}
+	compiled.fallback = compileRule(DefaultRule, rateLimitConfig.Default, defaultKey)
+	compiled.preAuth = compileRule(PreAuthRule, rateLimitConfig.PreAuth, KeyIP)
+	compiled.preAuth.Key = KeyIP
+	for _, routeRule := range rateLimitConfig.Routes {
+		compiled.byRoute[routeRule.Route] = compileRule(routeRule.Route, routeRule, defaultKey)
+	}
+
+	for _, rule := range append(slices.Collect(maps.Values(compiled.byRoute)), compiled.fallback, compiled.preAuth) {
+		if rule.Requests > 0 && rule.refillTime() > compiled.idle {
+			compiled.idle = rule.refillTime()
+		}
+	}
+
+	l.rules.Store(compiled)
+}
+
+// Rule returns the rule of a route, such as "GET /api/v1/customers/". It reports false when the route is unlimited.
+func (l *Limiter) Rule(route string) (Rule, bool) {
+	current := l.rules.Load()
+	rule, found := current.byRoute[route]
+	if !found {
+		rule = current.fallback
+	}
+	return rule, rule.Requests > 0
+}
+
+// PreAuth returns the rule counting requests per ip before authentication. It reports false when it is disabled.
+func (l *Limiter) PreAuth() (Rule, bool) {
+	rule := l.rules.Load().preAuth
+	return rule, rule.Requests > 0
+}
+
+// Allow takes a token from the bucket of subject under rule
+func (l *Limiter) Allow(ctx context.Context, rule Rule, subject string) (Decision, error) {
+	l.sweep()
+
+	capacity, rate := rule.capacity(), rule.rate()
+	tokens, allowed, err := l.store.Take(ctx, rule.Name+"|"+subject, capacity, rate)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     int(capacity),
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     secondsToDuration((capacity - tokens) / rate),
+	}
+	if !allowed {
+		decision.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	return decision, nil
+}
+
+// sweep removes idle buckets in the background, at most once per sweepInterval
+func (l *Limiter) sweep() {
+	last := l.lastSweep.Load()
+	now := time.Now().UnixNano()
+	if now-last < int64(sweepInterval) || !l.lastSweep.CompareAndSwap(last, now) {
+		return
+	}
+
+	idle := l.rules.Load().idle
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), sweepInterval)
+		defer cancel()
+		// Failures are logged by the store, the next sweep retries
+		_ = l.store.Sweep(ctx, idle)
+	}()
+}
+
+func compileRule(name string, configured config.RateLimitRule, defaultKey string) Rule {
+	key := strings.ToLower(configured.Key)
+	if key == "" {
+		key = defaultKey
+	}
+	return Rule{
+		Name:     name,
+		Requests: configured.Requests,
+		Period:   configured.Period,
+		Burst:    configured.Burst,
+		Key:      key,
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Max(0, seconds) * float64(time.Second))
+}
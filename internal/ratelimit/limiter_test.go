@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"org/gg/banking/internal/config"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	tests := []struct {
+		name       string
+		capacity   float64
+		rate       float64
+		elapsed    time.Duration
+		takes      int
+		wantLast   bool
+		wantTokens float64
+	}{
+		{name: "starts full", capacity: 3, rate: 0.001, takes: 1, wantLast: true, wantTokens: 2},
+		{name: "drains to empty", capacity: 3, rate: 0.001, takes: 3, wantLast: true, wantTokens: 0},
+		{name: "rejects when empty", capacity: 3, rate: 0.001, takes: 4, wantLast: false, wantTokens: 0},
+		{name: "refills over time", capacity: 3, rate: 1, elapsed: 2 * time.Second, takes: 4, wantLast: true, wantTokens: 1},
+		{name: "refill is capped", capacity: 3, rate: 1, elapsed: time.Hour, takes: 4, wantLast: true, wantTokens: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore().(*memoryStore)
+			ctx := context.Background()
+
+			var tokens float64
+			var allowed bool
+			var err error
+			for i := range tt.takes {
+				if i == 3 && tt.elapsed > 0 {
+					// The first three requests emptied the bucket, move them into the past
+					store.buckets["key"].updated = store.buckets["key"].updated.Add(-tt.elapsed)
+				}
+				if tokens, allowed, err = store.Take(ctx, "key", tt.capacity, tt.rate); err != nil {
+					t.Fatalf("Take: %v", err)
+				}
+			}
+
+			if allowed != tt.wantLast {
+				t.Errorf("last Take allowed = %v, want %v", allowed, tt.wantLast)
+			}
+			if tokens < tt.wantTokens || tokens > tt.wantTokens+0.01 {
+				t.Errorf("tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	if _, allowed, _ := store.Take(ctx, "a", 1, 0.001); !allowed {
+		t.Fatal("first request of a rejected")
+	}
+	if _, allowed, _ := store.Take(ctx, "a", 1, 0.001); allowed {
+		t.Error("second request of a allowed")
+	}
+	if _, allowed, _ := store.Take(ctx, "b", 1, 0.001); !allowed {
+		t.Error("first request of b rejected")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore().(*memoryStore)
+	ctx := context.Background()
+	for _, key := range []string{"idle", "active"} {
+		if _, _, err := store.Take(ctx, key, 1, 1); err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+	}
+	store.buckets["idle"].updated = time.Now().Add(-time.Hour)
+
+	if err := store.Sweep(ctx, time.Minute); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if _, found := store.buckets["idle"]; found {
+		t.Error("idle bucket kept")
+	}
+	if _, found := store.buckets["active"]; !found {
+		t.Error("active bucket removed")
+	}
+}
+
+func TestLimiterRule(t *testing.T) {
+	limiter := NewLimiter(config.RateLimitConfiguration{
+		Default: config.RateLimitRule{Requests: 300, Period: time.Minute, Burst: 60, Key: "Principal"},
+		Routes: []config.RateLimitRule{
+			{Route: "GET /health", Requests: 0},
+			{Route: "GET /api/v1/customers/", Requests: 60, Period: time.Minute, Key: "ip"},
+			{Route: "POST /api/v1/admin/api-keys", Requests: 10, Period: time.Hour},
+		},
+		PreAuth: config.RateLimitRule{Requests: 600, Period: time.Minute, Key: "principal"},
+	}, NewMemoryStore())
+
+	tests := []struct {
+		route       string
+		wantName    string
+		wantKey     string
+		wantLimited bool
+	}{
+		{route: "GET /health", wantName: "GET /health", wantLimited: false},
+		{route: "GET /api/v1/customers/", wantName: "GET /api/v1/customers/", wantKey: KeyIP, wantLimited: true},
+		{route: "POST /api/v1/admin/api-keys", wantName: "POST /api/v1/admin/api-keys", wantKey: KeyPrincipal, wantLimited: true},
+		{route: "DELETE /api/v1/customers/:email", wantName: DefaultRule, wantKey: KeyPrincipal, wantLimited: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			rule, limited := limiter.Rule(tt.route)
+			if limited != tt.wantLimited || rule.Name != tt.wantName {
+				t.Fatalf("Rule(%q) = %s, %v, want %s, %v", tt.route, rule.Name, limited, tt.wantName, tt.wantLimited)
+			}
+			if limited && rule.Key != tt.wantKey {
+				t.Errorf("Rule(%q).Key = %s, want %s", tt.route, rule.Key, tt.wantKey)
+			}
+		})
+	}
+
+	rule, limited := limiter.PreAuth()
+	if !limited || rule.Name != PreAuthRule || rule.Key != KeyIP {
+		t.Errorf("PreAuth() = %+v, %v, want an ip keyed %s rule", rule, limited, PreAuthRule)
+	}
+
+	limiter.Update(config.RateLimitConfiguration{})
+	if _, limited := limiter.PreAuth(); limited {
+		t.Error("PreAuth() limited after it was disabled")
+	}
+	if _, limited := limiter.Rule("GET /api/v1/customers/"); limited {
+		t.Error("Rule() kept a removed route")
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	limiter := NewLimiter(config.RateLimitConfiguration{
+		Default: config.RateLimitRule{Requests: 60, Period: time.Minute, Burst: 2},
+	}, NewMemoryStore())
+	rule, _ := limiter.Rule("GET /")
+	ctx := context.Background()
+
+	tests := []struct {
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{wantAllowed: true, wantRemaining: 1},
+		{wantAllowed: true, wantRemaining: 0},
+		{wantAllowed: false, wantRemaining: 0},
+	}
+	for i, tt := range tests {
+		decision, err := limiter.Allow(ctx, rule, "ip:192.0.2.1")
+		if err != nil {
+			t.Fatalf("Allow: %v", err)
+		}
+		if decision.Allowed != tt.wantAllowed || decision.Remaining != tt.wantRemaining || decision.Limit != 2 {
+			t.Errorf("request %d: %+v, want allowed %v with %d of 2 remaining", i+1, decision, tt.wantAllowed, tt.wantRemaining)
+		}
+		if decision.Allowed != (decision.RetryAfter == 0) {
+			t.Errorf("request %d: RetryAfter = %s with allowed %v", i+1, decision.RetryAfter, decision.Allowed)
+		}
+		// One request per second refills the two tokens in about two seconds
+		if decision.Reset > 2*time.Second || decision.RetryAfter > time.Second {
+			t.Errorf("request %d: Reset = %s, RetryAfter = %s", i+1, decision.Reset, decision.RetryAfter)
+		}
+	}
+
+	if decision, _ := limiter.Allow(ctx, rule, "ip:192.0.2.2"); !decision.Allowed {
+		t.Error("another subject shares the bucket")
+	}
+}
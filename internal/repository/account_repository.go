@@ -1,50 +1,58 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"org/gg/banking/internal/models"
 	"time"
 )
 
 type IAccountRepository interface {
-	FindByCustomerID(customerID int64) ([]models.Account, error)
-	CreateAccount(customerID int64, account models.Account) (models.Account, error)
-	DeleteByCustomerID(customerID int64) error // Add this method
+	FindByCustomerID(ctx context.Context, customerID int64) ([]models.Account, error)
+	FindByAccountNumber(ctx context.Context, accountNumber string) (models.Account, error)
+	CreateAccount(ctx context.Context, customerID int64, account models.Account) (models.Account, error)
+	DeleteByCustomerID(ctx context.Context, customerID int64) error // Add this method
+	CloseAccount(ctx context.Context, accountNumber string) error
+	// FindMovements returns the balance of an account before from and its movements from from until before to
+	FindMovements(ctx context.Context, accountID int64, from, to time.Time) (opening float64, movements []models.AccountMovement, err error)
 }
 
 type accountRepository struct {
 	db         *sql.DB
+	logger     *slog.Logger
+	statements *statementCache
 	collection string
 }
 
-func NewAccountRepository(db *sql.DB) IAccountRepository {
+// NewAccountRepository creates a repository whose statements are prepared once and reused
+func NewAccountRepository(db *sql.DB, logger *slog.Logger) IAccountRepository {
 	return &accountRepository{
 		db:         db,
+		logger:     logger,
+		statements: newStatementCache(db, logger),
 		collection: "account_collection",
 	}
 }
 
 // FindByCustomerID retrieves all non-deleted accounts for a customer
-func (repository *accountRepository) FindByCustomerID(customerID int64) ([]models.Account, error) {
+func (repository *accountRepository) FindByCustomerID(ctx context.Context, customerID int64) (accounts []models.Account, err error) {
 	query := `
 		SELECT id, customer_id, account_number, balance, account_description, created_at, updated_at, deleted_at
 		FROM accounts
 		WHERE customer_id = $1 AND deleted_at IS NULL
 	`
+	ctx, tracker := startQuery(ctx, repository.logger, "accounts.FindByCustomerID", query)
+	defer tracker.finish(&err)
 
-	rows, err := repository.db.Query(query, customerID)
+	rows, err := repository.statements.QueryContext(ctx, query, customerID)
 	if err != nil {
 		return nil, fmt.Errorf("error querying customer accounts: %v", err)
 	}
-	defer func(rows *sql.Rows) {
-		if err := rows.Close(); err != nil {
-			log.Printf("error closing rows: %v", err)
-		}
-	}(rows)
+	defer closeRows(ctx, repository.logger, rows)
 
-	var accounts []models.Account
 	for rows.Next() {
 		var account models.Account
 		if err := rows.Scan(
@@ -65,29 +73,59 @@ func (repository *accountRepository) FindByCustomerID(customerID int64) ([]model
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating accounts: %v", err)
 	}
+	tracker.setRows(int64(len(accounts)))
 
 	return accounts, nil
 }
 
-func (repository *accountRepository) CreateAccount(customerID int64, account models.Account) (models.Account, error) {
+// FindByAccountNumber retrieves an account by its number, closed accounts included
+func (repository *accountRepository) FindByAccountNumber(ctx context.Context, accountNumber string) (account models.Account, err error) {
 	query := `
-		INSERT INTO accounts (customer_id, account_number, balance, account_description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-        RETURNING id,account_number, balance, account_description, created_at, updated_at
+		SELECT id, customer_id, account_number, balance, account_description, created_at, updated_at, deleted_at
+		FROM accounts
+		WHERE account_number = $1
 	`
+	ctx, tracker := startQuery(ctx, repository.logger, "accounts.FindByAccountNumber", query)
+	defer tracker.finish(&err)
 
-	stmt, err := repository.db.Prepare(query)
+	err = repository.statements.QueryRowContext(ctx, query, accountNumber).Scan(
+		&account.ID,
+		&account.CustomerID,
+		&account.AccountNumber,
+		&account.Balance,
+		&account.AccountDescription,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+		&account.DeletedAt,
+	)
 	if err != nil {
-		return models.Account{}, fmt.Errorf("error preparing statement: %v", err)
-	}
-	defer func(stmt *sql.Stmt) {
-		if err := stmt.Close(); err != nil {
-			log.Printf("error closing statement: %v", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Account{}, fmt.Errorf("account %s %w", accountNumber, ErrNotFound)
 		}
-	}(stmt)
+		return models.Account{}, fmt.Errorf("error querying account: %v", err)
+	}
+	tracker.setRows(1)
+
+	return account, nil
+}
+
+const createAccountQuery = `
+	WITH account AS (
+		INSERT INTO accounts (customer_id, account_number, balance, account_description, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, account_number, balance, account_description, created_at, updated_at
+	), opening AS (
+		INSERT INTO account_movements (account_id, amount, description, occurred_at)
+		SELECT id, balance, 'Opening deposit', CURRENT_TIMESTAMP FROM account WHERE balance <> 0
+	)
+	SELECT id, account_number, balance, account_description, created_at, updated_at FROM account`
 
-	var createdAccount models.Account
-	err2 := stmt.QueryRow(customerID, account.AccountNumber, account.Balance, account.AccountDescription, time.Now(), time.Now()).Scan(
+// CreateAccount inserts an account, its initial balance is recorded as its first movement by the same statement
+func (repository *accountRepository) CreateAccount(ctx context.Context, customerID int64, account models.Account) (createdAccount models.Account, err error) {
+	ctx, tracker := startQuery(ctx, repository.logger, "accounts.CreateAccount", createAccountQuery)
+	defer tracker.finish(&err)
+
+	err = repository.statements.QueryRowContext(ctx, createAccountQuery, customerID, account.AccountNumber, account.Balance, account.AccountDescription, time.Now(), time.Now()).Scan(
 		&createdAccount.ID,
 		&createdAccount.AccountNumber,
 		&createdAccount.Balance,
@@ -95,15 +133,104 @@ func (repository *accountRepository) CreateAccount(customerID int64, account mod
 		&createdAccount.CreatedAt,
 		&createdAccount.UpdatedAt)
 
-	if err2 != nil {
-		return models.Account{}, fmt.Errorf("error executing statement: %v", err2)
+	if err != nil {
+		return models.Account{}, fmt.Errorf("error executing statement: %v", err)
 	}
+	tracker.setRows(1)
 
 	return createdAccount, nil
 }
 
-func (r *accountRepository) DeleteByCustomerID(customerID int64) error {
+func (r *accountRepository) DeleteByCustomerID(ctx context.Context, customerID int64) (err error) {
+	query := "DELETE FROM accounts WHERE customer_id = $1"
+	ctx, tracker := startQuery(ctx, r.logger, "accounts.DeleteByCustomerID", query)
+	defer tracker.finish(&err)
+
 	// Execute SQL to delete all accounts for a customer
-	_, err := r.db.Exec("DELETE FROM accounts WHERE customer_id = $1", customerID)
-	return err
+	result, err := r.statements.ExecContext(ctx, query, customerID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		tracker.setRows(affected)
+	}
+
+	return nil
+}
+
+// CloseAccount soft-deletes an open account by its account number
+func (repository *accountRepository) CloseAccount(ctx context.Context, accountNumber string) (err error) {
+	query := `
+		UPDATE accounts
+		SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE account_number = $1 AND deleted_at IS NULL
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "accounts.CloseAccount", query)
+	defer tracker.finish(&err)
+
+	result, err := repository.statements.ExecContext(ctx, query, accountNumber)
+	if err != nil {
+		return fmt.Errorf("error closing account: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error closing account: %v", err)
+	}
+	tracker.setRows(affected)
+	if affected == 0 {
+		return fmt.Errorf("open account %s %w", accountNumber, ErrNotFound)
+	}
+
+	return nil
+}
+
+// FindMovements reads the opening balance and the movements in one statement, so both come from the same snapshot
+func (repository *accountRepository) FindMovements(ctx context.Context, accountID int64, from, to time.Time) (opening float64, movements []models.AccountMovement, err error) {
+	query := `
+		WITH opening AS (
+			SELECT COALESCE(SUM(amount), 0) AS balance
+			FROM account_movements
+			WHERE account_id = $1 AND occurred_at < $2
+		)
+		SELECT o.balance, m.id, m.amount, m.description, m.occurred_at
+		FROM opening o
+		LEFT JOIN account_movements m ON m.account_id = $1 AND m.occurred_at >= $2 AND m.occurred_at < $3
+		ORDER BY m.occurred_at, m.id
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "account_movements.FindMovements", query)
+	defer tracker.finish(&err)
+
+	rows, err := repository.statements.QueryContext(ctx, query, accountID, from, to)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error querying account movements: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		var id sql.NullInt64
+		var amount sql.NullFloat64
+		var description sql.NullString
+		var occurredAt sql.NullTime
+		if err := rows.Scan(&opening, &id, &amount, &description, &occurredAt); err != nil {
+			return 0, nil, fmt.Errorf("error scanning account movement row: %v", err)
+		}
+		// Without movements in the period the opening balance comes alone
+		if id.Valid {
+			movements = append(movements, models.AccountMovement{
+				ID:          id.Int64,
+				AccountID:   accountID,
+				Amount:      amount.Float64,
+				Description: description.String,
+				OccurredAt:  occurredAt.Time,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("error iterating account movements: %v", err)
+	}
+	tracker.setRows(int64(len(movements)))
+
+	return opening, movements, nil
 }
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/models"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BenchmarkCreateAccount compares preparing and closing the annotated insert on every call against the statement
+// the repository prepares once, with concurrent callers sharing the pool. Calls are made for a request, as they are
+// when the API serves them. Errors are reported with Error, Fatal must not be called from the parallel goroutines.
+func BenchmarkCreateAccount(b *testing.B) {
+	db := openTestDB(b)
+	customer := seedCustomers(b, db, 1, 0)[0]
+	ctx := logger.WithRequest(context.Background(), "benchmark-request", "/api/v1/customers/", "127.0.0.1", time.Now())
+
+	var sequence atomic.Int64
+	nextAccountNumber := func() string {
+		return fmt.Sprintf("TEST-%d-%d", customer.ID, sequence.Add(1))
+	}
+
+	b.Run("prepare per call", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				stmt, err := db.PrepareContext(ctx, annotate(ctx, createAccountQuery))
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				var created models.Account
+				err = stmt.QueryRowContext(ctx, customer.ID, nextAccountNumber(), 100, "Benchmark account", time.Now(), time.Now()).Scan(
+					&created.ID,
+					&created.AccountNumber,
+					&created.Balance,
+					&created.AccountDescription,
+					&created.CreatedAt,
+					&created.UpdatedAt)
+				stmt.Close()
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+	b.Run("cached statement", func(b *testing.B) {
+		accountRepository := NewAccountRepository(db, testLogger())
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := accountRepository.CreateAccount(ctx, customer.ID, models.Account{
+					AccountNumber:      nextAccountNumber(),
+					Balance:            100,
+					AccountDescription: "Benchmark account",
+				}); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
+
+func TestFindMovementsBounds(t *testing.T) {
+	db := openTestDB(t)
+	ctx := testTx(t, db)
+	customerRepository := NewCustomerRepository(db, testLogger())
+	accountRepository := NewAccountRepository(db, testLogger())
+
+	run := time.Now().UnixNano()
+	customer, err := customerRepository.Create(ctx, models.Customer{FirstName: "Test", LastName: "Statement", Email: fmt.Sprintf("statement-%d@example.com", run)})
+	if err != nil {
+		t.Fatalf("creating customer: %v", err)
+	}
+	account, err := accountRepository.CreateAccount(ctx, customer.ID, models.Account{AccountNumber: fmt.Sprintf("STMT-%d", run), AccountDescription: "Test account"})
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	from := time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	movements := []struct {
+		amount     float64
+		occurredAt time.Time
+	}{
+		{amount: 100, occurredAt: from.Add(-time.Microsecond)},
+		{amount: 0.5, occurredAt: from},
+		{amount: -20, occurredAt: to.Add(-time.Microsecond)},
+		{amount: 1000, occurredAt: to},
+	}
+	for _, movement := range movements {
+		if _, err := conn(ctx, db).ExecContext(ctx, `INSERT INTO account_movements (account_id, amount, description, occurred_at) VALUES ($1, $2, 'Test', $3)`,
+			account.ID, movement.amount, movement.occurredAt); err != nil {
+			t.Fatalf("inserting movement: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		wantOpening float64
+		wantAmounts []float64
+	}{
+		{name: "from included, to excluded", from: from, to: to, wantOpening: 100, wantAmounts: []float64{0.5, -20}},
+		{name: "before every movement", from: from.AddDate(0, -1, 0), to: from.Add(-time.Microsecond), wantOpening: 0},
+		{name: "after every movement", from: to.Add(time.Microsecond), to: to.AddDate(0, 1, 0), wantOpening: 1080.5},
+		{name: "single instant", from: to, to: to.Add(time.Microsecond), wantOpening: 80.5, wantAmounts: []float64{1000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opening, found, err := accountRepository.FindMovements(ctx, account.ID, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("FindMovements() error = %v", err)
+			}
+			amounts := make([]float64, 0, len(found))
+			for _, movement := range found {
+				amounts = append(amounts, movement.Amount)
+			}
+			if opening != tt.wantOpening || !slices.Equal(amounts, tt.wantAmounts) {
+				t.Errorf("FindMovements() = %v, %v, want %v, %v", opening, amounts, tt.wantOpening, tt.wantAmounts)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type IAPIKeyRepository interface {
+	FindAll(ctx context.Context) ([]models.APIKey, error)
+	FindByID(ctx context.Context, id int64) (models.APIKey, error)
+	FindByPrefix(ctx context.Context, prefix string) (models.APIKey, error)
+	Create(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	// Rotate stores the replacement of a key and makes the old key expire at oldExpiresAt, atomically
+	Rotate(ctx context.Context, oldID int64, replacement models.APIKey, oldExpiresAt time.Time) (models.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	// TouchLastUsed records a use of the key, at most once per minute to spare writes on busy keys
+	TouchLastUsed(ctx context.Context, id int64) error
+}
+
+type apiKeyRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+	// statements holds the lookups made on every request authenticated with a key
+	statements *statementCache
+}
+
+func NewAPIKeyRepository(db *sql.DB, logger *slog.Logger) IAPIKeyRepository {
+	return &apiKeyRepository{
+		db:         db,
+		logger:     logger,
+		statements: newStatementCache(db, logger),
+	}
+}
+
+const apiKeyColumns = `id, prefix, secret_hash, name, owner_email, scopes, created_at, expires_at, last_used_at, revoked_at, rotated_from`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (key models.APIKey, err error) {
+	err = row.Scan(
+		&key.ID,
+		&key.Prefix,
+		&key.SecretHash,
+		&key.Name,
+		&key.OwnerEmail,
+		pq.Array(&key.Scopes),
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.RotatedFrom,
+	)
+	return key, err
+}
+
+// FindAll lists every key, newest first
+func (repository *apiKeyRepository) FindAll(ctx context.Context) (keys []models.APIKey, err error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id DESC`
+	ctx, tracker := startQuery(ctx, repository.logger, "api_keys.FindAll", query)
+	defer tracker.finish(&err)
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, annotate(ctx, query))
+	if err != nil {
+		return nil, fmt.Errorf("error querying api keys: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning api key row: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %v", err)
+	}
+	tracker.setRows(int64(len(keys)))
+
+	return keys, nil
+}
+
+// FindByID retrieves a key by id
+func (repository *apiKeyRepository) FindByID(ctx context.Context, id int64) (key models.APIKey, err error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+	ctx, tracker := startQuery(ctx, repository.logger, "api_keys.FindByID", query)
+	defer tracker.finish(&err)
+
+	key, err = scanAPIKey(conn(ctx, repository.db).QueryRowContext(ctx, annotate(ctx, query), id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, fmt.Errorf("api key %d %w", id, ErrNotFound)
+		}
+		return models.APIKey{}, fmt.Errorf("error querying api key: %v", err)
+	}
+	tracker.setRows(1)
+
+	return key, nil
+}
+
+// FindByPrefix retrieves a key by the public prefix embedded in the presented key
+func (repository *apiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (key models.APIKey, err error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+	ctx, tracker := startQuery(ctx, repository.logger, "api_keys.FindByPrefix", query)
+	defer tracker.finish(&err)
+
+	key, err = scanAPIKey(repository.statements.QueryRowContext(ctx, query, prefix))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, fmt.Errorf("api key with prefix %s %w", prefix, ErrNotFound)
+		}
+		return models.APIKey{}, fmt.Errorf("error querying api key: %v", err)
+	}
+	tracker.setRows(1)
+
+	return key, nil
+}
+
+// Create inserts a new key and returns it as stored
+func (repository *apiKeyRepository) Create(ctx context.Context, key models.APIKey) (created models.APIKey, err error) {
+	ctx, tracker := startQuery(ctx, repository.logger, "api_keys.Create", insertAPIKeyQuery)
+	defer tracker.finish(&err)
+
+	created, err = insertAPIKey(ctx, conn(ctx, repository.db), key)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	tracker.setRows(1)
+
+	return created, nil
+}
+
+// Rotate inserts the replacement and shortens the lifetime of the old key in one transaction
+func (repository *apiKeyRepository) Rotate(ctx context.Context, oldID int64, replacement models.APIKey, oldExpiresAt time.Time) (created models.APIKey, err error) {
+	expireQuery := `
+		UPDATE api_keys
+		SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "api_keys.Rotate", expireQuery+"; "+insertAPIKeyQuery)
+	defer tracker.finish(&err)
+
+	err = withinTx(ctx, repository.db, repository.logger, func(ctx context.Context) error {
+		result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, expireQuery), oldID, oldExpiresAt)
+		if err != nil {
+			return fmt.Errorf("error expiring rotated api key: %v", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error expiring rotated api key: %v", err)
+		}
+		if affected == 0 {
+			return fmt.Errorf("active api key %d %w", oldID, ErrNotFound)
+		}
+
+		replacement.RotatedFrom = sql.NullInt64{Int64: oldID, Valid: true}
+		created, err = insertAPIKey(ctx, conn(ctx, repository.db), replacement)
+		return err
+	})
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	tracker.setRows(2)
+
+	return created, nil
+}
+
+// Revoke disables a key immediately
+func (repository *apiKeyRepository) Revoke(ctx context.Context, id int64) (err error) {
+	query := `UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`
+	ctx, tracker := startQuery(ctx, repository.logger, "api_keys.Revoke", query)
+	defer tracker.finish(&err)
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query), id)
+	if err != nil {
+		return fmt.Errorf("error revoking api key: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error revoking api key: %v", err)
+	}
+	tracker.setRows(affected)
+	if affected == 0 {
+		return fmt.Errorf("active api key %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// TouchLastUsed updates last_used_at unless it was updated during the last minute
+func (repository *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64) (err error) {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "api_keys.TouchLastUsed", query)
+	defer tracker.finish(&err)
+
+	result, err := repository.statements.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("error updating api key last use: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		tracker.setRows(affected)
+	}
+
+	return nil
+}
+
+const insertAPIKeyQuery = `
+	INSERT INTO api_keys (prefix, secret_hash, name, owner_email, scopes, expires_at, rotated_from)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + apiKeyColumns
+
+func insertAPIKey(ctx context.Context, db DBTX, key models.APIKey) (models.APIKey, error) {
+	created, err := scanAPIKey(db.QueryRowContext(ctx, annotate(ctx, insertAPIKeyQuery),
+		key.Prefix,
+		key.SecretHash,
+		key.Name,
+		key.OwnerEmail,
+		pq.Array(key.Scopes),
+		key.ExpiresAt,
+		key.RotatedFrom,
+	))
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("error inserting api key: %v", err)
+	}
+	return created, nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/audit"
+	"org/gg/banking/internal/models"
+	"strings"
+	"time"
+)
+
+type IAuditRepository interface {
+	// Append records an entry, joining the transaction of ctx so the entry commits together with the change
+	Append(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error)
+	Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	// Head returns the newest chained entry, or an entry with a zero ID when nothing is chained yet
+	Head(ctx context.Context) (models.AuditEntry, error)
+	// Walk calls fn with every entry in id order until fn returns false
+	Walk(ctx context.Context, fn func(entry models.AuditEntry) bool) error
+}
+
+type auditRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewAuditRepository(db *sql.DB, logger *slog.Logger) IAuditRepository {
+	return &auditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const auditColumns = `id, occurred_at, actor, action, entity_type, entity_id, before, after, request_id, client_ip, prev_hash, hash`
+
+func scanAuditEntry(row rowScanner) (entry models.AuditEntry, err error) {
+	var before, after []byte
+	var requestID, clientIP, prevHash, hash sql.NullString
+	err = row.Scan(
+		&entry.ID,
+		&entry.OccurredAt,
+		&entry.Actor,
+		&entry.Action,
+		&entry.EntityType,
+		&entry.EntityID,
+		&before,
+		&after,
+		&requestID,
+		&clientIP,
+		&prevHash,
+		&hash,
+	)
+	entry.Before, entry.After = before, after
+	entry.RequestID, entry.ClientIP = requestID.String, clientIP.String
+	entry.PrevHash, entry.Hash = prevHash.String, hash.String
+	return entry, err
+}
+
+// Append inserts an entry linked to the chain head and returns it as stored.
+// Appends are serialized by a transaction-scoped advisory lock so each one links to the committed head,
+// which means transactions that record changes commit one at a time.
+func (repository *auditRepository) Append(ctx context.Context, entry models.AuditEntry) (created models.AuditEntry, err error) {
+	lockQuery := `SELECT pg_advisory_xact_lock(hashtext('audit_log'))`
+	headQuery := `SELECT hash FROM audit_log WHERE hash IS NOT NULL ORDER BY id DESC LIMIT 1`
+	query := `
+		INSERT INTO audit_log (occurred_at, actor, action, entity_type, entity_id, before, after, request_id, client_ip, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING ` + auditColumns
+	ctx, tracker := startQuery(ctx, repository.logger, "audit_log.Append", lockQuery+"; "+headQuery+"; "+query)
+	defer tracker.finish(&err)
+
+	err = withinTx(ctx, repository.db, repository.logger, func(ctx context.Context) error {
+		db := conn(ctx, repository.db)
+		if _, err := db.ExecContext(ctx, annotate(ctx, lockQuery)); err != nil {
+			return fmt.Errorf("error locking audit chain: %v", err)
+		}
+
+		entry.PrevHash = audit.GenesisHash
+		if err := db.QueryRowContext(ctx, annotate(ctx, headQuery)).Scan(&entry.PrevHash); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error reading audit chain head: %v", err)
+		}
+
+		// Postgres keeps microseconds, hash the timestamp exactly as it will be read back
+		entry.OccurredAt = time.Now().UTC().Truncate(time.Microsecond)
+		hash, err := audit.Hash(entry)
+		if err != nil {
+			return fmt.Errorf("error hashing audit entry: %v", err)
+		}
+		entry.Hash = hash
+
+		created, err = scanAuditEntry(db.QueryRowContext(ctx, annotate(ctx, query),
+			entry.OccurredAt,
+			entry.Actor,
+			entry.Action,
+			entry.EntityType,
+			entry.EntityID,
+			nullJSON(entry.Before),
+			nullJSON(entry.After),
+			sql.NullString{String: entry.RequestID, Valid: entry.RequestID != ""},
+			sql.NullString{String: entry.ClientIP, Valid: entry.ClientIP != ""},
+			entry.PrevHash,
+			entry.Hash,
+		))
+		if err != nil {
+			return fmt.Errorf("error inserting audit entry: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+	tracker.setRows(1)
+
+	return created, nil
+}
+
+// Find lists the entries matching filter, newest first
+func (repository *auditRepository) Find(ctx context.Context, filter models.AuditFilter) (entries []models.AuditEntry, err error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Actor != "" {
+		where("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		where("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where("entity_id = $%d", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		where("request_id = $%d", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		where("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("occurred_at < $%d", filter.To)
+	}
+	if filter.BeforeID > 0 {
+		where("id < $%d", filter.BeforeID)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	ctx, tracker := startQuery(ctx, repository.logger, "audit_log.Find", query)
+	defer tracker.finish(&err)
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, annotate(ctx, query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying audit log: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning audit entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %v", err)
+	}
+	tracker.setRows(int64(len(entries)))
+
+	return entries, nil
+}
+
+// Head returns the newest chained entry, or an entry with a zero ID when nothing is chained yet
+func (repository *auditRepository) Head(ctx context.Context) (entry models.AuditEntry, err error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE hash IS NOT NULL ORDER BY id DESC LIMIT 1`
+	ctx, tracker := startQuery(ctx, repository.logger, "audit_log.Head", query)
+	defer tracker.finish(&err)
+
+	entry, err = scanAuditEntry(conn(ctx, repository.db).QueryRowContext(ctx, annotate(ctx, query)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AuditEntry{}, nil
+	}
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("error reading audit chain head: %v", err)
+	}
+	tracker.setRows(1)
+
+	return entry, nil
+}
+
+// Walk streams every entry in id order, oldest first, until fn returns false
+func (repository *auditRepository) Walk(ctx context.Context, fn func(entry models.AuditEntry) bool) (err error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log ORDER BY id`
+	ctx, tracker := startQuery(ctx, repository.logger, "audit_log.Walk", query)
+	defer tracker.finish(&err)
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, annotate(ctx, query))
+	if err != nil {
+		return fmt.Errorf("error querying audit log: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	var count int64
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return fmt.Errorf("error scanning audit entry: %v", err)
+		}
+		count++
+		if !fn(entry) {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating audit log: %v", err)
+	}
+	tracker.setRows(count)
+
+	return nil
+}
+
+// nullJSON stores a missing snapshot as NULL rather than invalid JSON
+func nullJSON(snapshot []byte) any {
+	if len(snapshot) == 0 {
+		return nil
+	}
+	return string(snapshot)
+}
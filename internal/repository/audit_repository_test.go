@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"org/gg/banking/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	db := openTestDB(t)
+	ctx := testTx(t, db)
+	repository := NewAuditRepository(db, testLogger())
+
+	const requestID = "audit-repository-test"
+	var appended []models.AuditEntry
+	for _, action := range []string{models.AuditActionCreate, models.AuditActionDelete} {
+		entry, err := repository.Append(ctx, models.AuditEntry{
+			Actor:      "user-1",
+			Action:     action,
+			EntityType: models.AuditEntityCustomer,
+			EntityID:   "john.doe@example.com",
+			After:      []byte(`{"email":"john.doe@example.com"}`),
+			RequestID:  requestID,
+			ClientIP:   "203.0.113.7",
+		})
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		appended = append(appended, entry)
+	}
+	if appended[1].PrevHash != appended[0].Hash {
+		t.Errorf("second entry links to %s, want the hash of the first %s", appended[1].PrevHash, appended[0].Hash)
+	}
+
+	entries, err := repository.Find(ctx, models.AuditFilter{RequestID: requestID, Limit: 10})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != appended[1].ID || entries[1].ID != appended[0].ID {
+		t.Fatalf("Find() = %+v, want both entries newest first", entries)
+	}
+	if entries[1].ClientIP != "203.0.113.7" || string(entries[1].After) != `{"email": "john.doe@example.com"}` {
+		t.Errorf("Find() returned %+v, want the stored client ip and snapshot", entries[1])
+	}
+
+	entries, err = repository.Find(ctx, models.AuditFilter{RequestID: requestID, Action: models.AuditActionCreate, Limit: 10})
+	if err != nil || len(entries) != 1 || entries[0].ID != appended[0].ID {
+		t.Errorf("Find() by action = %+v, %v, want the creation only", entries, err)
+	}
+
+	// The trigger rejects changes, the failed statement aborts the test transaction so it must come last
+	_, err = conn(ctx, db).ExecContext(ctx, `UPDATE audit_log SET actor = 'someone' WHERE request_id = $1`, requestID)
+	if err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("updating an audit entry error = %v, want the append-only error", err)
+	}
+}
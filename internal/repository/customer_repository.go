@@ -1,45 +1,64 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"org/gg/banking/internal/models"
+
+	"github.com/lib/pq"
 )
 
+// ErrNotFound is returned when the requested record does not exist
+var ErrNotFound = errors.New("not found")
+
 type ICustomerRepository interface {
-	FindAll() ([]models.Customer, error)
-	FindByEmail(email string) (models.Customer, error)
-	Create(customer models.Customer) (models.Customer, error)
-	DeleteByEmail(email string) error // New method
+	FindAll(ctx context.Context) ([]models.Customer, error)
+	FindByEmail(ctx context.Context, email string) (models.Customer, error)
+	FindByID(ctx context.Context, id int64) (models.Customer, error)
+	// FindAllWithAccounts loads a page of customers with their open accounts in one query
+	FindAllWithAccounts(ctx context.Context, page models.CustomerPage) ([]models.CustomerWithAccounts, error)
+	// FindByEmailWithAccounts loads a customer with their open accounts in one query
+	FindByEmailWithAccounts(ctx context.Context, email string) (models.CustomerWithAccounts, error)
+	Create(ctx context.Context, customer models.Customer) (models.Customer, error)
+	// ImportBatch copies customers with distinct emails into a staging table and inserts those whose email is new,
+	// returning them. With dryRun nothing is inserted, the customers that would be are returned without an id.
+	ImportBatch(ctx context.Context, customers []models.Customer, dryRun bool) ([]models.Customer, error)
+	// Export calls fn with every customer matching filter, ordered by id, until fn fails
+	Export(ctx context.Context, filter models.CustomerExportFilter, fn func(row models.CustomerExportRow) error) error
+	DeleteByEmail(ctx context.Context, email string) error // New method
 }
 
 type customerRepository struct {
 	db         *sql.DB
+	logger     *slog.Logger
+	statements *statementCache
 	collection string
 }
 
-func NewCustomerRepository(db *sql.DB) ICustomerRepository {
+// NewCustomerRepository creates a repository whose statements are prepared once and reused
+func NewCustomerRepository(db *sql.DB, logger *slog.Logger) ICustomerRepository {
 	return &customerRepository{
 		db:         db,
+		logger:     logger,
+		statements: newStatementCache(db, logger),
 		collection: "customer_collection",
 	}
 }
 
-func (repo *customerRepository) FindAll() ([]models.Customer, error) {
-	rows, err := repo.db.Query("SELECT id, first_name, last_name, email, phone FROM customers")
+func (repo *customerRepository) FindAll(ctx context.Context) (customers []models.Customer, err error) {
+	query := "SELECT id, first_name, last_name, email, phone FROM customers"
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.FindAll", query)
+	defer tracker.finish(&err)
+
+	rows, err := repo.statements.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	defer func(rows *sql.Rows) {
-		err := rows.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(rows)
+	defer closeRows(ctx, repo.logger, rows)
 
-	var customers []models.Customer
 	for rows.Next() {
 		var customer models.Customer
 		if err := rows.Scan(&customer.ID, &customer.FirstName, &customer.LastName, &customer.Email, &customer.Phone); err != nil {
@@ -47,20 +66,124 @@ func (repo *customerRepository) FindAll() ([]models.Customer, error) {
 		}
 		customers = append(customers, customer)
 	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customers: %v", err)
+	}
+	tracker.setRows(int64(len(customers)))
+
 	return customers, nil
 }
 
-// FindByEmail retrieves a customer by email
-func (repo *customerRepository) FindByEmail(email string) (models.Customer, error) {
-	var customer models.Customer
+// customerWithAccountsColumns selects a customer and one of their accounts per row, the account columns are NULL
+// for customers without open accounts
+const customerWithAccountsColumns = `
+	c.id, c.first_name, c.last_name, c.email, c.phone,
+	a.id, a.account_number, a.balance, a.account_description, a.created_at, a.updated_at
+	FROM customers c
+	LEFT JOIN accounts a ON a.customer_id = c.id AND a.deleted_at IS NULL
+`
+
+// FindAllWithAccounts joins customers to their accounts instead of querying the accounts of each customer. The page
+// bounds the customers rather than the rows, a customer always comes with all of their accounts.
+func (repo *customerRepository) FindAllWithAccounts(ctx context.Context, page models.CustomerPage) (customers []models.CustomerWithAccounts, err error) {
+	query := `SELECT ` + customerWithAccountsColumns + `
+		WHERE c.id IN (SELECT id FROM customers ORDER BY id LIMIT $1 OFFSET $2)
+		ORDER BY c.id, a.id`
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.FindAllWithAccounts", query)
+	defer tracker.finish(&err)
+
+	rows, err := repo.statements.QueryContext(ctx, query, page.Limit, page.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying customers with accounts: %v", err)
+	}
+	defer closeRows(ctx, repo.logger, rows)
+
+	customers, scanned, err := scanCustomersWithAccounts(rows)
+	if err != nil {
+		return nil, err
+	}
+	tracker.setRows(scanned)
+
+	return customers, nil
+}
+
+// FindByEmailWithAccounts retrieves a customer by email together with their accounts
+func (repo *customerRepository) FindByEmailWithAccounts(ctx context.Context, email string) (customer models.CustomerWithAccounts, err error) {
+	query := `SELECT ` + customerWithAccountsColumns + ` WHERE c.email = $1 ORDER BY a.id`
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.FindByEmailWithAccounts", query)
+	defer tracker.finish(&err)
+
+	rows, err := repo.statements.QueryContext(ctx, query, email)
+	if err != nil {
+		return models.CustomerWithAccounts{}, fmt.Errorf("error querying customer with accounts: %v", err)
+	}
+	defer closeRows(ctx, repo.logger, rows)
+
+	customers, scanned, err := scanCustomersWithAccounts(rows)
+	if err != nil {
+		return models.CustomerWithAccounts{}, err
+	}
+	tracker.setRows(scanned)
+	if len(customers) == 0 {
+		return models.CustomerWithAccounts{}, fmt.Errorf("customer %s %w", email, ErrNotFound)
+	}
+
+	return customers[0], nil
+}
+
+// scanCustomersWithAccounts groups the rows of customerWithAccountsColumns, ordered by customer, into customers.
+// It also returns the number of rows read.
+func scanCustomersWithAccounts(rows *sql.Rows) (customers []models.CustomerWithAccounts, scanned int64, err error) {
+	for rows.Next() {
+		var customer models.Customer
+		var accountID sql.NullInt64
+		var accountNumber, accountDescription sql.NullString
+		var balance sql.NullFloat64
+		var createdAt, updatedAt sql.NullTime
+		err := rows.Scan(
+			&customer.ID, &customer.FirstName, &customer.LastName, &customer.Email, &customer.Phone,
+			&accountID, &accountNumber, &balance, &accountDescription, &createdAt, &updatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning customer with accounts: %v", err)
+		}
+		scanned++
+
+		if len(customers) == 0 || customers[len(customers)-1].Customer.ID != customer.ID {
+			customers = append(customers, models.CustomerWithAccounts{Customer: customer})
+		}
+		if accountID.Valid {
+			current := &customers[len(customers)-1]
+			current.Accounts = append(current.Accounts, models.Account{
+				ID:                 accountID.Int64,
+				CustomerID:         customer.ID,
+				AccountNumber:      accountNumber.String,
+				Balance:            balance.Float64,
+				AccountDescription: accountDescription.String,
+				CreatedAt:          createdAt.Time,
+				UpdatedAt:          updatedAt.Time,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating customers with accounts: %v", err)
+	}
+	return customers, scanned, nil
+}
 
+// FindByEmail retrieves a customer by email
+func (repo *customerRepository) FindByEmail(ctx context.Context, email string) (customer models.Customer, err error) {
 	query := `
 		SELECT id, first_name, last_name, email, phone
 		FROM customers
 		WHERE email = $1
 	`
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.FindByEmail", query)
+	defer tracker.finish(&err)
 
-	err := repo.db.QueryRow(query, email).Scan(
+	err = repo.statements.QueryRowContext(ctx, query, email).Scan(
 		&customer.ID,
 		&customer.FirstName,
 		&customer.LastName,
@@ -70,24 +193,55 @@ func (repo *customerRepository) FindByEmail(email string) (models.Customer, erro
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.Customer{}, fmt.Errorf("customer with email %s not found", email)
+			return models.Customer{}, fmt.Errorf("customer with email %s %w", email, ErrNotFound)
 		}
 		return models.Customer{}, fmt.Errorf("error querying customer by email: %v", err)
 	}
+	tracker.setRows(1)
+
+	return customer, nil
+}
+
+// FindByID retrieves a customer by id
+func (repo *customerRepository) FindByID(ctx context.Context, id int64) (customer models.Customer, err error) {
+	query := `
+		SELECT id, first_name, last_name, email, phone
+		FROM customers
+		WHERE id = $1
+	`
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.FindByID", query)
+	defer tracker.finish(&err)
+
+	err = repo.statements.QueryRowContext(ctx, query, id).Scan(
+		&customer.ID,
+		&customer.FirstName,
+		&customer.LastName,
+		&customer.Email,
+		&customer.Phone,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Customer{}, fmt.Errorf("customer %d %w", id, ErrNotFound)
+		}
+		return models.Customer{}, fmt.Errorf("error querying customer by id: %v", err)
+	}
+	tracker.setRows(1)
 
 	return customer, nil
 }
 
 // Create inserts a new customer into the database and returns the created customer
-func (repo *customerRepository) Create(customer models.Customer) (models.Customer, error) {
+func (repo *customerRepository) Create(ctx context.Context, customer models.Customer) (createdCustomer models.Customer, err error) {
 	query := `
 		INSERT INTO customers (first_name, last_name, email, phone)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id, first_name, last_name, email, phone
 	`
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.Create", query)
+	defer tracker.finish(&err)
 
-	var createdCustomer models.Customer
-	err := repo.db.QueryRow(query, customer.FirstName, customer.LastName, customer.Email, customer.Phone).Scan(
+	err = repo.statements.QueryRowContext(ctx, query, customer.FirstName, customer.LastName, customer.Email, customer.Phone).Scan(
 		&createdCustomer.ID,
 		&createdCustomer.FirstName,
 		&createdCustomer.LastName,
@@ -98,12 +252,230 @@ func (repo *customerRepository) Create(customer models.Customer) (models.Custome
 	if err != nil {
 		return models.Customer{}, fmt.Errorf("error inserting new customer: %v", err)
 	}
+	tracker.setRows(1)
 
 	return createdCustomer, nil
 }
 
-// DeleteByEmail deletes a customer by email
-func (repo *customerRepository) DeleteByEmail(email string) error {
-	_, err := repo.db.Exec("DELETE FROM customers WHERE email = $1", email)
+// exportFetchSize is how many rows an export fetches from its cursor at a time
+const exportFetchSize = 1000
+
+// Export reads the customers through a server-side cursor, so however many there are only one fetch of rows is held
+// in memory. The cursor lives in the transaction of ctx, or in one of its own kept open until the export ends.
+func (repo *customerRepository) Export(ctx context.Context, filter models.CustomerExportFilter, fn func(row models.CustomerExportRow) error) (err error) {
+	query := `SELECT id, first_name, last_name, email, phone, NULL, NULL, NULL, NULL, NULL, NULL FROM customers c`
+	if filter.WithAccounts {
+		query = `SELECT ` + customerWithAccountsColumns
+	}
+	// The filter and the page select customers, so a page never splits the accounts of a customer
+	customers := `SELECT id FROM customers`
+	var args []any
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		customers += ` WHERE email = $1`
+	}
+	customers += ` ORDER BY id`
+	if filter.Page.Limit > 0 {
+		args = append(args, filter.Page.Limit)
+		customers += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	if filter.Page.Offset > 0 {
+		args = append(args, filter.Page.Offset)
+		customers += fmt.Sprintf(` OFFSET $%d`, len(args))
+	}
+	if len(args) > 0 {
+		query += ` WHERE c.id IN (` + customers + `)`
+	}
+	query += ` ORDER BY c.id`
+	if filter.WithAccounts {
+		query += `, a.id`
+	}
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.Export", query)
+	defer tracker.finish(&err)
+
+	var exported int64
+	err = withinTx(ctx, repo.db, repo.logger, func(ctx context.Context) error {
+		db := conn(ctx, repo.db)
+		if _, err := db.ExecContext(ctx, annotate(ctx, `DECLARE customer_export NO SCROLL CURSOR FOR `+query), args...); err != nil {
+			return fmt.Errorf("error declaring export cursor: %v", err)
+		}
+		defer func() {
+			if _, err := db.ExecContext(context.WithoutCancel(ctx), `CLOSE customer_export`); err != nil {
+				repo.logger.DebugContext(ctx, "Error closing export cursor", slog.Any("error", err))
+			}
+		}()
+
+		fetch := fmt.Sprintf(`FETCH FORWARD %d FROM customer_export`, exportFetchSize)
+		for {
+			fetched, err := repo.fetchExport(ctx, db, fetch, fn)
+			exported += fetched
+			if err != nil {
+				return err
+			}
+			if fetched < exportFetchSize {
+				return nil
+			}
+		}
+	})
+	tracker.setRows(exported)
+
 	return err
 }
+
+// fetchExport fetches the next rows of the export cursor and hands them to fn, returning how many it fetched
+func (repo *customerRepository) fetchExport(ctx context.Context, db DBTX, fetch string, fn func(row models.CustomerExportRow) error) (fetched int64, err error) {
+	rows, err := db.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching exported customers: %v", err)
+	}
+	defer closeRows(ctx, repo.logger, rows)
+
+	for rows.Next() {
+		var row models.CustomerExportRow
+		var accountID sql.NullInt64
+		var accountNumber, accountDescription sql.NullString
+		var balance sql.NullFloat64
+		var createdAt, updatedAt sql.NullTime
+		err := rows.Scan(
+			&row.Customer.ID, &row.Customer.FirstName, &row.Customer.LastName, &row.Customer.Email, &row.Customer.Phone,
+			&accountID, &accountNumber, &balance, &accountDescription, &createdAt, &updatedAt,
+		)
+		if err != nil {
+			return fetched, fmt.Errorf("error scanning exported customer: %v", err)
+		}
+		fetched++
+
+		if accountID.Valid {
+			row.Account = &models.Account{
+				ID:                 accountID.Int64,
+				CustomerID:         row.Customer.ID,
+				AccountNumber:      accountNumber.String,
+				Balance:            balance.Float64,
+				AccountDescription: accountDescription.String,
+				CreatedAt:          createdAt.Time,
+				UpdatedAt:          updatedAt.Time,
+			}
+		}
+		if err := fn(row); err != nil {
+			return fetched, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fetched, fmt.Errorf("error iterating exported customers: %v", err)
+	}
+	return fetched, nil
+}
+
+// customerImportStaging is the table imports are copied into, private to the transaction and dropped with it
+const customerImportStaging = "customer_import_staging"
+
+// ImportBatch loads the batch with COPY, which is far cheaper than an insert per row, and inserts from the staging
+// table in one statement. Emails that already exist are left alone, so concurrent imports of the same customer
+// cannot fail each other. It runs in the transaction of ctx or in one of its own.
+func (repo *customerRepository) ImportBatch(ctx context.Context, customers []models.Customer, dryRun bool) (imported []models.Customer, err error) {
+	query := `
+		INSERT INTO customers (first_name, last_name, email, phone)
+		SELECT first_name, last_name, email, phone FROM ` + customerImportStaging + ` ORDER BY position
+		ON CONFLICT (email) DO NOTHING
+		RETURNING id, first_name, last_name, email, phone
+	`
+	if dryRun {
+		query = `
+			SELECT 0, s.first_name, s.last_name, s.email, s.phone
+			FROM ` + customerImportStaging + ` s
+			WHERE NOT EXISTS (SELECT 1 FROM customers c WHERE c.email = s.email)
+			ORDER BY s.position
+		`
+	}
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.ImportBatch", query)
+	defer tracker.finish(&err)
+
+	err = withinTx(ctx, repo.db, repo.logger, func(ctx context.Context) error {
+		if err := repo.stage(ctx, customers); err != nil {
+			return err
+		}
+
+		// The staging table is created in this transaction, so these statements are not kept prepared
+		rows, err := conn(ctx, repo.db).QueryContext(ctx, annotate(ctx, query))
+		if err != nil {
+			return fmt.Errorf("error importing customers: %v", err)
+		}
+		defer closeRows(ctx, repo.logger, rows)
+
+		for rows.Next() {
+			var customer models.Customer
+			if err := rows.Scan(&customer.ID, &customer.FirstName, &customer.LastName, &customer.Email, &customer.Phone); err != nil {
+				return fmt.Errorf("error scanning imported customer: %v", err)
+			}
+			imported = append(imported, customer)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating imported customers: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	tracker.setRows(int64(len(imported)))
+
+	return imported, nil
+}
+
+// stage copies customers into an empty staging table of the transaction of ctx
+func (repo *customerRepository) stage(ctx context.Context, customers []models.Customer) error {
+	tx := ctx.Value(txKey{}).(*sql.Tx)
+
+	_, err := tx.ExecContext(ctx, annotate(ctx, `
+		CREATE TEMP TABLE IF NOT EXISTS `+customerImportStaging+`
+		(
+			position   INTEGER PRIMARY KEY,
+			first_name VARCHAR(100) NOT NULL,
+			last_name  VARCHAR(100) NOT NULL,
+			email      VARCHAR(100) NOT NULL,
+			phone      VARCHAR(20)  NOT NULL
+		) ON COMMIT DROP;
+		TRUNCATE `+customerImportStaging))
+	if err != nil {
+		return fmt.Errorf("error creating import staging table: %v", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(customerImportStaging, "position", "first_name", "last_name", "email", "phone"))
+	if err != nil {
+		return fmt.Errorf("error starting copy: %v", err)
+	}
+	defer func() {
+		if err := stmt.Close(); err != nil {
+			repo.logger.WarnContext(ctx, "Error closing statement", slog.Any("error", err))
+		}
+	}()
+
+	for i, customer := range customers {
+		if _, err := stmt.ExecContext(ctx, i, customer.FirstName, customer.LastName, customer.Email, customer.Phone); err != nil {
+			return fmt.Errorf("error copying customer %s: %v", customer.Email, err)
+		}
+	}
+	// Flushes the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("error copying customers: %v", err)
+	}
+	return nil
+}
+
+// DeleteByEmail deletes a customer by email
+func (repo *customerRepository) DeleteByEmail(ctx context.Context, email string) (err error) {
+	query := "DELETE FROM customers WHERE email = $1"
+	ctx, tracker := startQuery(ctx, repo.logger, "customers.DeleteByEmail", query)
+	defer tracker.finish(&err)
+
+	result, err := repo.statements.ExecContext(ctx, query, email)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		tracker.setRows(affected)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"org/gg/banking/internal/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+// BenchmarkFindByEmailWithAccounts compares loading a customer and their accounts with one JOIN against looking
+// up the customer and then their accounts
+func BenchmarkFindByEmailWithAccounts(b *testing.B) {
+	db := openTestDB(b)
+	customer := seedCustomers(b, db, 1, 5)[0]
+	customerRepository := NewCustomerRepository(db, testLogger())
+	accountRepository := NewAccountRepository(db, testLogger())
+	ctx := context.Background()
+
+	b.Run("join", func(b *testing.B) {
+		for range b.N {
+			if _, err := customerRepository.FindByEmailWithAccounts(ctx, customer.Email); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("two queries", func(b *testing.B) {
+		for range b.N {
+			found, err := customerRepository.FindByEmail(ctx, customer.Email)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := accountRepository.FindByCustomerID(ctx, found.ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkFindAllWithAccounts compares loading a page of customers and their accounts with one JOIN against
+// listing the customers and looking up the accounts of each
+func BenchmarkFindAllWithAccounts(b *testing.B) {
+	const customers = 100
+	db := openTestDB(b)
+	seedCustomers(b, db, customers, 3)
+	customerRepository := NewCustomerRepository(db, testLogger())
+	accountRepository := NewAccountRepository(db, testLogger())
+	ctx := context.Background()
+
+	b.Run("join", func(b *testing.B) {
+		for range b.N {
+			if _, err := customerRepository.FindAllWithAccounts(ctx, models.CustomerPage{Limit: customers}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("query per customer", func(b *testing.B) {
+		for range b.N {
+			found, err := customerRepository.FindAll(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for _, customer := range found[:min(customers, len(found))] {
+				if _, err := accountRepository.FindByCustomerID(ctx, customer.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestExportPage(t *testing.T) {
+	db := openTestDB(t)
+	ctx := testTx(t, db)
+	customerRepository := NewCustomerRepository(db, testLogger())
+	accountRepository := NewAccountRepository(db, testLogger())
+
+	run := time.Now().UnixNano()
+	var seeded []models.Customer
+	for i := range 3 {
+		customer, err := customerRepository.Create(ctx, models.Customer{
+			FirstName: "Test",
+			LastName:  fmt.Sprintf("Customer %d", i),
+			Email:     fmt.Sprintf("export-%d-%d@example.com", run, i),
+		})
+		if err != nil {
+			t.Fatalf("creating customer: %v", err)
+		}
+		seeded = append(seeded, customer)
+		for j := range 2 {
+			if _, err := accountRepository.CreateAccount(ctx, customer.ID, models.Account{
+				AccountNumber:      fmt.Sprintf("EXPORT-%d-%d-%d", run, i, j),
+				AccountDescription: "Test account",
+			}); err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+		}
+	}
+
+	export := func(filter models.CustomerExportFilter) (customerIDs []int64, rows int) {
+		t.Helper()
+		err := customerRepository.Export(ctx, filter, func(row models.CustomerExportRow) error {
+			if len(customerIDs) == 0 || customerIDs[len(customerIDs)-1] != row.Customer.ID {
+				customerIDs = append(customerIDs, row.Customer.ID)
+			}
+			rows++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Export(%+v) error = %v", filter, err)
+		}
+		return customerIDs, rows
+	}
+
+	// Other customers may exist, the page is placed relative to the first seeded one
+	all, _ := export(models.CustomerExportFilter{})
+	before := slices.Index(all, seeded[0].ID)
+	if before < 0 || len(all) != before+len(seeded) {
+		t.Fatalf("Export() customers %v, want the seeded ones last", all)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.CustomerExportFilter
+		wantIDs  []int64
+		wantRows int
+	}{
+		{name: "page", filter: models.CustomerExportFilter{Page: models.CustomerPage{Limit: 2, Offset: before}},
+			wantIDs: []int64{seeded[0].ID, seeded[1].ID}, wantRows: 2},
+		{name: "page with accounts", filter: models.CustomerExportFilter{WithAccounts: true, Page: models.CustomerPage{Limit: 2, Offset: before + 1}},
+			wantIDs: []int64{seeded[1].ID, seeded[2].ID}, wantRows: 4},
+		{name: "offset only", filter: models.CustomerExportFilter{WithAccounts: true, Page: models.CustomerPage{Offset: before + 2}},
+			wantIDs: []int64{seeded[2].ID}, wantRows: 2},
+		{name: "email", filter: models.CustomerExportFilter{Email: seeded[1].Email, WithAccounts: true},
+			wantIDs: []int64{seeded[1].ID}, wantRows: 2},
+		{name: "email and page", filter: models.CustomerExportFilter{Email: seeded[1].Email, Page: models.CustomerPage{Limit: 10}},
+			wantIDs: []int64{seeded[1].ID}, wantRows: 1},
+		{name: "email past the page", filter: models.CustomerExportFilter{Email: seeded[1].Email, Page: models.CustomerPage{Offset: 1}},
+			wantIDs: nil, wantRows: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, rows := export(tt.filter)
+			if !slices.Equal(ids, tt.wantIDs) || rows != tt.wantRows {
+				t.Errorf("Export() customers %v in %d rows, want %v in %d rows", ids, rows, tt.wantIDs, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/models"
+	"slices"
+	"time"
+)
+
+type IOutboxRepository interface {
+	// Add writes an event, joining the transaction of ctx so the event commits together with the change
+	Add(ctx context.Context, event models.Event) error
+	// Claim leases up to limit due events, oldest first. Claimed events become due again once lease expires,
+	// so an event whose relay stopped before marking it is delivered again.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, outboxID int64) error
+	// MarkFailed records a failed delivery and when to try again
+	MarkFailed(ctx context.Context, outboxID int64, reason string, retryAt time.Time) error
+	// Purge deletes events published before the given time and returns how many were deleted
+	Purge(ctx context.Context, publishedBefore time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewOutboxRepository(db *sql.DB, logger *slog.Logger) IOutboxRepository {
+	return &outboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Add inserts a pending event
+func (repository *outboxRepository) Add(ctx context.Context, event models.Event) (err error) {
+	query := `
+		INSERT INTO outbox (event_type, aggregate_type, aggregate_id, payload, request_id, customer_email)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "outbox.Add", query)
+	defer tracker.finish(&err)
+
+	_, err = conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query),
+		event.Type,
+		event.AggregateType,
+		event.AggregateID,
+		string(event.Payload),
+		sql.NullString{String: event.RequestID, Valid: event.RequestID != ""},
+		sql.NullString{String: event.CustomerEmail, Valid: event.CustomerEmail != ""},
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting outbox event: %v", err)
+	}
+	tracker.setRows(1)
+
+	return nil
+}
+
+// Claim leases due events in a single statement. SKIP LOCKED lets several relays claim concurrently
+// without blocking on or delivering the same events.
+func (repository *outboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) (events []models.OutboxEvent, err error) {
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, next_attempt_at = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE published_at IS NULL AND next_attempt_at <= now()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, attempts, event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at, request_id, customer_email
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "outbox.Claim", query)
+	defer tracker.finish(&err)
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, annotate(ctx, query), limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming outbox events: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		var requestID, customerEmail sql.NullString
+		err := rows.Scan(
+			&event.OutboxID,
+			&event.Attempts,
+			&event.Event.ID,
+			&event.Event.Type,
+			&event.Event.AggregateType,
+			&event.Event.AggregateID,
+			&payload,
+			&event.Event.OccurredAt,
+			&requestID,
+			&customerEmail,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning outbox event: %v", err)
+		}
+		event.Event.Payload, event.Event.RequestID, event.Event.CustomerEmail = payload, requestID.String, customerEmail.String
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox events: %v", err)
+	}
+	tracker.setRows(int64(len(events)))
+
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(events, func(a, b models.OutboxEvent) int {
+		return cmp.Compare(a.OutboxID, b.OutboxID)
+	})
+	return events, nil
+}
+
+// MarkPublished records a successful delivery
+func (repository *outboxRepository) MarkPublished(ctx context.Context, outboxID int64) (err error) {
+	query := `UPDATE outbox SET published_at = now(), last_error = NULL WHERE id = $1`
+	ctx, tracker := startQuery(ctx, repository.logger, "outbox.MarkPublished", query)
+	defer tracker.finish(&err)
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query), outboxID)
+	if err != nil {
+		return fmt.Errorf("error marking outbox event %d published: %v", outboxID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error marking outbox event %d published: %v", outboxID, err)
+	}
+	tracker.setRows(affected)
+	if affected == 0 {
+		return fmt.Errorf("outbox event %d %w", outboxID, ErrNotFound)
+	}
+
+	return nil
+}
+
+// MarkFailed records the error of a failed delivery and schedules the next attempt
+func (repository *outboxRepository) MarkFailed(ctx context.Context, outboxID int64, reason string, retryAt time.Time) (err error) {
+	query := `UPDATE outbox SET last_error = $2, next_attempt_at = $3 WHERE id = $1 AND published_at IS NULL`
+	ctx, tracker := startQuery(ctx, repository.logger, "outbox.MarkFailed", query)
+	defer tracker.finish(&err)
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query), outboxID, reason, retryAt)
+	if err != nil {
+		return fmt.Errorf("error marking outbox event %d failed: %v", outboxID, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error marking outbox event %d failed: %v", outboxID, err)
+	}
+	tracker.setRows(affected)
+
+	return nil
+}
+
+// Purge deletes published events older than publishedBefore
+func (repository *outboxRepository) Purge(ctx context.Context, publishedBefore time.Time) (deleted int64, err error) {
+	query := `DELETE FROM outbox WHERE published_at < $1`
+	ctx, tracker := startQuery(ctx, repository.logger, "outbox.Purge", query)
+	defer tracker.finish(&err)
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query), publishedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error purging outbox: %v", err)
+	}
+
+	deleted, err = result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error purging outbox: %v", err)
+	}
+	tracker.setRows(deleted)
+
+	return deleted, nil
+}
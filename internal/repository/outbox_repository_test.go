@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"org/gg/banking/internal/models"
+	"testing"
+	"time"
+)
+
+func TestOutboxClaimLeases(t *testing.T) {
+	db := openTestDB(t)
+	ctx := testTx(t, db)
+	repository := NewOutboxRepository(db, testLogger())
+
+	// Pending events of the database would be claimed first, the transaction hides them from this test only
+	if _, err := conn(ctx, db).ExecContext(ctx, `UPDATE outbox SET published_at = now() WHERE published_at IS NULL`); err != nil {
+		t.Fatalf("hiding pending events: %v", err)
+	}
+	for _, eventType := range []string{models.EventCustomerCreated, models.EventCustomerDeleted} {
+		event := models.Event{Type: eventType, AggregateType: models.AggregateCustomer, AggregateID: "john.doe@example.com", Payload: []byte(`{}`)}
+		if err := repository.Add(ctx, event); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	// now() is fixed for the transaction, so a lease of 0 makes an event due again and a longer one does not
+	claimed, err := repository.Claim(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].OutboxID >= claimed[1].OutboxID || claimed[0].Attempts != 1 {
+		t.Fatalf("Claim() = %+v, want both events oldest first on their first attempt", claimed)
+	}
+	created, deleted := claimed[0], claimed[1]
+
+	if err := repository.MarkPublished(ctx, created.OutboxID); err != nil {
+		t.Fatalf("MarkPublished: %v", err)
+	}
+	if err := repository.MarkFailed(ctx, deleted.OutboxID, "broker unavailable", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+
+	claimed, err = repository.Claim(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].OutboxID != deleted.OutboxID || claimed[0].Attempts != 2 {
+		t.Fatalf("Claim() after a failure = %+v, want only the failed event on its second attempt", claimed)
+	}
+
+	if claimed, err := repository.Claim(ctx, 10, time.Minute); err != nil || len(claimed) != 0 {
+		t.Errorf("Claim() of a leased event = %+v, %v, want nothing due", claimed, err)
+	}
+
+	if err := repository.MarkPublished(ctx, -1); err == nil {
+		t.Error("MarkPublished() of an unknown event succeeded")
+	}
+
+	purged, err := repository.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if purged < 1 {
+		t.Errorf("Purge() deleted %d events, want at least the published one", purged)
+	}
+}
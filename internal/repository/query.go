@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/metrics"
+	"org/gg/banking/internal/tracing"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// safeCommentValue guards against closing the SQL comment from a request id
+var safeCommentValue = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// annotate prefixes a query with a comment carrying the request id found in ctx,
+// so statements can be correlated with request logs in pg_stat_activity and the Postgres log
+func annotate(ctx context.Context, query string) string {
+	requestID := requestIDOf(ctx)
+	if requestID == "" || !safeCommentValue.MatchString(requestID) {
+		return query
+	}
+	return "/* request_id=" + requestID + " */ " + query
+}
+
+// requestIDOf returns the id of the request ctx belongs to, or an empty string outside of a request
+func requestIDOf(ctx context.Context) string {
+	return logger.RequestID(ctx)
+}
+
+// whitespace collapses the indentation of multi-line statements for span attributes
+var whitespace = regexp.MustCompile(`\s+`)
+
+// queryTracker follows a single repository statement: it owns the statement's span and
+// logs and measures its outcome when finished
+type queryTracker struct {
+	ctx       context.Context
+	logger    *slog.Logger
+	operation string
+	start     time.Time
+	span      trace.Span
+}
+
+// startQuery opens a span for a statement of the given repository operation, such as customers.FindAll.
+// The returned context carries the span and must be used to run the statement; finish is deferred with a pointer
+// to the operation's named error result.
+func startQuery(ctx context.Context, logger *slog.Logger, operation, query string) (context.Context, *queryTracker) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation.name", operation),
+		// Values are always bound as parameters, so the statement text holds no customer data
+		attribute.String("db.query.text", sanitizeQuery(query)),
+	}
+	// Prepared statements cannot carry the request id in their text, their spans always do
+	if requestID := requestIDOf(ctx); requestID != "" {
+		attrs = append(attrs, attribute.String("request_id", requestID))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+
+	return ctx, &queryTracker{
+		ctx:       ctx,
+		logger:    logger,
+		operation: operation,
+		start:     time.Now(),
+		span:      span,
+	}
+}
+
+// setRows records how many rows the statement returned or affected
+func (t *queryTracker) setRows(rows int64) {
+	t.span.SetAttributes(attribute.Int64("db.response.rows", rows))
+}
+
+// finish ends the span and logs the outcome: failures are logged at error level, everything else at debug.
+// A missing record is an expected outcome, not a failure.
+func (t *queryTracker) finish(err *error) {
+	duration := time.Since(t.start)
+	failed := *err != nil && !errors.Is(*err, ErrNotFound)
+	metrics.ObserveQuery(t.operation, duration, failed)
+
+	attrs := []slog.Attr{
+		slog.String("db_operation", t.operation),
+		slog.Float64("db_duration_ms", float64(duration.Microseconds())/1000),
+	}
+
+	if failed {
+		tracing.End(t.span, *err)
+		t.logger.LogAttrs(t.ctx, slog.LevelError, "Database operation failed", append(attrs, slog.Any("error", *err))...)
+		return
+	}
+	t.span.End()
+	t.logger.LogAttrs(t.ctx, slog.LevelDebug, "Database operation completed", attrs...)
+}
+
+// sanitizeQuery returns the statement on a single line
+func sanitizeQuery(query string) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+}
+
+// closeRows closes a result set, logging instead of failing when the driver reports an error
+func closeRows(ctx context.Context, logger *slog.Logger, rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		logger.WarnContext(ctx, "Error closing rows", slog.Any("error", err))
+	}
+}
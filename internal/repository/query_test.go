@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/config/logger"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording every span until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestStartQueryCarriesRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+	}{
+		{name: "request", requestID: "req-42"},
+		{name: "background"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := recordSpans(t)
+			var logs bytes.Buffer
+			queryLogger := slog.New(logger.NewContextHandler(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = logger.WithRequest(ctx, tt.requestID, "/api/v1/customers/", "127.0.0.1", time.Now())
+			}
+			_, tracker := startQuery(ctx, queryLogger, "customers.FindAll", "SELECT id\n\t\tFROM customers")
+			var err error
+			tracker.finish(&err)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d spans, want 1", len(spans))
+			}
+			attrs := map[string]string{}
+			for _, attr := range spans[0].Attributes() {
+				attrs[string(attr.Key)] = attr.Value.Emit()
+			}
+			if attrs["db.query.text"] != "SELECT id FROM customers" {
+				t.Errorf("db.query.text = %q, want the statement on one line", attrs["db.query.text"])
+			}
+			if attrs["request_id"] != tt.requestID {
+				t.Errorf("span request_id = %q, want %q", attrs["request_id"], tt.requestID)
+			}
+
+			var record map[string]any
+			if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+				t.Fatalf("decoding log record %q: %v", logs.String(), err)
+			}
+			if got, _ := record["request_id"].(string); got != tt.requestID {
+				t.Errorf("log request_id = %q, want %q", got, tt.requestID)
+			}
+		})
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		want      string
+	}{
+		{name: "request", requestID: "req-42.a_b", want: "/* request_id=req-42.a_b */ SELECT 1"},
+		{name: "background", want: "SELECT 1"},
+		{name: "id closing the comment", requestID: "x*/ DROP TABLE customers; --", want: "SELECT 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = logger.WithRequest(ctx, tt.requestID, "/api/v1/customers/", "127.0.0.1", time.Now())
+			}
+			if got := annotate(ctx, "SELECT 1"); got != tt.want {
+				t.Errorf("annotate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryTrackerLevels(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantLevel string
+	}{
+		{name: "success", wantLevel: "DEBUG"},
+		{name: "not found", err: fmt.Errorf("customer %w", ErrNotFound), wantLevel: "DEBUG"},
+		{name: "failure", err: errors.New("connection reset"), wantLevel: "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			queryLogger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			_, tracker := startQuery(context.Background(), queryLogger, "customers.FindByEmail", "SELECT id FROM customers")
+			err := tt.err
+			tracker.finish(&err)
+
+			var record map[string]any
+			if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+				t.Fatalf("decoding log record %q: %v", logs.String(), err)
+			}
+			if record["level"] != tt.wantLevel || record["db_operation"] != "customers.FindByEmail" {
+				t.Errorf("logged %v for %v, want %s for customers.FindByEmail", record["level"], record["db_operation"], tt.wantLevel)
+			}
+		})
+	}
+}
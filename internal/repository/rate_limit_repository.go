@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// IRateLimitRepository stores token buckets in Postgres so every instance shares them.
+// It satisfies ratelimit.IStore.
+type IRateLimitRepository interface {
+	Take(ctx context.Context, key string, capacity, ratePerSecond float64) (float64, bool, error)
+	Sweep(ctx context.Context, idle time.Duration) error
+}
+
+type rateLimitRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewRateLimitRepository(db *sql.DB, logger *slog.Logger) IRateLimitRepository {
+	return &rateLimitRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Take refills and takes from a bucket in a single statement, the row lock serializes concurrent requests.
+// Time is read from the database so instance clocks do not matter.
+func (repository *rateLimitRepository) Take(ctx context.Context, key string, capacity, ratePerSecond float64) (tokens float64, allowed bool, err error) {
+	query := `
+		INSERT INTO rate_limit_buckets AS bucket (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, now())
+		ON CONFLICT (key) DO UPDATE SET (tokens, allowed, updated_at) = (
+			SELECT CASE WHEN refill.tokens >= 1 THEN refill.tokens - 1 ELSE refill.tokens END, refill.tokens >= 1, now()
+			FROM (
+				SELECT LEAST($2::float8, bucket.tokens + EXTRACT(EPOCH FROM now() - bucket.updated_at)::float8 * $3::float8) AS tokens
+			) AS refill
+		)
+		RETURNING tokens, allowed
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "rate_limit_buckets.Take", query)
+	defer tracker.finish(&err)
+
+	if err = repository.db.QueryRowContext(ctx, annotate(ctx, query), key, capacity, ratePerSecond).Scan(&tokens, &allowed); err != nil {
+		return 0, false, fmt.Errorf("error taking rate limit token: %v", err)
+	}
+	tracker.setRows(1)
+
+	return tokens, allowed, nil
+}
+
+// Sweep deletes buckets untouched for longer than idle
+func (repository *rateLimitRepository) Sweep(ctx context.Context, idle time.Duration) (err error) {
+	query := `DELETE FROM rate_limit_buckets WHERE updated_at < now() - make_interval(secs => $1)`
+	ctx, tracker := startQuery(ctx, repository.logger, "rate_limit_buckets.Sweep", query)
+	defer tracker.finish(&err)
+
+	result, err := repository.db.ExecContext(ctx, annotate(ctx, query), idle.Seconds())
+	if err != nil {
+		return fmt.Errorf("error sweeping rate limit buckets: %v", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil {
+		tracker.setRows(affected)
+	}
+
+	return nil
+}
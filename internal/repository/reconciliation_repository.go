@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/models"
+)
+
+type IReconciliationRepository interface {
+	FindDiscrepancies(ctx context.Context) ([]models.Discrepancy, error)
+}
+
+type reconciliationRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewReconciliationRepository(db *sql.DB, logger *slog.Logger) IReconciliationRepository {
+	return &reconciliationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// reconciliationChecks maps each check name to a query returning (entity_type, entity_key, detail) rows
+var reconciliationChecks = []struct {
+	name  string
+	query string
+}{
+	{
+		name: "closed_account_with_balance",
+		query: `
+			SELECT 'account', account_number, 'closed account still holds balance ' || balance::text
+			FROM accounts
+			WHERE deleted_at IS NOT NULL AND balance <> 0
+			ORDER BY account_number
+		`,
+	},
+	{
+		name: "negative_balance",
+		query: `
+			SELECT 'account', account_number, 'open account has negative balance ' || balance::text
+			FROM accounts
+			WHERE deleted_at IS NULL AND balance < 0
+			ORDER BY account_number
+		`,
+	},
+	{
+		name: "balance_differs_from_movements",
+		query: `
+			SELECT 'account', a.account_number, 'balance ' || a.balance::text || ' differs from its movements ' || COALESCE(m.total, 0)::text
+			FROM accounts a
+			LEFT JOIN (SELECT account_id, SUM(amount) AS total FROM account_movements GROUP BY account_id) m ON m.account_id = a.id
+			WHERE a.balance <> COALESCE(m.total, 0)
+			ORDER BY a.account_number
+		`,
+	},
+	{
+		name: "timestamps_out_of_order",
+		query: `
+			SELECT 'account', account_number, 'updated_at is earlier than created_at'
+			FROM accounts
+			WHERE updated_at < created_at
+			ORDER BY account_number
+		`,
+	},
+	{
+		name: "customer_without_open_account",
+		query: `
+			SELECT 'customer', c.email, 'customer has no open accounts'
+			FROM customers c
+			WHERE NOT EXISTS (
+				SELECT 1 FROM accounts a WHERE a.customer_id = c.id AND a.deleted_at IS NULL
+			)
+			ORDER BY c.email
+		`,
+	},
+}
+
+// FindDiscrepancies runs every reconciliation check and returns the inconsistencies found
+func (repository *reconciliationRepository) FindDiscrepancies(ctx context.Context) ([]models.Discrepancy, error) {
+	var discrepancies []models.Discrepancy
+	for _, check := range reconciliationChecks {
+		found, err := repository.runCheck(ctx, check.name, check.query)
+		if err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, found...)
+	}
+
+	return discrepancies, nil
+}
+
+func (repository *reconciliationRepository) runCheck(ctx context.Context, name, query string) (discrepancies []models.Discrepancy, err error) {
+	ctx, tracker := startQuery(ctx, repository.logger, "reconciliation."+name, query)
+	defer tracker.finish(&err)
+
+	rows, err := repository.db.QueryContext(ctx, annotate(ctx, query))
+	if err != nil {
+		return nil, fmt.Errorf("error running reconciliation check %s: %v", name, err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		discrepancy := models.Discrepancy{Check: name}
+		if err := rows.Scan(&discrepancy.EntityType, &discrepancy.EntityKey, &discrepancy.Detail); err != nil {
+			return nil, fmt.Errorf("error scanning reconciliation row: %v", err)
+		}
+		discrepancies = append(discrepancies, discrepancy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reconciliation rows: %v", err)
+	}
+	tracker.setRows(int64(len(discrepancies)))
+
+	return discrepancies, nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// statementCache prepares each statement of a repository once and shares it between goroutines. A *sql.Stmt
+// prepares itself again on every pooled connection it runs on, so statements survive connections being lost and
+// replaced. Statements run in the transaction carried by the context, through tx.StmtContext, when there is one.
+//
+// The statement text is fixed when it is prepared, so unlike other statements it is not annotated with the request
+// id. The span and log records of every statement carry the id instead, see startQuery.
+type statementCache struct {
+	db     *sql.DB
+	logger *slog.Logger
+
+	mu         sync.RWMutex
+	statements map[string]*sql.Stmt
+}
+
+func newStatementCache(db *sql.DB, logger *slog.Logger) *statementCache {
+	return &statementCache{
+		db:         db,
+		logger:     logger,
+		statements: make(map[string]*sql.Stmt),
+	}
+}
+
+// ExecContext runs a prepared statement that returns no rows
+func (c *statementCache) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	run := func() (sql.Result, error) {
+		stmt, err := c.stmt(ctx, query)
+		if err != nil {
+			return conn(ctx, c.db).ExecContext(ctx, query, args...)
+		}
+		return stmt.ExecContext(ctx, args...)
+	}
+
+	result, err := run()
+	if c.discardIfInvalid(ctx, query, err) {
+		return run()
+	}
+	return result, err
+}
+
+// QueryContext runs a prepared statement that returns rows
+func (c *statementCache) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	run := func() (*sql.Rows, error) {
+		stmt, err := c.stmt(ctx, query)
+		if err != nil {
+			return conn(ctx, c.db).QueryContext(ctx, query, args...)
+		}
+		return stmt.QueryContext(ctx, args...)
+	}
+
+	rows, err := run()
+	if c.discardIfInvalid(ctx, query, err) {
+		return run()
+	}
+	return rows, err
+}
+
+// QueryRowContext runs a prepared statement that returns at most one row. Its errors are only reported by Scan,
+// so a statement Postgres no longer knows is not prepared again until an Exec or Query of it fails.
+func (c *statementCache) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	stmt, err := c.stmt(ctx, query)
+	if err != nil {
+		return conn(ctx, c.db).QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// stmt returns the statement prepared for query, bound to the transaction of ctx. When preparing fails, for
+// instance while the database is unreachable, the caller runs the query unprepared and preparing is tried again
+// on the next call.
+func (c *statementCache) stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.RLock()
+	stmt, ok := c.statements[query]
+	c.mu.RUnlock()
+
+	if !ok {
+		// Prepared on the pool rather than the transaction, so the statement outlives it
+		prepared, err := c.db.PrepareContext(context.WithoutCancel(ctx), query)
+		if err != nil {
+			c.logger.WarnContext(ctx, "Error preparing statement, running it unprepared", slog.Any("error", err))
+			return nil, err
+		}
+
+		c.mu.Lock()
+		if stmt, ok = c.statements[query]; !ok {
+			stmt = prepared
+			c.statements[query] = stmt
+		}
+		c.mu.Unlock()
+		if stmt != prepared {
+			// Another goroutine prepared it meanwhile
+			c.close(ctx, prepared)
+		}
+	}
+
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		// Closed together with the transaction
+		return tx.StmtContext(ctx, stmt), nil
+	}
+	return stmt, nil
+}
+
+// discardIfInvalid drops the statement of query when Postgres reports it unusable, which happens when a pooler
+// such as PgBouncer discards prepared statements or the schema of a table it reads changed. It reports whether
+// the statement can be prepared and run again right away, which is not the case within a transaction the error
+// aborted.
+func (c *statementCache) discardIfInvalid(ctx context.Context, query string, err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	// 26000 invalid_sql_statement_name: the prepared statement does not exist
+	// 0A000 feature_not_supported: cached plan must not change result type
+	if pqErr.Code != "26000" && pqErr.Code != "0A000" {
+		return false
+	}
+
+	c.mu.Lock()
+	stmt, ok := c.statements[query]
+	delete(c.statements, query)
+	c.mu.Unlock()
+	if ok {
+		c.logger.WarnContext(ctx, "Prepared statement is no longer valid, preparing it again", slog.String("query", sanitizeQuery(query)))
+		c.close(ctx, stmt)
+	}
+
+	_, inTx := ctx.Value(txKey{}).(*sql.Tx)
+	return !inTx
+}
+
+func (c *statementCache) close(ctx context.Context, stmt *sql.Stmt) {
+	if err := stmt.Close(); err != nil {
+		c.logger.WarnContext(ctx, "Error closing statement", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// DBTX is the part of *sql.DB and *sql.Tx the repositories use
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type txKey struct{}
+
+// conn returns the transaction carried by ctx, so statements join it, or db outside of a transaction
+func conn(ctx context.Context, db *sql.DB) DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// ITransactor runs work spanning several repositories atomically
+type ITransactor interface {
+	// WithinTx runs fn with a context carrying a transaction, committed when fn succeeds and rolled back otherwise.
+	// Repository calls made with that context join the transaction, as do nested WithinTx calls.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactor struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewTransactor(db *sql.DB, logger *slog.Logger) ITransactor {
+	return &transactor{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withinTx(ctx, t.db, t.logger, fn)
+}
+
+// withinTx runs fn in the transaction of ctx, or in a new one it commits or rolls back
+func withinTx(ctx context.Context, db *sql.DB, logger *slog.Logger, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(ctx, logger, tx)
+			panic(p)
+		}
+		if err != nil {
+			rollback(ctx, logger, tx)
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	return nil
+}
+
+func rollback(ctx context.Context, logger *slog.Logger, tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logger.WarnContext(ctx, "Error rolling back transaction", slog.Any("error", err))
+	}
+}
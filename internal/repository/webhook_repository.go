@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"org/gg/banking/internal/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type IWebhookRepository interface {
+	// FindSubscriptions lists the subscriptions owned by ownerEmail, or every subscription when it is empty
+	FindSubscriptions(ctx context.Context, ownerEmail string) ([]models.WebhookSubscription, error)
+	FindSubscriptionByID(ctx context.Context, id int64) (models.WebhookSubscription, error)
+	CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookSubscription, error)
+	// DeleteSubscription removes a subscription together with its delivery history
+	DeleteSubscription(ctx context.Context, id int64) error
+	// Enqueue queues event for every subscription to its type and returns how many deliveries were queued.
+	// Subscriptions owned by a customer only receive the events about that customer. An event already queued for
+	// a subscription is skipped, so enqueueing is idempotent.
+	Enqueue(ctx context.Context, event models.Event) (int64, error)
+	// ClaimDeliveries leases up to limit due deliveries, oldest first, and counts the attempt
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDispatch, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	// MarkFailed records a failed attempt, retried at retryAt or given up on when dead is set
+	MarkFailed(ctx context.Context, id int64, statusCode int, reason string, retryAt time.Time, dead bool) error
+	FindDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	// Redeliver moves a dead delivery back to pending with a fresh set of attempts
+	Redeliver(ctx context.Context, subscriptionID, deliveryID int64) (models.WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewWebhookRepository(db *sql.DB, logger *slog.Logger) IWebhookRepository {
+	return &webhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const webhookSubscriptionColumns = `id, url, event_types, secret, description, owner_email, created_by, created_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at`
+
+func scanWebhookSubscription(row rowScanner) (subscription models.WebhookSubscription, err error) {
+	err = row.Scan(
+		&subscription.ID,
+		&subscription.URL,
+		pq.Array(&subscription.EventTypes),
+		&subscription.Secret,
+		&subscription.Description,
+		&subscription.OwnerEmail,
+		&subscription.CreatedBy,
+		&subscription.CreatedAt,
+	)
+	return subscription, err
+}
+
+// webhookDeliveryFields returns the scan destinations of webhookDeliveryColumns
+func webhookDeliveryFields(delivery *models.WebhookDelivery, payload *[]byte) []any {
+	return []any{
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	}
+}
+
+func scanWebhookDelivery(row rowScanner) (delivery models.WebhookDelivery, err error) {
+	var payload []byte
+	err = row.Scan(webhookDeliveryFields(&delivery, &payload)...)
+	delivery.Payload = payload
+	return delivery, err
+}
+
+// FindSubscriptions lists subscriptions, newest first
+func (repository *webhookRepository) FindSubscriptions(ctx context.Context, ownerEmail string) (subscriptions []models.WebhookSubscription, err error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE $1::text = '' OR owner_email = $1 ORDER BY id DESC`
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_subscriptions.FindAll", query)
+	defer tracker.finish(&err)
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, annotate(ctx, query), ownerEmail)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook subscriptions: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook subscription row: %v", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook subscription rows: %v", err)
+	}
+	tracker.setRows(int64(len(subscriptions)))
+
+	return subscriptions, nil
+}
+
+// FindSubscriptionByID retrieves a subscription by id
+func (repository *webhookRepository) FindSubscriptionByID(ctx context.Context, id int64) (subscription models.WebhookSubscription, err error) {
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_subscriptions.FindByID", query)
+	defer tracker.finish(&err)
+
+	subscription, err = scanWebhookSubscription(conn(ctx, repository.db).QueryRowContext(ctx, annotate(ctx, query), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebhookSubscription{}, fmt.Errorf("webhook subscription %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("error querying webhook subscription: %v", err)
+	}
+	tracker.setRows(1)
+
+	return subscription, nil
+}
+
+// CreateSubscription inserts a subscription and returns it as stored
+func (repository *webhookRepository) CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (created models.WebhookSubscription, err error) {
+	query := `
+		INSERT INTO webhook_subscriptions (url, event_types, secret, description, owner_email, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + webhookSubscriptionColumns
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_subscriptions.Create", query)
+	defer tracker.finish(&err)
+
+	created, err = scanWebhookSubscription(conn(ctx, repository.db).QueryRowContext(ctx, annotate(ctx, query),
+		subscription.URL,
+		pq.Array(subscription.EventTypes),
+		subscription.Secret,
+		subscription.Description,
+		subscription.OwnerEmail,
+		subscription.CreatedBy,
+	))
+	if err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("error inserting webhook subscription: %v", err)
+	}
+	tracker.setRows(1)
+
+	return created, nil
+}
+
+// DeleteSubscription deletes a subscription, its deliveries cascade
+func (repository *webhookRepository) DeleteSubscription(ctx context.Context, id int64) (err error) {
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1`
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_subscriptions.Delete", query)
+	defer tracker.finish(&err)
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query), id)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook subscription: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error deleting webhook subscription: %v", err)
+	}
+	tracker.setRows(affected)
+	if affected == 0 {
+		return fmt.Errorf("webhook subscription %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// Enqueue fans an event out to the matching subscriptions in one statement. An event without a customer only
+// reaches the subscriptions without an owner.
+func (repository *webhookRepository) Enqueue(ctx context.Context, event models.Event) (queued int64, err error) {
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1::uuid, $2::text, $3::jsonb
+		FROM webhook_subscriptions
+		WHERE ($2::text = ANY (event_types) OR '*' = ANY (event_types))
+		  AND (owner_email IS NULL OR owner_email = $4)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_deliveries.Enqueue", query)
+	defer tracker.finish(&err)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding webhook payload: %v", err)
+	}
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query), event.ID, event.Type, string(payload),
+		sql.NullString{String: event.CustomerEmail, Valid: event.CustomerEmail != ""})
+	if err != nil {
+		return 0, fmt.Errorf("error queueing webhook deliveries: %v", err)
+	}
+
+	queued, err = result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error queueing webhook deliveries: %v", err)
+	}
+	tracker.setRows(queued)
+
+	return queued, nil
+}
+
+// ClaimDeliveries leases due deliveries with the url and secret of their subscription.
+// SKIP LOCKED lets several workers claim concurrently without delivering the same rows.
+func (repository *webhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (dispatches []models.WebhookDispatch, err error) {
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1, next_attempt_at = now() + make_interval(secs => $2)
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= now()
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING ` + webhookDeliveryColumns + `
+		)
+		SELECT claimed.*, s.url, s.secret
+		FROM claimed
+		JOIN webhook_subscriptions s ON s.id = claimed.subscription_id
+		ORDER BY claimed.id
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_deliveries.Claim", query)
+	defer tracker.finish(&err)
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, annotate(ctx, query), limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		var dispatch models.WebhookDispatch
+		var payload []byte
+		fields := append(webhookDeliveryFields(&dispatch.Delivery, &payload), &dispatch.URL, &dispatch.Secret)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %v", err)
+		}
+		dispatch.Delivery.Payload = payload
+		dispatches = append(dispatches, dispatch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %v", err)
+	}
+	tracker.setRows(int64(len(dispatches)))
+
+	return dispatches, nil
+}
+
+// MarkDelivered records a successful attempt
+func (repository *webhookRepository) MarkDelivered(ctx context.Context, id int64, statusCode int) (err error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', delivered_at = now(), last_status_code = $2, last_error = NULL
+		WHERE id = $1
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_deliveries.MarkDelivered", query)
+	defer tracker.finish(&err)
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query), id, statusCode)
+	if err != nil {
+		return fmt.Errorf("error marking webhook delivery %d delivered: %v", id, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error marking webhook delivery %d delivered: %v", id, err)
+	}
+	tracker.setRows(affected)
+
+	return nil
+}
+
+// MarkFailed records a failed attempt. A status code of 0 means no response was received.
+func (repository *webhookRepository) MarkFailed(ctx context.Context, id int64, statusCode int, reason string, retryAt time.Time, dead bool) (err error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $5 THEN 'dead' ELSE 'pending' END,
+		    next_attempt_at = $4, last_status_code = $2, last_error = $3
+		WHERE id = $1 AND status = 'pending'
+	`
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_deliveries.MarkFailed", query)
+	defer tracker.finish(&err)
+
+	result, err := conn(ctx, repository.db).ExecContext(ctx, annotate(ctx, query),
+		id,
+		sql.NullInt64{Int64: int64(statusCode), Valid: statusCode != 0},
+		reason,
+		retryAt,
+		dead,
+	)
+	if err != nil {
+		return fmt.Errorf("error marking webhook delivery %d failed: %v", id, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error marking webhook delivery %d failed: %v", id, err)
+	}
+	tracker.setRows(affected)
+
+	return nil
+}
+
+// FindDeliveries lists the deliveries of a subscription, newest first
+func (repository *webhookRepository) FindDeliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (deliveries []models.WebhookDelivery, err error) {
+	conditions := []string{"subscription_id = $1"}
+	args := []any{filter.SubscriptionID}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.BeforeID > 0 {
+		args = append(args, filter.BeforeID)
+		conditions = append(conditions, fmt.Sprintf("id < $%d", len(args)))
+	}
+	args = append(args, filter.Limit)
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE ` + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_deliveries.Find", query)
+	defer tracker.finish(&err)
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, annotate(ctx, query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook deliveries: %v", err)
+	}
+	defer closeRows(ctx, repository.logger, rows)
+
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %v", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %v", err)
+	}
+	tracker.setRows(int64(len(deliveries)))
+
+	return deliveries, nil
+}
+
+// Redeliver resets a dead delivery so the worker picks it up again right away
+func (repository *webhookRepository) Redeliver(ctx context.Context, subscriptionID, deliveryID int64) (delivery models.WebhookDelivery, err error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND subscription_id = $2 AND status = 'dead'
+		RETURNING ` + webhookDeliveryColumns
+	ctx, tracker := startQuery(ctx, repository.logger, "webhook_deliveries.Redeliver", query)
+	defer tracker.finish(&err)
+
+	delivery, err = scanWebhookDelivery(conn(ctx, repository.db).QueryRowContext(ctx, annotate(ctx, query), deliveryID, subscriptionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebhookDelivery{}, fmt.Errorf("dead webhook delivery %d %w", deliveryID, ErrNotFound)
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("error redelivering webhook delivery: %v", err)
+	}
+	tracker.setRows(1)
+
+	return delivery, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"org/gg/banking/internal/models"
+	"testing"
+)
+
+func TestEnqueueScopesOwnedSubscriptions(t *testing.T) {
+	db := openTestDB(t)
+	ctx := testTx(t, db)
+	repository := NewWebhookRepository(db, testLogger())
+
+	subscribe := func(ownerEmail string) int64 {
+		t.Helper()
+		subscription := models.WebhookSubscription{
+			URL:        "https://partner.example/hooks",
+			EventTypes: []string{"*"},
+			Secret:     "whsec_test",
+			CreatedBy:  "test",
+			OwnerEmail: sql.NullString{String: ownerEmail, Valid: ownerEmail != ""},
+		}
+		created, err := repository.CreateSubscription(ctx, subscription)
+		if err != nil {
+			t.Fatalf("CreateSubscription: %v", err)
+		}
+		return created.ID
+	}
+	unowned := subscribe("")
+	john := subscribe("John.Doe@example.com")
+	jane := subscribe("jane.smith@example.com")
+
+	tests := []struct {
+		name          string
+		eventID       string
+		customerEmail string
+		want          map[int64]bool
+	}{
+		{
+			name:          "event about the owner",
+			eventID:       "00000000-0000-4000-8000-000000000001",
+			customerEmail: "john.doe@example.com",
+			want:          map[int64]bool{unowned: true, john: true, jane: false},
+		},
+		{
+			name:          "event about another customer",
+			eventID:       "00000000-0000-4000-8000-000000000002",
+			customerEmail: "jane.smith@example.com",
+			want:          map[int64]bool{unowned: true, john: false, jane: true},
+		},
+		{
+			name:    "event about no customer",
+			eventID: "00000000-0000-4000-8000-000000000003",
+			want:    map[int64]bool{unowned: true, john: false, jane: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := models.Event{
+				ID:            tt.eventID,
+				Type:          models.EventCustomerCreated,
+				AggregateType: models.AggregateCustomer,
+				AggregateID:   tt.customerEmail,
+				Payload:       []byte(`{}`),
+				CustomerEmail: tt.customerEmail,
+			}
+			if _, err := repository.Enqueue(ctx, event); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+
+			for subscriptionID, want := range tt.want {
+				deliveries, err := repository.FindDeliveries(ctx, models.WebhookDeliveryFilter{SubscriptionID: subscriptionID, Limit: 100})
+				if err != nil {
+					t.Fatalf("FindDeliveries: %v", err)
+				}
+				got := false
+				for _, delivery := range deliveries {
+					got = got || delivery.EventID == tt.eventID
+				}
+				if got != want {
+					t.Errorf("subscription %d received the event: %v, want %v", subscriptionID, got, want)
+				}
+			}
+		})
+	}
+}
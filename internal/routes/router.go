@@ -1,28 +1,100 @@
 package routes
 
 import (
+	"io"
+	"log/slog"
+	"net/http"
 	"org/gg/banking/internal/controllers"
+	"org/gg/banking/internal/metrics"
 	"org/gg/banking/internal/middleware/errors"
 	"org/gg/banking/internal/middleware/logger"
+	metricsmiddleware "org/gg/banking/internal/middleware/metrics"
+	"org/gg/banking/internal/middleware/requestid"
+	"os"
+	"slices"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// RouterConfig holds the dependencies of the middleware chain
+type RouterConfig struct {
+	Logger *slog.Logger
+	// ServiceName names the server spans of incoming requests
+	ServiceName string
+	// AccessLogFormat is common, combined or json
+	AccessLogFormat string
+	// AccessLogOutput receives common and combined access log lines, defaults to os.Stdout
+	AccessLogOutput io.Writer
+	Redactor        *logger.Redactor
+	BodyCapture     *logger.BodyCapture
+	// MetricsPath serves the Prometheus metrics, they are not exposed when empty
+	MetricsPath string
+	// TrustedProxies may set X-Forwarded-For, the client ip of other requests is their remote address
+	TrustedProxies []string
+}
+
 // SetupRouter initializes the Gin router and applies middleware
-func SetupRouter() *gin.Engine {
-	router := gin.Default()
+func SetupRouter(routerConfig RouterConfig) *gin.Engine {
+	// gin.New instead of gin.Default, the access log replaces gin's logger and the error middleware recovers panics
+	router := gin.New()
+	// Let gin.Context expose values of the request context, such as the request id, to loggers
+	router.ContextWithFallback = true
+	// Client ips key rate limits and appear in the access log, spoofed forwarding headers must not change them
+	if err := router.SetTrustedProxies(routerConfig.TrustedProxies); err != nil {
+		routerConfig.Logger.Error("Ignoring invalid trusted proxies", slog.Any("error", err))
+	}
 
-	router.Use(logger.HTTPLoggerMiddleware())
+	accessLogOutput := routerConfig.AccessLogOutput
+	if accessLogOutput == nil {
+		accessLogOutput = os.Stdout
+	}
+
+	// Tracing comes first so the request id can reuse the trace id and every later middleware runs inside the span
+	router.Use(otelgin.Middleware(routerConfig.ServiceName))
+	router.Use(requestid.RequestIDMiddleware())
+	router.Use(metricsmiddleware.MetricsMiddleware())
+	router.Use(logger.AccessLogMiddleware(routerConfig.Logger, routerConfig.Redactor, routerConfig.AccessLogFormat, accessLogOutput))
+	router.Use(logger.HTTPLoggerMiddleware(routerConfig.Logger, routerConfig.Redactor, routerConfig.BodyCapture))
 	// Register error middleware
-	router.Use(errors.ErrorHandlerMiddleware())
+	router.Use(errors.ErrorHandlerMiddleware(routerConfig.Logger))
+
+	if routerConfig.MetricsPath != "" {
+		router.GET(routerConfig.MetricsPath, gin.WrapH(metrics.Handler()))
+	}
 
 	return router
 }
 
+// RoutesConfig holds the controllers and the authentication applied to route groups
+type RoutesConfig struct {
+	CustomerController         controllers.ICustomerController
+	CustomerImportController   controllers.ICustomerImportController
+	CustomerExportController   controllers.ICustomerExportController
+	AccountStatementController controllers.IAccountStatementController
+	APIKeyController           controllers.IAPIKeyController
+	AuditController            controllers.IAuditController
+	WebhookController          controllers.IWebhookController
+	// Auth authenticates requests to the groups listed in ProtectedGroups, every other group is public
+	Auth            gin.HandlerFunc
+	ProtectedGroups []string
+	// PreAuthRateLimit runs before Auth on protected groups, so failed authentication is throttled per ip
+	PreAuthRateLimit gin.HandlerFunc
+	// RateLimit is applied to every group after authentication, so buckets can be keyed by principal
+	RateLimit gin.HandlerFunc
+}
+
 // RegisterRoutes adds all application routes to the router
-func RegisterRoutes(router *gin.Engine, customerController controllers.ICustomerController) {
+func RegisterRoutes(router *gin.Engine, routesConfig RoutesConfig) {
+	// Health check, public so load balancers and orchestrators can probe it
+	health := group(router, "/health", routesConfig)
+	health.GET("", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
 	// API v1 group
-	v1 := router.Group("/api/v1")
+	v1 := group(router, "/api/v1", routesConfig)
+	customerController := routesConfig.CustomerController
 
 	// Customer routes
 	customerGroup := v1.Group("/customers")
@@ -31,6 +103,57 @@ func RegisterRoutes(router *gin.Engine, customerController controllers.ICustomer
 		customerGroup.GET("/:email", customerController.GetCustomerByEmail)
 		customerGroup.POST("/", customerController.CreateCustomer)
 		customerGroup.DELETE("/:email", customerController.DeleteCustomerByEmail)
+		if importController := routesConfig.CustomerImportController; importController != nil {
+			customerGroup.POST("/import", importController.ImportCustomers)
+		}
+		if exportController := routesConfig.CustomerExportController; exportController != nil {
+			customerGroup.GET("/export", exportController.ExportCustomers)
+		}
 	}
 
+	// Account statements
+	if statementController := routesConfig.AccountStatementController; statementController != nil {
+		v1.GET("/accounts/:account_number/statements", statementController.GetStatement)
+	}
+
+	// Audit log, read-only
+	if auditController := routesConfig.AuditController; auditController != nil {
+		v1.GET("/audit", auditController.GetAuditLog)
+	}
+
+	// Webhook subscriptions and their delivery history
+	if webhookController := routesConfig.WebhookController; webhookController != nil {
+		webhookGroup := v1.Group("/webhooks")
+		webhookGroup.GET("", webhookController.GetWebhooks)
+		webhookGroup.POST("", webhookController.CreateWebhook)
+		webhookGroup.DELETE("/:id", webhookController.DeleteWebhook)
+		webhookGroup.GET("/:id/deliveries", webhookController.GetWebhookDeliveries)
+		webhookGroup.POST("/:id/deliveries/:delivery_id/retry", webhookController.RetryWebhookDelivery)
+	}
+
+	// API key administration, the policy restricts it to admins
+	apiKeyController := routesConfig.APIKeyController
+	if apiKeyController != nil {
+		apiKeyGroup := v1.Group("/admin/api-keys")
+		apiKeyGroup.GET("", apiKeyController.GetAPIKeys)
+		apiKeyGroup.POST("", apiKeyController.IssueAPIKey)
+		apiKeyGroup.POST("/:id/rotate", apiKeyController.RotateAPIKey)
+		apiKeyGroup.DELETE("/:id", apiKeyController.RevokeAPIKey)
+	}
+}
+
+// group creates a top level route group, protected by the auth middleware when its path is listed in the configuration
+// and rate limited when a limiter is configured
+func group(router *gin.Engine, path string, routesConfig RoutesConfig) *gin.RouterGroup {
+	routerGroup := router.Group(path)
+	if routesConfig.Auth != nil && slices.Contains(routesConfig.ProtectedGroups, path) {
+		if routesConfig.PreAuthRateLimit != nil {
+			routerGroup.Use(routesConfig.PreAuthRateLimit)
+		}
+		routerGroup.Use(routesConfig.Auth)
+	}
+	if routesConfig.RateLimit != nil {
+		routerGroup.Use(routesConfig.RateLimit)
+	}
+	return routerGroup
 }
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+)
+
+type IAccountService interface {
+	CloseAccount(ctx context.Context, accountNumber string) error
+}
+
+type accountService struct {
+	customerRepository repository.ICustomerRepository
+	accountRepository  repository.IAccountRepository
+	auditRepository    repository.IAuditRepository
+	outboxRepository   repository.IOutboxRepository
+	transactor         repository.ITransactor
+}
+
+// NewAccountService creates a new service with the provided repositories.
+// Changes are audited and their domain events written to the outbox in the same transaction.
+func NewAccountService(customerRepository repository.ICustomerRepository, accountRepository repository.IAccountRepository, auditRepository repository.IAuditRepository, outboxRepository repository.IOutboxRepository, transactor repository.ITransactor) IAccountService {
+	return &accountService{
+		customerRepository: customerRepository,
+		accountRepository:  accountRepository,
+		auditRepository:    auditRepository,
+		outboxRepository:   outboxRepository,
+		transactor:         transactor,
+	}
+}
+
+// CloseAccount soft-deletes an open account
+func (s *accountService) CloseAccount(ctx context.Context, accountNumber string) error {
+	return withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		before, err := s.accountRepository.FindByAccountNumber(ctx, accountNumber)
+		if err != nil && !stderrors.Is(err, repository.ErrNotFound) {
+			return errors.InternalServerError(fmt.Sprintf("Failed to retrieve account %s: %v", accountNumber, err))
+		}
+
+		err = s.accountRepository.CloseAccount(ctx, accountNumber)
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return errors.NotFoundError(fmt.Sprintf("Open account %s not found", accountNumber))
+		}
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to close account %s: %v", accountNumber, err))
+		}
+
+		after, err := s.accountRepository.FindByAccountNumber(ctx, accountNumber)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to retrieve account %s: %v", accountNumber, err))
+		}
+		customer, err := s.customerRepository.FindByID(ctx, after.CustomerID)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to retrieve the customer of account %s: %v", accountNumber, err))
+		}
+		if err := recordChange(ctx, s.auditRepository, models.AuditActionUpdate, models.AuditEntityAccount, accountNumber, before.ToAccountDTO(), after.ToAccountDTO()); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outboxRepository, models.EventAccountClosed, models.AggregateAccount, accountNumber, customer.Email, models.AccountPayload{
+			CustomerID: after.CustomerID,
+			Account:    after.ToAccountDTO(),
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"time"
+)
+
+type IAccountStatementService interface {
+	// Statement returns the movements of an account over the days of request with the running balance
+	Statement(ctx context.Context, request models.AccountStatementRequest) (models.AccountStatement, error)
+}
+
+type accountStatementService struct {
+	accountRepository  repository.IAccountRepository
+	customerRepository repository.ICustomerRepository
+	policy             IPolicy
+	location           *time.Location
+	maxDays            int
+}
+
+// NewAccountStatementService creates a service building account statements over days of the configured time zone,
+// authorizing every statement with policy
+func NewAccountStatementService(accountRepository repository.IAccountRepository, customerRepository repository.ICustomerRepository, policy IPolicy, statementConfig config.StatementConfiguration) (IAccountStatementService, error) {
+	location, err := time.LoadLocation(statementConfig.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("loading statement time zone: %w", err)
+	}
+	return &accountStatementService{
+		accountRepository:  accountRepository,
+		customerRepository: customerRepository,
+		policy:             policy,
+		location:           location,
+		maxDays:            statementConfig.MaxDays,
+	}, nil
+}
+
+// Statement covers the days from request.From to request.To, both included. Closed accounts keep their statements.
+// Accounts of other customers are reported as not found to callers restricted to their own records.
+func (s *accountStatementService) Statement(ctx context.Context, request models.AccountStatementRequest) (models.AccountStatement, error) {
+	access, err := s.policy.Authorize(ctx, ActionReadStatement)
+	if err != nil {
+		return models.AccountStatement{}, err
+	}
+
+	from := time.Date(request.From.Year(), request.From.Month(), request.From.Day(), 0, 0, 0, 0, s.location)
+	to := time.Date(request.To.Year(), request.To.Month(), request.To.Day(), 0, 0, 0, 0, s.location)
+	if to.Before(from) {
+		return models.AccountStatement{}, errors.BadRequestError("to must not be before from")
+	}
+	// The period ends at the start of the day after to
+	end := to.AddDate(0, 0, 1)
+	if end.After(from.AddDate(0, 0, s.maxDays)) {
+		return models.AccountStatement{}, errors.BadRequestError(fmt.Sprintf("A statement covers at most %d days", s.maxDays))
+	}
+
+	notFound := errors.NotFoundError(fmt.Sprintf("Account %s not found", request.AccountNumber))
+	account, err := s.accountRepository.FindByAccountNumber(ctx, request.AccountNumber)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return models.AccountStatement{}, notFound
+	}
+	if err != nil {
+		return models.AccountStatement{}, errors.InternalServerError(fmt.Sprintf("Failed to retrieve account %s: %v", request.AccountNumber, err))
+	}
+	customer, err := s.customerRepository.FindByID(ctx, account.CustomerID)
+	if err != nil {
+		return models.AccountStatement{}, errors.InternalServerError(fmt.Sprintf("Failed to retrieve the owner of account %s: %v", request.AccountNumber, err))
+	}
+	if !access.Allows(customer.Email) {
+		return models.AccountStatement{}, notFound
+	}
+
+	opening, movements, err := s.accountRepository.FindMovements(ctx, account.ID, from, end)
+	if err != nil {
+		return models.AccountStatement{}, errors.InternalServerError(fmt.Sprintf("Failed to retrieve the movements of account %s: %v", request.AccountNumber, err))
+	}
+
+	statement := models.AccountStatement{
+		Customer:       customer,
+		Account:        account,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		Lines:          make([]models.AccountStatementLine, len(movements)),
+		GeneratedAt:    time.Now().In(s.location),
+	}
+	balance := opening
+	for i, movement := range movements {
+		// Amounts are cents, rounding keeps the float sum from drifting
+		balance = math.Round((balance+movement.Amount)*100) / 100
+		movement.OccurredAt = movement.OccurredAt.In(s.location)
+		statement.Lines[i] = models.AccountStatementLine{AccountMovement: movement, Balance: balance}
+	}
+	statement.ClosingBalance = balance
+	return statement, nil
+}
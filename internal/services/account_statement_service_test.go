@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// ledgerStub answers FindMovements from an in-memory ledger with the bounds of the SQL query: the opening balance
+// sums the movements before from, the period holds those from from until before to
+type ledgerStub struct {
+	repository.IAccountRepository
+	accounts  map[string]models.Account
+	movements []models.AccountMovement
+	from, to  time.Time
+}
+
+func (l *ledgerStub) FindByAccountNumber(_ context.Context, accountNumber string) (models.Account, error) {
+	account, ok := l.accounts[accountNumber]
+	if !ok {
+		return models.Account{}, repository.ErrNotFound
+	}
+	return account, nil
+}
+
+func (l *ledgerStub) FindMovements(_ context.Context, accountID int64, from, to time.Time) (float64, []models.AccountMovement, error) {
+	l.from, l.to = from, to
+	var opening float64
+	var movements []models.AccountMovement
+	for _, movement := range l.movements {
+		switch {
+		case movement.AccountID != accountID:
+		case movement.OccurredAt.Before(from):
+			opening += movement.Amount
+		case movement.OccurredAt.Before(to):
+			movements = append(movements, movement)
+		}
+	}
+	return opening, movements, nil
+}
+
+func TestAccountStatementBalances(t *testing.T) {
+	athens, err := time.LoadLocation("Europe/Athens")
+	if err != nil {
+		t.Fatalf("loading time zone: %v", err)
+	}
+	utc := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", value, err)
+		}
+		return parsed
+	}
+	date := func(value string) time.Time {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", value, err)
+		}
+		return parsed
+	}
+
+	// Athens is UTC+2 in winter, so its days start at 22:00 UTC the day before
+	ledger := &ledgerStub{
+		accounts: map[string]models.Account{
+			"ACC-1": {ID: 1, CustomerID: 1, AccountNumber: "ACC-1"},
+			"ACC-2": {ID: 2, CustomerID: 2, AccountNumber: "ACC-2"},
+		},
+		movements: []models.AccountMovement{
+			{ID: 1, AccountID: 1, Amount: 100, OccurredAt: utc("2026-01-15T10:00:00Z")},
+			{ID: 2, AccountID: 1, Amount: 0.1, OccurredAt: utc("2026-01-31T21:59:59Z")}, // Jan 31 23:59:59 in Athens
+			{ID: 3, AccountID: 1, Amount: 0.2, OccurredAt: utc("2026-01-31T22:00:00Z")}, // Feb 1 00:00 in Athens
+			{ID: 4, AccountID: 1, Amount: -50.25, OccurredAt: utc("2026-02-14T12:00:00Z")},
+			{ID: 5, AccountID: 1, Amount: 10, OccurredAt: utc("2026-02-28T21:59:59Z")},   // Feb 28 23:59:59 in Athens
+			{ID: 6, AccountID: 1, Amount: 1000, OccurredAt: utc("2026-02-28T22:00:00Z")}, // Mar 1 00:00 in Athens
+			{ID: 7, AccountID: 2, Amount: 999, OccurredAt: utc("2026-02-10T12:00:00Z")},
+		},
+	}
+	customers := customerRepositoryStub{customers: map[string]models.Customer{
+		"john.doe@example.com": {ID: 1, Email: "john.doe@example.com"},
+		"jane.doe@example.com": {ID: 2, Email: "jane.doe@example.com"},
+	}}
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	service, err := NewAccountStatementService(ledger, customers, policy, config.StatementConfiguration{Timezone: "Europe/Athens", MaxDays: 31})
+	if err != nil {
+		t.Fatalf("NewAccountStatementService: %v", err)
+	}
+	teller := principalContext("", RoleTeller)
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		request      models.AccountStatementRequest
+		wantOpening  float64
+		wantBalances []float64
+		wantClosing  float64
+		wantStatus   int
+	}{
+		{
+			name:         "month",
+			request:      models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-02-01"), To: date("2026-02-28")},
+			wantOpening:  100.1,
+			wantBalances: []float64{100.3, 50.05, 60.05},
+			wantClosing:  60.05,
+		},
+		{
+			name:         "first day holds the movement at midnight",
+			request:      models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-02-01"), To: date("2026-02-01")},
+			wantOpening:  100.1,
+			wantBalances: []float64{100.3},
+			wantClosing:  100.3,
+		},
+		{
+			name:         "last day before the movement",
+			request:      models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-01-31"), To: date("2026-01-31")},
+			wantOpening:  100,
+			wantBalances: []float64{100.1},
+			wantClosing:  100.1,
+		},
+		{
+			name:         "next period opens with the closing balance",
+			request:      models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-03-01"), To: date("2026-03-31")},
+			wantOpening:  60.05,
+			wantBalances: []float64{1060.05},
+			wantClosing:  1060.05,
+		},
+		{
+			name:        "without movements",
+			request:     models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-02-15"), To: date("2026-02-27")},
+			wantOpening: 50.05,
+			wantClosing: 50.05,
+		},
+		{
+			name:        "before the first movement",
+			request:     models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2025-12-01"), To: date("2025-12-31")},
+			wantOpening: 0,
+			wantClosing: 0,
+		},
+		{
+			name:         "time of day ignored",
+			request:      models.AccountStatementRequest{AccountNumber: "ACC-1", From: utc("2026-02-01T23:30:00Z"), To: utc("2026-02-28T01:00:00Z")},
+			wantOpening:  100.1,
+			wantBalances: []float64{100.3, 50.05, 60.05},
+			wantClosing:  60.05,
+		},
+		{
+			name:         "owner",
+			ctx:          principalContext("john.doe@example.com", RoleCustomer),
+			request:      models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-02-01"), To: date("2026-02-01")},
+			wantOpening:  100.1,
+			wantBalances: []float64{100.3},
+			wantClosing:  100.3,
+		},
+		{
+			name:       "account of another customer",
+			ctx:        principalContext("john.doe@example.com", RoleCustomer),
+			request:    models.AccountStatementRequest{AccountNumber: "ACC-2", From: date("2026-02-01"), To: date("2026-02-28")},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown account",
+			request:    models.AccountStatementRequest{AccountNumber: "ACC-9", From: date("2026-02-01"), To: date("2026-02-28")},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "to before from",
+			request:    models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-02-02"), To: date("2026-02-01")},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "longest period",
+			request:      models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-01-01"), To: date("2026-01-31")},
+			wantOpening:  0,
+			wantBalances: []float64{100, 100.1},
+			wantClosing:  100.1,
+		},
+		{
+			name:       "period too long",
+			request:    models.AccountStatementRequest{AccountNumber: "ACC-1", From: date("2026-01-01"), To: date("2026-02-01")},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = teller
+			}
+
+			statement, err := service.Statement(ctx, tt.request)
+			if tt.wantStatus != 0 {
+				wantStatus(t, err, tt.wantStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Statement() error = %v", err)
+			}
+
+			// The period runs from midnight of from until midnight after to, in the statement time zone
+			wantFrom := time.Date(tt.request.From.Year(), tt.request.From.Month(), tt.request.From.Day(), 0, 0, 0, 0, athens)
+			wantTo := time.Date(tt.request.To.Year(), tt.request.To.Month(), tt.request.To.Day()+1, 0, 0, 0, 0, athens)
+			if !ledger.from.Equal(wantFrom) || !ledger.to.Equal(wantTo) {
+				t.Errorf("movements read from %s until %s, want %s until %s", ledger.from, ledger.to, wantFrom, wantTo)
+			}
+
+			if statement.OpeningBalance != tt.wantOpening || statement.ClosingBalance != tt.wantClosing {
+				t.Errorf("Statement() opening %v closing %v, want %v and %v", statement.OpeningBalance, statement.ClosingBalance, tt.wantOpening, tt.wantClosing)
+			}
+			if len(statement.Lines) != len(tt.wantBalances) {
+				t.Fatalf("Statement() has %d lines, want %d", len(statement.Lines), len(tt.wantBalances))
+			}
+			for i, line := range statement.Lines {
+				if line.Balance != tt.wantBalances[i] {
+					t.Errorf("line %d balance = %v, want %v", i+1, line.Balance, tt.wantBalances[i])
+				}
+				if line.OccurredAt.Location().String() != athens.String() {
+					t.Errorf("line %d occurred at %s, want Athens time", i+1, line.OccurredAt)
+				}
+			}
+		})
+	}
+}
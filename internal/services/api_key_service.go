@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix marks banking API keys, presented as bk_<prefix>_<secret>
+const apiKeyPrefix = "bk"
+
+type IAPIKeyService interface {
+	List(ctx context.Context) ([]models.APIKeyDTO, error)
+	Issue(ctx context.Context, request models.IssueAPIKeyRequest) (models.IssuedAPIKeyDTO, error)
+	Rotate(ctx context.Context, id int64) (models.IssuedAPIKeyDTO, error)
+	Revoke(ctx context.Context, id int64) error
+	// Authenticate resolves a presented key to the principal it acts as
+	Authenticate(ctx context.Context, key string) (auth.Principal, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepository repository.IAPIKeyRepository
+	auditRepository  repository.IAuditRepository
+	transactor       repository.ITransactor
+	policy           IPolicy
+	// rotationGrace is how long a rotated key keeps working so partners can roll out the replacement
+	rotationGrace time.Duration
+	now           func() time.Time
+}
+
+// NewAPIKeyService creates a new service with the provided repositories, authorizing management calls with policy.
+// Changes are audited in the same transaction.
+func NewAPIKeyService(apiKeyRepository repository.IAPIKeyRepository, auditRepository repository.IAuditRepository, transactor repository.ITransactor, policy IPolicy, rotationGrace time.Duration) IAPIKeyService {
+	return &apiKeyService{
+		apiKeyRepository: apiKeyRepository,
+		auditRepository:  auditRepository,
+		transactor:       transactor,
+		policy:           policy,
+		rotationGrace:    rotationGrace,
+		now:              time.Now,
+	}
+}
+
+// List returns every key without its secret
+func (s *apiKeyService) List(ctx context.Context) ([]models.APIKeyDTO, error) {
+	if _, err := s.authorizeAll(ctx, ActionListAPIKeys); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.apiKeyRepository.FindAll(ctx)
+	if err != nil {
+		return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve api keys: %v", err))
+	}
+
+	keyDtos := make([]models.APIKeyDTO, 0, len(keys))
+	for _, key := range keys {
+		keyDtos = append(keyDtos, key.ToAPIKeyDTO())
+	}
+	return keyDtos, nil
+}
+
+// Issue creates a key and returns its secret, which cannot be retrieved again
+func (s *apiKeyService) Issue(ctx context.Context, request models.IssueAPIKeyRequest) (models.IssuedAPIKeyDTO, error) {
+	if _, err := s.authorizeAll(ctx, ActionIssueAPIKey); err != nil {
+		return models.IssuedAPIKeyDTO{}, err
+	}
+
+	for _, scope := range request.Scopes {
+		if !slices.Contains(knownRoles, scope) {
+			return models.IssuedAPIKeyDTO{}, errors.BadRequestError(fmt.Sprintf("Unknown scope %q, must be one of %s", scope, strings.Join(knownRoles, ", ")))
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(s.now()) {
+		return models.IssuedAPIKeyDTO{}, errors.BadRequestError("expires_at must be in the future")
+	}
+
+	key := models.APIKey{Name: request.Name, Scopes: request.Scopes}
+	if request.OwnerEmail != "" {
+		key.OwnerEmail.String, key.OwnerEmail.Valid = models.NormalizeEmail(request.OwnerEmail), true
+	}
+	if request.ExpiresAt != nil {
+		key.ExpiresAt.Time, key.ExpiresAt.Valid = request.ExpiresAt.UTC(), true
+	}
+
+	secret, err := generateKey(&key)
+	if err != nil {
+		return models.IssuedAPIKeyDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to generate api key: %v", err))
+	}
+
+	var created models.APIKey
+	err = withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		var err error
+		created, err = s.apiKeyRepository.Create(ctx, key)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to store api key: %v", err))
+		}
+		return recordChange(ctx, s.auditRepository, models.AuditActionCreate, models.AuditEntityAPIKey, strconv.FormatInt(created.ID, 10), nil, created.ToAPIKeyDTO())
+	})
+	if err != nil {
+		return models.IssuedAPIKeyDTO{}, err
+	}
+
+	return models.IssuedAPIKeyDTO{APIKeyDTO: created.ToAPIKeyDTO(), Key: secret}, nil
+}
+
+// Rotate issues a replacement with the same name, owner, scopes and expiry.
+// The old key keeps working for the rotation grace period.
+func (s *apiKeyService) Rotate(ctx context.Context, id int64) (models.IssuedAPIKeyDTO, error) {
+	if _, err := s.authorizeAll(ctx, ActionRotateAPIKey); err != nil {
+		return models.IssuedAPIKeyDTO{}, err
+	}
+
+	old, err := s.apiKeyRepository.FindByID(ctx, id)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return models.IssuedAPIKeyDTO{}, errors.NotFoundError(fmt.Sprintf("API key %d not found", id))
+	}
+	if err != nil {
+		return models.IssuedAPIKeyDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to retrieve api key %d: %v", id, err))
+	}
+	if !old.Active(s.now()) {
+		return models.IssuedAPIKeyDTO{}, errors.ConflictError(fmt.Sprintf("API key %d is revoked or expired", id))
+	}
+
+	replacement := models.APIKey{
+		Name:       old.Name,
+		OwnerEmail: old.OwnerEmail,
+		Scopes:     old.Scopes,
+		ExpiresAt:  old.ExpiresAt,
+	}
+	secret, err := generateKey(&replacement)
+	if err != nil {
+		return models.IssuedAPIKeyDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to generate api key: %v", err))
+	}
+
+	var created models.APIKey
+	err = withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		var err error
+		created, err = s.apiKeyRepository.Rotate(ctx, id, replacement, s.now().UTC().Add(s.rotationGrace))
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return errors.ConflictError(fmt.Sprintf("API key %d is revoked", id))
+		}
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to rotate api key %d: %v", id, err))
+		}
+
+		rotated, err := s.apiKeyRepository.FindByID(ctx, id)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to retrieve api key %d: %v", id, err))
+		}
+		if err := recordChange(ctx, s.auditRepository, models.AuditActionUpdate, models.AuditEntityAPIKey, strconv.FormatInt(id, 10), old.ToAPIKeyDTO(), rotated.ToAPIKeyDTO()); err != nil {
+			return err
+		}
+		return recordChange(ctx, s.auditRepository, models.AuditActionCreate, models.AuditEntityAPIKey, strconv.FormatInt(created.ID, 10), nil, created.ToAPIKeyDTO())
+	})
+	if err != nil {
+		return models.IssuedAPIKeyDTO{}, err
+	}
+
+	return models.IssuedAPIKeyDTO{APIKeyDTO: created.ToAPIKeyDTO(), Key: secret}, nil
+}
+
+// Revoke disables a key immediately
+func (s *apiKeyService) Revoke(ctx context.Context, id int64) error {
+	if _, err := s.authorizeAll(ctx, ActionRevokeAPIKey); err != nil {
+		return err
+	}
+
+	return withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		err := s.apiKeyRepository.Revoke(ctx, id)
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return errors.NotFoundError(fmt.Sprintf("Active API key %d not found", id))
+		}
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to revoke api key %d: %v", id, err))
+		}
+
+		revoked, err := s.apiKeyRepository.FindByID(ctx, id)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to retrieve api key %d: %v", id, err))
+		}
+		before := revoked
+		before.RevokedAt = sql.NullTime{}
+		return recordChange(ctx, s.auditRepository, models.AuditActionUpdate, models.AuditEntityAPIKey, strconv.FormatInt(id, 10), before.ToAPIKeyDTO(), revoked.ToAPIKeyDTO())
+	})
+}
+
+// Authenticate checks the presented key against the stored hash. Every failure returns the same error
+// so callers cannot tell unknown, revoked and expired keys apart.
+func (s *apiKeyService) Authenticate(ctx context.Context, presented string) (auth.Principal, error) {
+	prefix, secret, ok := parseKey(presented)
+	if !ok {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepository.FindByPrefix(ctx, prefix)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 || !key.Active(s.now()) {
+		return auth.Principal{}, auth.ErrInvalidAPIKey
+	}
+
+	// Bookkeeping must not fail an authenticated request, the repository already logs the failure
+	_ = s.apiKeyRepository.TouchLastUsed(ctx, key.ID)
+
+	return auth.Principal{
+		Subject: auth.APIKeySubjectPrefix + key.Prefix,
+		Email:   key.OwnerEmail.String,
+		Roles:   key.Scopes,
+	}, nil
+}
+
+// authorizeAll requires unrestricted access, keys are not owned by customers
+func (s *apiKeyService) authorizeAll(ctx context.Context, action string) (Access, error) {
+	access, err := s.policy.Authorize(ctx, action)
+	if err != nil {
+		return Access{}, err
+	}
+	if !access.All() {
+		return Access{}, errors.ForbiddenError(fmt.Sprintf("Not allowed to perform %s", action))
+	}
+	return access, nil
+}
+
+// generateKey fills in the prefix and secret hash of key and returns the full key to hand out
+func generateKey(key *models.APIKey) (string, error) {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+
+	key.Prefix = hex.EncodeToString(prefixBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	key.SecretHash = hashSecret(secret)
+
+	return apiKeyPrefix + "_" + key.Prefix + "_" + secret, nil
+}
+
+// parseKey splits bk_<prefix>_<secret>. The secret is base64url and may itself contain underscores.
+func parseKey(presented string) (prefix, secret string, ok bool) {
+	parts := strings.SplitN(presented, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// hashSecret returns the hex SHA-256 of the secret. Secrets are 256 random bits, so a slow password hash is not needed.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	stderrors "errors"
+	"net/http"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiKeyRepositoryStub keeps keys in memory and behaves like the database for revoked and rotated keys
+type apiKeyRepositoryStub struct {
+	keys          map[int64]models.APIKey
+	prefixLookups []string
+	findErr       error
+}
+
+func newAPIKeyRepositoryStub() *apiKeyRepositoryStub {
+	return &apiKeyRepositoryStub{keys: map[int64]models.APIKey{}}
+}
+
+func (r *apiKeyRepositoryStub) FindAll(context.Context) ([]models.APIKey, error) {
+	keys := make([]models.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepositoryStub) FindByID(_ context.Context, id int64) (models.APIKey, error) {
+	key, ok := r.keys[id]
+	if !ok {
+		return models.APIKey{}, repository.ErrNotFound
+	}
+	return key, nil
+}
+
+func (r *apiKeyRepositoryStub) FindByPrefix(_ context.Context, prefix string) (models.APIKey, error) {
+	r.prefixLookups = append(r.prefixLookups, prefix)
+	if r.findErr != nil {
+		return models.APIKey{}, r.findErr
+	}
+	for _, key := range r.keys {
+		if key.Prefix == prefix {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, repository.ErrNotFound
+}
+
+func (r *apiKeyRepositoryStub) Create(_ context.Context, key models.APIKey) (models.APIKey, error) {
+	key.ID = int64(len(r.keys) + 1)
+	key.CreatedAt = time.Now()
+	r.keys[key.ID] = key
+	return key, nil
+}
+
+func (r *apiKeyRepositoryStub) Rotate(ctx context.Context, oldID int64, replacement models.APIKey, oldExpiresAt time.Time) (models.APIKey, error) {
+	old, ok := r.keys[oldID]
+	if !ok || old.RevokedAt.Valid {
+		return models.APIKey{}, repository.ErrNotFound
+	}
+	old.ExpiresAt = sql.NullTime{Time: oldExpiresAt, Valid: true}
+	r.keys[oldID] = old
+	replacement.RotatedFrom = sql.NullInt64{Int64: oldID, Valid: true}
+	return r.Create(ctx, replacement)
+}
+
+func (r *apiKeyRepositoryStub) Revoke(_ context.Context, id int64) error {
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt.Valid {
+		return repository.ErrNotFound
+	}
+	key.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	r.keys[id] = key
+	return nil
+}
+
+func (r *apiKeyRepositoryStub) TouchLastUsed(context.Context, int64) error {
+	return nil
+}
+
+// auditRepositoryStub records the appended entries
+type auditRepositoryStub struct {
+	repository.IAuditRepository
+	entries []models.AuditEntry
+}
+
+func (r *auditRepositoryStub) Append(_ context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	r.entries = append(r.entries, entry)
+	return entry, nil
+}
+
+// transactorStub runs fn without a transaction
+type transactorStub struct{}
+
+func (transactorStub) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// newTestAPIKeyService returns the service and its stores with a clock the test controls through now
+func newTestAPIKeyService(t *testing.T, rotationGrace time.Duration, now *time.Time) (*apiKeyService, *apiKeyRepositoryStub, *auditRepositoryStub) {
+	t.Helper()
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	keys, audit := newAPIKeyRepositoryStub(), &auditRepositoryStub{}
+	service := NewAPIKeyService(keys, audit, transactorStub{}, policy, rotationGrace).(*apiKeyService)
+	service.now = func() time.Time { return *now }
+	return service, keys, audit
+}
+
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var appErr errors.AppError
+	if !stderrors.As(err, &appErr) || appErr.StatusCode != status {
+		t.Errorf("error = %v, want status %d", err, status)
+	}
+}
+
+func TestAPIKeyServiceIssueStoresOnlyTheHash(t *testing.T) {
+	now := time.Now()
+	service, keys, audit := newTestAPIKeyService(t, time.Hour, &now)
+	admin := principalContext("", RoleAdmin)
+
+	issued, err := service.Issue(admin, models.IssueAPIKeyRequest{Name: "partner", OwnerEmail: "Partner@Example.com", Scopes: []string{RoleTeller}})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	prefix, secret, ok := parseKey(issued.Key)
+	if !ok || !strings.HasPrefix(issued.Key, "bk_") {
+		t.Fatalf("Issue() key %q is not bk_<prefix>_<secret>", issued.Key)
+	}
+	stored := keys.keys[issued.ID]
+	sum := sha256.Sum256([]byte(secret))
+	if stored.Prefix != prefix || stored.SecretHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("stored prefix %q hash %q, want %q and the SHA-256 of the secret", stored.Prefix, stored.SecretHash, prefix)
+	}
+	if strings.Contains(stored.SecretHash, secret) {
+		t.Error("the secret is stored in clear")
+	}
+	if stored.OwnerEmail.String != "partner@example.com" {
+		t.Errorf("stored owner = %q, want the normalized email", stored.OwnerEmail.String)
+	}
+	if len(audit.entries) != 1 || strings.Contains(string(audit.entries[0].After), secret) {
+		t.Errorf("audit entries = %d, want one entry without the secret", len(audit.entries))
+	}
+
+	_, err = service.Issue(admin, models.IssueAPIKeyRequest{Name: "partner", Scopes: []string{"root"}})
+	wantStatus(t, err, http.StatusBadRequest)
+	past := now.Add(-time.Minute)
+	_, err = service.Issue(admin, models.IssueAPIKeyRequest{Name: "partner", ExpiresAt: &past})
+	wantStatus(t, err, http.StatusBadRequest)
+	_, err = service.Issue(principalContext("", RoleTeller), models.IssueAPIKeyRequest{Name: "partner"})
+	wantStatus(t, err, http.StatusForbidden)
+}
+
+func TestAPIKeyServiceAuthenticate(t *testing.T) {
+	now := time.Now()
+	service, keys, _ := newTestAPIKeyService(t, time.Hour, &now)
+	admin := principalContext("", RoleAdmin)
+
+	expiresAt := now.Add(time.Hour)
+	issue := func(request models.IssueAPIKeyRequest) models.IssuedAPIKeyDTO {
+		issued, err := service.Issue(admin, request)
+		if err != nil {
+			t.Fatalf("Issue() error = %v", err)
+		}
+		return issued
+	}
+	active := issue(models.IssueAPIKeyRequest{Name: "active", OwnerEmail: "partner@example.com", Scopes: []string{RoleTeller, RoleAuditor}})
+	expiring := issue(models.IssueAPIKeyRequest{Name: "expiring", ExpiresAt: &expiresAt})
+	revoked := issue(models.IssueAPIKeyRequest{Name: "revoked"})
+	if err := service.Revoke(admin, revoked.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	activePrefix, activeSecret, _ := parseKey(active.Key)
+
+	tests := []struct {
+		name       string
+		key        string
+		at         time.Time
+		want       auth.Principal
+		wantErr    error
+		wantLookup bool
+	}{
+		{
+			name:       "active",
+			key:        active.Key,
+			at:         now,
+			want:       auth.Principal{Subject: auth.APIKeySubjectPrefix + activePrefix, Email: "partner@example.com", Roles: []string{RoleTeller, RoleAuditor}},
+			wantLookup: true,
+		},
+		{name: "wrong secret", key: "bk_" + activePrefix + "_" + strings.ToUpper(activeSecret), at: now, wantErr: auth.ErrInvalidAPIKey, wantLookup: true},
+		{name: "truncated secret", key: active.Key[:len(active.Key)-1], at: now, wantErr: auth.ErrInvalidAPIKey, wantLookup: true},
+		{name: "unknown prefix", key: "bk_000000000000_" + activeSecret, at: now, wantErr: auth.ErrInvalidAPIKey, wantLookup: true},
+		{name: "before expiry", key: expiring.Key, at: expiresAt.Add(-time.Second), want: auth.Principal{Subject: auth.APIKeySubjectPrefix + keys.keys[expiring.ID].Prefix}, wantLookup: true},
+		{name: "expired", key: expiring.Key, at: expiresAt, wantErr: auth.ErrInvalidAPIKey, wantLookup: true},
+		{name: "revoked", key: revoked.Key, at: now, wantErr: auth.ErrInvalidAPIKey, wantLookup: true},
+		{name: "other scheme", key: "sk_" + activePrefix + "_" + activeSecret, at: now, wantErr: auth.ErrInvalidAPIKey},
+		{name: "without secret", key: "bk_" + activePrefix + "_", at: now, wantErr: auth.ErrInvalidAPIKey},
+		{name: "without prefix", key: "bk__" + activeSecret, at: now, wantErr: auth.ErrInvalidAPIKey},
+		{name: "empty", key: "", at: now, wantErr: auth.ErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = tt.at
+			keys.prefixLookups = nil
+
+			got, err := service.Authenticate(context.Background(), tt.key)
+			if !stderrors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.Subject != tt.want.Subject || got.Email != tt.want.Email || strings.Join(got.Roles, ",") != strings.Join(tt.want.Roles, ",")) {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+			// Keys are looked up by their prefix only, the secret is compared against the stored hash
+			if gotLookup := len(keys.prefixLookups) > 0; gotLookup != tt.wantLookup {
+				t.Errorf("looked up %v, want a lookup: %v", keys.prefixLookups, tt.wantLookup)
+			}
+			if wantPrefix, _, _ := parseKey(tt.key); tt.wantLookup && keys.prefixLookups[0] != wantPrefix {
+				t.Errorf("looked up %q, want the prefix %q only", keys.prefixLookups[0], wantPrefix)
+			}
+		})
+	}
+
+	// Storage failures are not reported as invalid keys, they are server errors
+	keys.findErr = stderrors.New("connection refused")
+	if _, err := service.Authenticate(context.Background(), active.Key); err == nil || stderrors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with a failing repository error = %v, want the repository error", err)
+	}
+}
+
+func TestAPIKeyServiceRotate(t *testing.T) {
+	start := time.Now()
+	now := start
+	service, keys, audit := newTestAPIKeyService(t, time.Hour, &now)
+	admin := principalContext("", RoleAdmin)
+
+	old, err := service.Issue(admin, models.IssueAPIKeyRequest{Name: "partner", OwnerEmail: "partner@example.com", Scopes: []string{RoleTeller}})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	audit.entries = nil
+
+	replacement, err := service.Rotate(admin, old.ID)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if replacement.Key == old.Key || replacement.Name != "partner" || replacement.OwnerEmail != "partner@example.com" || replacement.Scopes[0] != RoleTeller {
+		t.Errorf("Rotate() = %+v, want a new key with the name, owner and scopes of the old one", replacement.APIKeyDTO)
+	}
+	if rotatedFrom := keys.keys[replacement.ID].RotatedFrom; rotatedFrom.Int64 != old.ID {
+		t.Errorf("replacement rotated from %d, want %d", rotatedFrom.Int64, old.ID)
+	}
+	if len(audit.entries) != 2 {
+		t.Errorf("audit entries = %d, want the update of the old key and the creation of the replacement", len(audit.entries))
+	}
+
+	tests := []struct {
+		name        string
+		at          time.Time
+		wantOld     bool
+		wantReplace bool
+	}{
+		{name: "right after rotating", at: start, wantOld: true, wantReplace: true},
+		{name: "within the grace period", at: start.Add(59 * time.Minute), wantOld: true, wantReplace: true},
+		{name: "grace period over", at: start.Add(time.Hour), wantOld: false, wantReplace: true},
+		{name: "long after", at: start.Add(24 * time.Hour), wantOld: false, wantReplace: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = tt.at
+			if _, err := service.Authenticate(context.Background(), old.Key); (err == nil) != tt.wantOld {
+				t.Errorf("Authenticate(old) error = %v, want accepted: %v", err, tt.wantOld)
+			}
+			if _, err := service.Authenticate(context.Background(), replacement.Key); (err == nil) != tt.wantReplace {
+				t.Errorf("Authenticate(replacement) error = %v, want accepted: %v", err, tt.wantReplace)
+			}
+		})
+	}
+
+	// The old key expired with the grace period and cannot be rotated again
+	now = start.Add(2 * time.Hour)
+	_, err = service.Rotate(admin, old.ID)
+	wantStatus(t, err, http.StatusConflict)
+	_, err = service.Rotate(admin, 999)
+	wantStatus(t, err, http.StatusNotFound)
+	_, err = service.Rotate(principalContext("partner@example.com", RoleCustomer), replacement.ID)
+	wantStatus(t, err, http.StatusForbidden)
+}
+
+func TestAPIKeyServiceRevoke(t *testing.T) {
+	now := time.Now()
+	service, _, audit := newTestAPIKeyService(t, time.Hour, &now)
+	admin := principalContext("", RoleAdmin)
+
+	issued, err := service.Issue(admin, models.IssueAPIKeyRequest{Name: "partner"})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	rotated, err := service.Rotate(admin, issued.ID)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	audit.entries = nil
+
+	// Revoking ends the grace period of a rotated key as well
+	for _, key := range []models.IssuedAPIKeyDTO{issued, rotated} {
+		if err := service.Revoke(admin, key.ID); err != nil {
+			t.Fatalf("Revoke(%d) error = %v", key.ID, err)
+		}
+		if _, err := service.Authenticate(context.Background(), key.Key); !stderrors.Is(err, auth.ErrInvalidAPIKey) {
+			t.Errorf("Authenticate() of revoked key %d error = %v, want %v", key.ID, err, auth.ErrInvalidAPIKey)
+		}
+	}
+	if len(audit.entries) != 2 {
+		t.Fatalf("audit entries = %d, want one per revocation", len(audit.entries))
+	}
+	if entry := audit.entries[0]; strings.Contains(string(entry.Before), "revoked_at") || !strings.Contains(string(entry.After), "revoked_at") {
+		t.Errorf("audit entry before %s after %s, want the revocation recorded", entry.Before, entry.After)
+	}
+
+	wantStatus(t, service.Revoke(admin, issued.ID), http.StatusNotFound)
+	wantStatus(t, service.Revoke(admin, 999), http.StatusNotFound)
+	wantStatus(t, service.Revoke(principalContext("", RoleAuditor), rotated.ID), http.StatusForbidden)
+}
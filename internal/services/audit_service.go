@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+)
+
+// systemActor records changes made without an authenticated principal
+const systemActor = "system"
+
+// Audit log page sizes
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type IAuditService interface {
+	Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntryDTO, error)
+}
+
+type auditService struct {
+	auditRepository repository.IAuditRepository
+	policy          IPolicy
+}
+
+// NewAuditService creates a read-only service over the audit log, authorizing every call with policy
+func NewAuditService(auditRepository repository.IAuditRepository, policy IPolicy) IAuditService {
+	return &auditService{
+		auditRepository: auditRepository,
+		policy:          policy,
+	}
+}
+
+// Find lists the audit entries matching filter, newest first
+func (s *auditService) Find(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntryDTO, error) {
+	access, err := s.policy.Authorize(ctx, ActionListAudit)
+	if err != nil {
+		return nil, err
+	}
+	if !access.All() {
+		return nil, errors.ForbiddenError(fmt.Sprintf("Not allowed to perform %s", ActionListAudit))
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	filter.Limit = min(filter.Limit, maxAuditLimit)
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, errors.BadRequestError("from must be before to")
+	}
+
+	entries, err := s.auditRepository.Find(ctx, filter)
+	if err != nil {
+		return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve audit log: %v", err))
+	}
+
+	entryDtos := make([]models.AuditEntryDTO, 0, len(entries))
+	for _, entry := range entries {
+		entryDtos = append(entryDtos, entry.ToAuditEntryDTO())
+	}
+	return entryDtos, nil
+}
+
+// recordChange appends an audit entry for a change made in ctx. Before and after are snapshots of the entity,
+// nil for creations and deletions respectively. It must be called inside the transaction of the change.
+func recordChange(ctx context.Context, auditRepository repository.IAuditRepository, action, entityType, entityID string, before, after any) error {
+	entry := models.AuditEntry{
+		Actor:      systemActor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		entry.Actor = principal.Subject
+	}
+	if request := logger.RequestFromContext(ctx); request != nil {
+		entry.RequestID, entry.ClientIP = request.ID, request.ClientIP
+	}
+
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		return errors.InternalServerError(fmt.Sprintf("Failed to snapshot %s %s: %v", entityType, entityID, err))
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		return errors.InternalServerError(fmt.Sprintf("Failed to snapshot %s %s: %v", entityType, entityID, err))
+	}
+
+	if _, err := auditRepository.Append(ctx, entry); err != nil {
+		return errors.InternalServerError(fmt.Sprintf("Failed to audit %s of %s %s: %v", action, entityType, entityID, err))
+	}
+	return nil
+}
+
+func snapshot(entity any) (json.RawMessage, error) {
+	if entity == nil {
+		return nil, nil
+	}
+	return json.Marshal(entity)
+}
+
+// withinTx runs fn in a transaction. Failures outside fn, such as a failed commit, are reported as internal errors.
+func withinTx(ctx context.Context, transactor repository.ITransactor, fn func(ctx context.Context) error) error {
+	err := transactor.WithinTx(ctx, fn)
+	var appErr errors.AppError
+	if err != nil && !stderrors.As(err, &appErr) {
+		return errors.InternalServerError(fmt.Sprintf("Failed to commit changes: %v", err))
+	}
+	return err
+}
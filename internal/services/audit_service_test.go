@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// auditLogStub records the appended entries and whether they were written inside a transaction
+type auditLogStub struct {
+	repository.IAuditRepository
+	entries   []models.AuditEntry
+	inTx      []bool
+	appendErr error
+	filter    *models.AuditFilter
+}
+
+func (r *auditLogStub) Append(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	if r.appendErr != nil {
+		return models.AuditEntry{}, r.appendErr
+	}
+	r.entries = append(r.entries, entry)
+	r.inTx = append(r.inTx, ctx.Value(testTxKey{}) != nil)
+	return entry, nil
+}
+
+func (r *auditLogStub) Find(_ context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	r.filter = &filter
+	return []models.AuditEntry{{ID: 1, Actor: "user-1", Action: models.AuditActionDelete}}, nil
+}
+
+type testTxKey struct{}
+
+// txTransactorStub marks the context of fn as transactional and records whether the transaction was rolled back
+type txTransactorStub struct {
+	rolledBack bool
+}
+
+func (t *txTransactorStub) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(context.WithValue(ctx, testTxKey{}, true))
+	t.rolledBack = err != nil
+	return err
+}
+
+// deletingCustomerRepositoryStub deletes customers from customerRepositoryStub
+type deletingCustomerRepositoryStub struct {
+	customerRepositoryStub
+}
+
+func (r deletingCustomerRepositoryStub) DeleteByEmail(_ context.Context, email string) error {
+	delete(r.customers, email)
+	return nil
+}
+
+type accountRepositoryStub struct {
+	repository.IAccountRepository
+	accounts []models.Account
+}
+
+func (r *accountRepositoryStub) FindByCustomerID(_ context.Context, customerID int64) ([]models.Account, error) {
+	var accounts []models.Account
+	for _, account := range r.accounts {
+		if account.CustomerID == customerID {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (r *accountRepositoryStub) DeleteByCustomerID(context.Context, int64) error {
+	return nil
+}
+
+type outboxRepositoryStub struct {
+	repository.IOutboxRepository
+	events []models.Event
+}
+
+func (r *outboxRepositoryStub) Add(_ context.Context, event models.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestAuditServiceFind(t *testing.T) {
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	auditor := principalContext("auditor@example.com", RoleAuditor)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		filter     models.AuditFilter
+		wantFilter models.AuditFilter
+		wantStatus int
+	}{
+		{name: "default limit", ctx: auditor, filter: models.AuditFilter{Actor: "user-1"}, wantFilter: models.AuditFilter{Actor: "user-1", Limit: 100}},
+		{name: "limit capped", ctx: auditor, filter: models.AuditFilter{Limit: 5000}, wantFilter: models.AuditFilter{Limit: 1000}},
+		{name: "period", ctx: auditor, filter: models.AuditFilter{From: from, To: from.Add(time.Hour), Limit: 10}, wantFilter: models.AuditFilter{From: from, To: from.Add(time.Hour), Limit: 10}},
+		{name: "empty period", ctx: auditor, filter: models.AuditFilter{From: from, To: from}, wantStatus: http.StatusBadRequest},
+		{name: "admin", ctx: principalContext("", RoleAdmin), wantFilter: models.AuditFilter{Limit: 100}},
+		{name: "teller", ctx: principalContext("", RoleTeller), wantStatus: http.StatusForbidden},
+		{name: "customer", ctx: principalContext("john.doe@example.com", RoleCustomer), wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &auditLogStub{}
+			entries, err := NewAuditService(audit, policy).Find(tt.ctx, tt.filter)
+			if tt.wantStatus != 0 {
+				wantStatus(t, err, tt.wantStatus)
+				if audit.filter != nil {
+					t.Error("Find() read the audit log of a rejected request")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Find() error = %v", err)
+			}
+			if !reflect.DeepEqual(*audit.filter, tt.wantFilter) {
+				t.Errorf("Find() filter = %+v, want %+v", *audit.filter, tt.wantFilter)
+			}
+			if len(entries) != 1 || entries[0].Actor != "user-1" {
+				t.Errorf("Find() = %+v, want the stored entry", entries)
+			}
+		})
+	}
+}
+
+func TestRecordChange(t *testing.T) {
+	customer := models.Customer{ID: 7, FirstName: "John", LastName: "Doe", Email: "john.doe@example.com"}
+	wantSnapshot := json.RawMessage(`{"id":7,"first_name":"John","last_name":"Doe","email":"john.doe@example.com","phone":""}`)
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		before, after any
+		want          models.AuditEntry
+	}{
+		{
+			name:  "creation by a principal in a request",
+			ctx:   logger.WithRequest(principalContext("", RoleTeller), "req-42", "/api/v1/customers/", "203.0.113.7", time.Now()),
+			after: customer,
+			want: models.AuditEntry{
+				Actor: "user-1", Action: models.AuditActionCreate, EntityType: models.AuditEntityCustomer, EntityID: customer.Email,
+				After:     wantSnapshot,
+				RequestID: "req-42", ClientIP: "203.0.113.7",
+			},
+		},
+		{
+			name:   "deletion by the system",
+			ctx:    context.Background(),
+			before: customer,
+			want: models.AuditEntry{
+				Actor: systemActor, Action: models.AuditActionDelete, EntityType: models.AuditEntityCustomer, EntityID: customer.Email,
+				Before: wantSnapshot,
+			},
+		},
+		{
+			name:  "principal without subject",
+			ctx:   auth.WithPrincipal(context.Background(), auth.Principal{Roles: []string{RoleAdmin}}),
+			after: customer,
+			want: models.AuditEntry{
+				Actor: systemActor, Action: models.AuditActionCreate, EntityType: models.AuditEntityCustomer, EntityID: customer.Email,
+				After: wantSnapshot,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := &auditLogStub{}
+			if err := recordChange(tt.ctx, audit, tt.want.Action, tt.want.EntityType, tt.want.EntityID, tt.before, tt.after); err != nil {
+				t.Fatalf("recordChange() error = %v", err)
+			}
+			if len(audit.entries) != 1 {
+				t.Fatalf("recordChange() appended %d entries, want 1", len(audit.entries))
+			}
+			got := audit.entries[0]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recordChange() appended\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+
+	if err := recordChange(context.Background(), &auditLogStub{}, models.AuditActionCreate, models.AuditEntityCustomer, "x", nil, func() {}); err == nil {
+		t.Error("recordChange() accepted a snapshot that cannot be encoded")
+	}
+}
+
+func TestDeleteCustomerAuditsInTheTransaction(t *testing.T) {
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	newCustomers := func() deletingCustomerRepositoryStub {
+		return deletingCustomerRepositoryStub{customerRepositoryStub{customers: map[string]models.Customer{
+			"john.doe@example.com": {ID: 1, FirstName: "John", LastName: "Doe", Email: "john.doe@example.com"},
+		}}}
+	}
+	accounts := &accountRepositoryStub{accounts: []models.Account{
+		{ID: 10, CustomerID: 1, AccountNumber: "ACC-10", Balance: 5},
+		{ID: 11, CustomerID: 1, AccountNumber: "ACC-11"},
+		{ID: 20, CustomerID: 2, AccountNumber: "ACC-20"},
+	}}
+	admin := principalContext("", RoleAdmin)
+
+	t.Run("deleted", func(t *testing.T) {
+		audit, outbox, transactor := &auditLogStub{}, &outboxRepositoryStub{}, &txTransactorStub{}
+		service := NewCustomerService(newCustomers(), accounts, audit, outbox, transactor, policy)
+
+		if err := service.DeleteCustomerByEmail(admin, "John.Doe@example.com"); err != nil {
+			t.Fatalf("DeleteCustomerByEmail() error = %v", err)
+		}
+
+		var deleted []string
+		for i, entry := range audit.entries {
+			if !audit.inTx[i] {
+				t.Errorf("audit entry of %s %s written outside the transaction", entry.EntityType, entry.EntityID)
+			}
+			if entry.Action != models.AuditActionDelete || entry.Actor != "user-1" || entry.Before == nil || entry.After != nil {
+				t.Errorf("audit entry %+v, want a deletion by user-1 with only a before snapshot", entry)
+			}
+			deleted = append(deleted, entry.EntityType+" "+entry.EntityID)
+		}
+		want := []string{"account ACC-10", "account ACC-11", "customer john.doe@example.com"}
+		if !reflect.DeepEqual(deleted, want) {
+			t.Errorf("audited %v, want %v", deleted, want)
+		}
+		if len(outbox.events) != 1 || outbox.events[0].Type != models.EventCustomerDeleted {
+			t.Errorf("recorded events %+v, want a CustomerDeleted event", outbox.events)
+		}
+	})
+
+	t.Run("audit failure rolls the deletion back", func(t *testing.T) {
+		audit := &auditLogStub{appendErr: stderrors.New("connection reset")}
+		outbox, transactor := &outboxRepositoryStub{}, &txTransactorStub{}
+		service := NewCustomerService(newCustomers(), accounts, audit, outbox, transactor, policy)
+
+		err := service.DeleteCustomerByEmail(admin, "john.doe@example.com")
+		wantStatus(t, err, http.StatusInternalServerError)
+		if !transactor.rolledBack {
+			t.Error("the transaction of an unaudited deletion was committed")
+		}
+		if len(outbox.events) != 0 {
+			t.Errorf("recorded events %+v for a failed deletion", outbox.events)
+		}
+	})
+}
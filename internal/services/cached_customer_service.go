@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"org/gg/banking/internal/cache"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/metrics"
+	"org/gg/banking/internal/models"
+	"slices"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// customerCacheName labels the metrics of the customer cache
+const customerCacheName = "customers"
+
+// cachedCustomer is a cached customer with accounts, together with the id recorded in the request log attributes
+type cachedCustomer struct {
+	customer   models.CustomerDTO
+	customerID int64
+}
+
+// cachedCustomerService answers customer lookups from an in-process LRU cache keyed by email
+type cachedCustomerService struct {
+	next   ICustomerService
+	policy IPolicy
+	cache  *cache.LRU[string, cachedCustomer]
+	// loads collapses concurrent misses for the same email into one lookup
+	loads singleflight.Group
+	// generation counts invalidations, a lookup that raced with one is not cached
+	generation atomic.Uint64
+}
+
+// NewCachedCustomerService decorates a customer service with a read-through cache of FindCustomerWithAccounts.
+// Creating or deleting a customer through it drops the cached entry. Hits are authorized with policy like the
+// lookups of next, so a cached customer is never served to a caller who could not read it.
+func NewCachedCustomerService(next ICustomerService, policy IPolicy, settings config.CacheSettings) ICustomerService {
+	return &cachedCustomerService{
+		next:   next,
+		policy: policy,
+		cache:  cache.NewLRU[string, cachedCustomer](settings.Size, settings.TTL),
+	}
+}
+
+func (s *cachedCustomerService) FindAll(ctx context.Context) ([]models.CustomerDTO, error) {
+	return s.next.FindAll(ctx)
+}
+
+func (s *cachedCustomerService) FindAllWithAccounts(ctx context.Context, page models.CustomerPage) ([]models.CustomerDTO, error) {
+	return s.next.FindAllWithAccounts(ctx, page)
+}
+
+// FindCustomerWithAccounts returns the cached customer, looking it up on a miss. Lookups that fail are not cached.
+func (s *cachedCustomerService) FindCustomerWithAccounts(ctx context.Context, email string) (models.CustomerDTO, error) {
+	email = models.NormalizeEmail(email)
+	if err := authorizeFor(ctx, s.policy, ActionReadCustomer, email); err != nil {
+		return models.CustomerDTO{}, err
+	}
+
+	cached, ok := s.cache.Get(email)
+	if ok {
+		metrics.CacheHits.WithLabelValues(customerCacheName).Inc()
+	} else {
+		metrics.CacheMisses.WithLabelValues(customerCacheName).Inc()
+		loaded, err, _ := s.loads.Do(email, func() (any, error) {
+			return s.load(ctx, email)
+		})
+		if err != nil {
+			return models.CustomerDTO{}, err
+		}
+		cached = loaded.(cachedCustomer)
+	}
+
+	logger.SetCustomerID(ctx, cached.customerID)
+	customer := cached.customer
+	// Callers must not be able to change the cached accounts
+	customer.Accounts = slices.Clone(customer.Accounts)
+	return customer, nil
+}
+
+// load looks a customer up and caches it unless it was invalidated meanwhile
+func (s *cachedCustomerService) load(ctx context.Context, email string) (cachedCustomer, error) {
+	generation := s.generation.Load()
+
+	// The lookup is shared with every caller waiting for email, the first one going away must not fail the others.
+	// A request context of its own captures the customer id the lookup records.
+	loadCtx := context.WithoutCancel(ctx)
+	if request := logger.RequestFromContext(ctx); request != nil {
+		loadCtx = logger.WithRequest(loadCtx, request.ID, request.Route, request.ClientIP, request.Start)
+	}
+
+	customer, err := s.next.FindCustomerWithAccounts(loadCtx, email)
+	if err != nil {
+		return cachedCustomer{}, err
+	}
+
+	loaded := cachedCustomer{customer: customer}
+	if request := logger.RequestFromContext(loadCtx); request != nil {
+		loaded.customerID = request.CustomerID()
+	}
+	if s.generation.Load() == generation && s.cache.Add(email, loaded) {
+		metrics.CacheEvictions.WithLabelValues(customerCacheName).Inc()
+	}
+	return loaded, nil
+}
+
+// CreateCustomer drops the cached lookup of the email
+func (s *cachedCustomerService) CreateCustomer(ctx context.Context, customer models.CustomerDTO) (models.CustomerDTO, error) {
+	// Invalidate even when creating failed, a failed commit may still have been applied
+	defer s.invalidate(models.NormalizeEmail(customer.Email))
+	return s.next.CreateCustomer(ctx, customer)
+}
+
+func (s *cachedCustomerService) DeleteCustomerByEmail(ctx context.Context, email string) error {
+	defer s.invalidate(models.NormalizeEmail(email))
+	return s.next.DeleteCustomerByEmail(ctx, email)
+}
+
+// invalidate drops the cached customer and keeps lookups in flight from caching or sharing what they read
+func (s *cachedCustomerService) invalidate(email string) {
+	s.generation.Add(1)
+	s.loads.Forget(email)
+	s.cache.Remove(email)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"org/gg/banking/internal/exports"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"slices"
+	"strings"
+)
+
+// ExportOpener creates the writer of an export once its columns are known. It is only called when the export is
+// allowed and valid, so a caller streaming over HTTP can still answer with an error until then.
+type ExportOpener func(columns []string) (exports.IWriter, error)
+
+type ICustomerExportService interface {
+	// Export writes the customers selected by request to the writer returned by open
+	Export(ctx context.Context, request models.CustomerExportRequest, open ExportOpener) error
+}
+
+type customerExportService struct {
+	customerRepository repository.ICustomerRepository
+	policy             IPolicy
+}
+
+// NewCustomerExportService creates a service streaming customer exports, authorizing every export with policy
+func NewCustomerExportService(customerRepository repository.ICustomerRepository, policy IPolicy) ICustomerExportService {
+	return &customerExportService{
+		customerRepository: customerRepository,
+		policy:             policy,
+	}
+}
+
+// Export streams the customers, with a row per account when request expands accounts, optionally a page of them.
+// Callers restricted to their own records only export themselves. Once the writer is opened a failure can only cut
+// the export short.
+func (s *customerExportService) Export(ctx context.Context, request models.CustomerExportRequest, open ExportOpener) error {
+	access, err := s.policy.Authorize(ctx, ActionExportCustomers)
+	if err != nil {
+		return err
+	}
+
+	filter := models.CustomerExportFilter{Email: access.Owner()}
+	if access.All() {
+		filter.Email = ""
+	}
+	switch request.Expand {
+	case "":
+	case "accounts":
+		filter.WithAccounts = true
+	default:
+		return errors.BadRequestError("expand must be accounts")
+	}
+	if request.Page.Limit < 0 || request.Page.Offset < 0 {
+		return errors.BadRequestError("limit and offset must not be negative")
+	}
+	filter.Page = request.Page
+	columns, err := exportColumns(request.Columns, filter.WithAccounts)
+	if err != nil {
+		return err
+	}
+
+	writer, err := open(columns)
+	if err != nil {
+		return errors.InternalServerError(fmt.Sprintf("Failed to start export: %v", err))
+	}
+	values := make([]any, len(columns))
+	err = s.customerRepository.Export(ctx, filter, func(row models.CustomerExportRow) error {
+		for i, column := range columns {
+			values[i] = row.Value(column)
+		}
+		return writer.Write(values)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return errors.InternalServerError(fmt.Sprintf("Export stopped: %v", err))
+	}
+	return nil
+}
+
+// exportColumns validates the requested columns, defaulting to every column available
+func exportColumns(requested []string, withAccounts bool) ([]string, error) {
+	available := models.CustomerExportColumns
+	if withAccounts {
+		available = slices.Concat(models.CustomerExportColumns, models.AccountExportColumns)
+	}
+	if len(requested) == 0 {
+		return available, nil
+	}
+
+	columns := make([]string, 0, len(requested))
+	for _, column := range requested {
+		column = strings.TrimSpace(column)
+		switch {
+		case slices.Contains(columns, column):
+			return nil, errors.BadRequestError(fmt.Sprintf("Column %s is selected twice", column))
+		case slices.Contains(available, column):
+			columns = append(columns, column)
+		case slices.Contains(models.AccountExportColumns, column):
+			return nil, errors.BadRequestError(fmt.Sprintf("Column %s needs expand=accounts", column))
+		default:
+			return nil, errors.BadRequestError(fmt.Sprintf("Unknown column %s, columns are %s", column, strings.Join(available, ", ")))
+		}
+	}
+	return columns, nil
+}
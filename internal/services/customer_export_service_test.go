@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/exports"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"reflect"
+	"testing"
+)
+
+// exportRepositoryStub records the filter of the export and exports nothing
+type exportRepositoryStub struct {
+	repository.ICustomerRepository
+	filter *models.CustomerExportFilter
+}
+
+func (r *exportRepositoryStub) Export(_ context.Context, filter models.CustomerExportFilter, _ func(row models.CustomerExportRow) error) error {
+	r.filter = &filter
+	return nil
+}
+
+// discardWriter accepts the exported rows
+type discardWriter struct{}
+
+func (discardWriter) Write([]any) error { return nil }
+func (discardWriter) Close() error      { return nil }
+
+func TestCustomerExportServiceFilter(t *testing.T) {
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	ownPolicy, err := NewRolePolicy(config.AuthorizationConfiguration{Policies: map[string]map[string][]string{
+		"customers": {"export": {"customer:own"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	auditor := principalContext("auditor@example.com", RoleAuditor)
+
+	tests := []struct {
+		name        string
+		policy      IPolicy
+		ctx         context.Context
+		request     models.CustomerExportRequest
+		wantFilter  models.CustomerExportFilter
+		wantColumns []string
+		wantStatus  int
+	}{
+		{
+			name:        "everything",
+			ctx:         auditor,
+			wantColumns: models.CustomerExportColumns,
+		},
+		{
+			name:        "page with accounts",
+			ctx:         auditor,
+			request:     models.CustomerExportRequest{Expand: "accounts", Columns: []string{"email", " balance"}, Page: models.CustomerPage{Limit: 100, Offset: 200}},
+			wantFilter:  models.CustomerExportFilter{WithAccounts: true, Page: models.CustomerPage{Limit: 100, Offset: 200}},
+			wantColumns: []string{"email", "balance"},
+		},
+		{
+			name:        "restricted to own records",
+			policy:      ownPolicy,
+			ctx:         principalContext("John.Doe@Example.com", RoleCustomer),
+			request:     models.CustomerExportRequest{Page: models.CustomerPage{Limit: 10}},
+			wantFilter:  models.CustomerExportFilter{Email: "john.doe@example.com", Page: models.CustomerPage{Limit: 10}},
+			wantColumns: models.CustomerExportColumns,
+		},
+		{name: "negative limit", ctx: auditor, request: models.CustomerExportRequest{Page: models.CustomerPage{Limit: -1}}, wantStatus: http.StatusBadRequest},
+		{name: "negative offset", ctx: auditor, request: models.CustomerExportRequest{Page: models.CustomerPage{Offset: -1}}, wantStatus: http.StatusBadRequest},
+		{name: "unknown expand", ctx: auditor, request: models.CustomerExportRequest{Expand: "movements"}, wantStatus: http.StatusBadRequest},
+		{name: "account column without accounts", ctx: auditor, request: models.CustomerExportRequest{Columns: []string{"balance"}}, wantStatus: http.StatusBadRequest},
+		{name: "duplicate column", ctx: auditor, request: models.CustomerExportRequest{Columns: []string{"email", "email"}}, wantStatus: http.StatusBadRequest},
+		{name: "teller", ctx: principalContext("", RoleTeller), wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customers := &exportRepositoryStub{}
+			service := NewCustomerExportService(customers, policy)
+			if tt.policy != nil {
+				service = NewCustomerExportService(customers, tt.policy)
+			}
+
+			var opened []string
+			err := service.Export(tt.ctx, tt.request, func(columns []string) (exports.IWriter, error) {
+				opened = columns
+				return discardWriter{}, nil
+			})
+			if tt.wantStatus != 0 {
+				wantStatus(t, err, tt.wantStatus)
+				if opened != nil || customers.filter != nil {
+					t.Error("Export() opened the writer of a rejected export")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export() error = %v", err)
+			}
+			if !reflect.DeepEqual(*customers.filter, tt.wantFilter) {
+				t.Errorf("Export() filter = %+v, want %+v", *customers.filter, tt.wantFilter)
+			}
+			if !reflect.DeepEqual(opened, tt.wantColumns) {
+				t.Errorf("Export() columns = %v, want %v", opened, tt.wantColumns)
+			}
+		})
+	}
+}
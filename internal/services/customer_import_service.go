@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/imports"
+	"org/gg/banking/internal/metrics"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"unicode/utf8"
+)
+
+// Column sizes of the customers table
+const (
+	maxNameLength  = 100
+	maxEmailLength = 100
+	maxPhoneLength = 20
+)
+
+type ICustomerImportService interface {
+	// Import creates the customers read from reader and reports the outcome of every row
+	Import(ctx context.Context, reader imports.IReader, dryRun bool) (models.CustomerImportReport, error)
+}
+
+type customerImportService struct {
+	customerRepository repository.ICustomerRepository
+	auditRepository    repository.IAuditRepository
+	outboxRepository   repository.IOutboxRepository
+	transactor         repository.ITransactor
+	policy             IPolicy
+	settings           config.ImportConfiguration
+}
+
+// NewCustomerImportService creates a service importing customers in batches, authorizing every import with policy.
+// Imports only add customers with new emails, so they never leave a cached lookup stale.
+func NewCustomerImportService(customerRepository repository.ICustomerRepository, auditRepository repository.IAuditRepository, outboxRepository repository.IOutboxRepository, transactor repository.ITransactor, policy IPolicy, settings config.ImportConfiguration) ICustomerImportService {
+	return &customerImportService{
+		customerRepository: customerRepository,
+		auditRepository:    auditRepository,
+		outboxRepository:   outboxRepository,
+		transactor:         transactor,
+		policy:             policy,
+		settings:           settings,
+	}
+}
+
+// pendingImport is a valid row waiting for its batch to be written
+type pendingImport struct {
+	// result is the index of the row in the report
+	result   int
+	customer models.Customer
+}
+
+// Import validates every row as it is read and writes the valid ones in batches, each in its own transaction with
+// the audit entries and events of the customers it creates. Rows whose email exists, or appeared earlier in the
+// file, are skipped. A dry run validates and checks the emails the same way without writing anything.
+// When the file cannot be read to the end the import stops, batches written before stay committed.
+func (s *customerImportService) Import(ctx context.Context, reader imports.IReader, dryRun bool) (models.CustomerImportReport, error) {
+	access, err := s.policy.Authorize(ctx, ActionImportCustomers)
+	if err != nil {
+		return models.CustomerImportReport{}, err
+	}
+	if !access.All() {
+		return models.CustomerImportReport{}, errors.ForbiddenError(fmt.Sprintf("Not allowed to perform %s", ActionImportCustomers))
+	}
+
+	report := models.CustomerImportReport{DryRun: dryRun, Rows: []models.CustomerImportRowDTO{}}
+	// seen maps every email read so far to its line
+	seen := make(map[string]int)
+	batch := make([]pendingImport, 0, s.settings.BatchSize)
+
+	for {
+		row, err := reader.Read()
+		if stderrors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return models.CustomerImportReport{}, errors.BadRequestError(fmt.Sprintf("Import stopped after %d rows, %d customers were created: %v", report.Total, report.Created, err))
+		}
+		if report.Total == s.settings.MaxRows {
+			return models.CustomerImportReport{}, errors.BadRequestError(fmt.Sprintf("Import stopped at line %d, imports are limited to %d rows, %d customers were created", row.Line, s.settings.MaxRows, report.Created))
+		}
+		report.Total++
+		row.Customer.Email = models.NormalizeEmail(row.Customer.Email)
+
+		result := models.CustomerImportRowDTO{Line: row.Line, Email: row.Customer.Email}
+		if row.Err == nil {
+			row.Err = validateImportedCustomer(row.Customer)
+		}
+		if row.Err != nil {
+			result.Status, result.Reason = models.ImportStatusFailed, row.Err.Error()
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+		if line, ok := seen[row.Customer.Email]; ok {
+			result.Status, result.Reason = models.ImportStatusSkipped, fmt.Sprintf("duplicate of line %d", line)
+			report.Rows = append(report.Rows, result)
+			continue
+		}
+		seen[row.Customer.Email] = row.Line
+
+		report.Rows = append(report.Rows, result)
+		batch = append(batch, pendingImport{result: len(report.Rows) - 1, customer: row.Customer.ToCustomer()})
+		if len(batch) == s.settings.BatchSize {
+			if err := s.importBatch(ctx, &report, batch); err != nil {
+				return models.CustomerImportReport{}, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.importBatch(ctx, &report, batch); err != nil {
+			return models.CustomerImportReport{}, err
+		}
+	}
+
+	for _, result := range report.Rows {
+		switch result.Status {
+		case models.ImportStatusSkipped:
+			report.Skipped++
+		case models.ImportStatusFailed:
+			report.Failed++
+		}
+	}
+	if !dryRun {
+		metrics.CustomersCreated.Add(float64(report.Created))
+		metrics.CustomerImportRows.WithLabelValues(models.ImportStatusCreated).Add(float64(report.Created))
+		metrics.CustomerImportRows.WithLabelValues(models.ImportStatusSkipped).Add(float64(report.Skipped))
+		metrics.CustomerImportRows.WithLabelValues(models.ImportStatusFailed).Add(float64(report.Failed))
+	}
+
+	return report, nil
+}
+
+// importBatch writes one batch and sets the status of its rows in report
+func (s *customerImportService) importBatch(ctx context.Context, report *models.CustomerImportReport, batch []pendingImport) error {
+	customers := make([]models.Customer, 0, len(batch))
+	for _, pending := range batch {
+		customers = append(customers, pending.customer)
+	}
+
+	var imported []models.Customer
+	err := withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		var err error
+		imported, err = s.customerRepository.ImportBatch(ctx, customers, report.DryRun)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Import stopped after %d rows, %d customers were created: %v", report.Total, report.Created, err))
+		}
+		if report.DryRun {
+			return nil
+		}
+
+		for _, customer := range imported {
+			if err := recordChange(ctx, s.auditRepository, models.AuditActionCreate, models.AuditEntityCustomer, customer.Email, nil, customer); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, s.outboxRepository, models.EventCustomerCreated, models.AggregateCustomer, customer.Email, customer.Email, models.CustomerCreatedPayload{
+				CustomerID: customer.ID,
+				Customer:   customer.ToCustomerDTO(),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	created := make(map[string]bool, len(imported))
+	for _, customer := range imported {
+		created[customer.Email] = true
+	}
+	for _, pending := range batch {
+		result := &report.Rows[pending.result]
+		if created[pending.customer.Email] {
+			result.Status = models.ImportStatusCreated
+			report.Created++
+		} else {
+			result.Status, result.Reason = models.ImportStatusSkipped, "customer already exists"
+		}
+	}
+	return nil
+}
+
+// validateImportedCustomer applies the rules of the customer payload and the column sizes of the customers table
+func validateImportedCustomer(customer models.CustomerDTO) error {
+	switch {
+	case customer.FirstName == "":
+		return stderrors.New("first_name is required")
+	case customer.LastName == "":
+		return stderrors.New("last_name is required")
+	case customer.Email == "":
+		return stderrors.New("email is required")
+	case utf8.RuneCountInString(customer.FirstName) > maxNameLength:
+		return fmt.Errorf("first_name is longer than %d characters", maxNameLength)
+	case utf8.RuneCountInString(customer.LastName) > maxNameLength:
+		return fmt.Errorf("last_name is longer than %d characters", maxNameLength)
+	case utf8.RuneCountInString(customer.Email) > maxEmailLength:
+		return fmt.Errorf("email is longer than %d characters", maxEmailLength)
+	case utf8.RuneCountInString(customer.Phone) > maxPhoneLength:
+		return fmt.Errorf("phone is longer than %d characters", maxPhoneLength)
+	}
+
+	// A bare address only, names such as "Jane <jane@example.com>" are rejected
+	address, err := mail.ParseAddress(customer.Email)
+	if err != nil || address.Address != customer.Email {
+		return stderrors.New("email is not a valid address")
+	}
+	return nil
+}
@@ -1,35 +1,65 @@
 package services
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/metrics"
 	"org/gg/banking/internal/middleware/errors"
 	"org/gg/banking/internal/models"
 	"org/gg/banking/internal/repository"
 )
 
 type ICustomerService interface {
-	FindAll() ([]models.CustomerDTO, error)
-	FindCustomerWithAccounts(email string) (models.CustomerDTO, error)
-	CreateCustomer(customer models.CustomerDTO) (models.CustomerDTO, error)
-	DeleteCustomerByEmail(email string) error // New method
+	FindAll(ctx context.Context) ([]models.CustomerDTO, error)
+	// FindAllWithAccounts lists a page of the customers FindAll lists, each with their accounts
+	FindAllWithAccounts(ctx context.Context, page models.CustomerPage) ([]models.CustomerDTO, error)
+	FindCustomerWithAccounts(ctx context.Context, email string) (models.CustomerDTO, error)
+	CreateCustomer(ctx context.Context, customer models.CustomerDTO) (models.CustomerDTO, error)
+	DeleteCustomerByEmail(ctx context.Context, email string) error // New method
 }
 
+// Page sizes of customers listed with their accounts
+const (
+	defaultCustomerPageLimit = 100
+	maxCustomerPageLimit     = 1000
+)
+
 type customerService struct {
 	customerRepository repository.ICustomerRepository
 	accountRepository  repository.IAccountRepository
+	auditRepository    repository.IAuditRepository
+	outboxRepository   repository.IOutboxRepository
+	transactor         repository.ITransactor
+	policy             IPolicy
 }
 
-// NewCustomerService creates a new service with the provided repository
-func NewCustomerService(customerRepository repository.ICustomerRepository, accountRepository repository.IAccountRepository) ICustomerService {
+// NewCustomerService creates a new service with the provided repositories, authorizing every call with policy.
+// Changes are audited and their domain events written to the outbox in the same transaction.
+func NewCustomerService(customerRepository repository.ICustomerRepository, accountRepository repository.IAccountRepository, auditRepository repository.IAuditRepository, outboxRepository repository.IOutboxRepository, transactor repository.ITransactor, policy IPolicy) ICustomerService {
 	return &customerService{
 		customerRepository: customerRepository,
 		accountRepository:  accountRepository,
+		auditRepository:    auditRepository,
+		outboxRepository:   outboxRepository,
+		transactor:         transactor,
+		policy:             policy,
 	}
 }
 
-// FindAll GetCustomers delegates to the repository layer
-func (s *customerService) FindAll() ([]models.CustomerDTO, error) {
-	customers, err := s.customerRepository.FindAll()
+// FindAll GetCustomers delegates to the repository layer.
+// Callers restricted to their own records only see themselves.
+func (s *customerService) FindAll(ctx context.Context) ([]models.CustomerDTO, error) {
+	access, err := s.policy.Authorize(ctx, ActionListCustomers)
+	if err != nil {
+		return nil, err
+	}
+	if !access.All() {
+		return s.findOwn(ctx, access.Owner())
+	}
+
+	customers, err := s.customerRepository.FindAll(ctx)
 	if err != nil {
 		// Transform technical errors to domain errors
 		return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve customers: %v", err))
@@ -49,67 +79,198 @@ func (s *customerService) FindAll() ([]models.CustomerDTO, error) {
 	return customerResponses, nil
 }
 
-// FindCustomerWithAccounts retrieves a CustomerDTO containing customer details and associated account information
-func (s *customerService) FindCustomerWithAccounts(email string) (models.CustomerDTO, error) {
-	customer, err := s.customerRepository.FindByEmail(email)
+// findOwn lists only the customer record of the principal
+func (s *customerService) findOwn(ctx context.Context, email string) ([]models.CustomerDTO, error) {
+	customer, err := s.customerRepository.FindByEmail(ctx, email)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, errors.NotFoundError("No customers found")
+	}
 	if err != nil {
-		return models.CustomerDTO{}, errors.NotFoundError(fmt.Sprintf("Customer with email %s not found: %v", email, err))
+		return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve customers: %v", err))
 	}
+	logger.SetCustomerID(ctx, customer.ID)
+
+	return []models.CustomerDTO{customer.ToCustomerDTO()}, nil
+}
 
-	accounts, err := s.accountRepository.FindByCustomerID(customer.ID)
+// FindAllWithAccounts loads a page of customers and their accounts in one query.
+// Callers restricted to their own records only see themselves, on the first page.
+func (s *customerService) FindAllWithAccounts(ctx context.Context, page models.CustomerPage) ([]models.CustomerDTO, error) {
+	access, err := s.policy.Authorize(ctx, ActionListCustomers)
 	if err != nil {
-		return models.CustomerDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to retrieve accounts for customer with email %s: %v", email, err))
+		return nil, err
+	}
+	if page.Limit <= 0 {
+		page.Limit = defaultCustomerPageLimit
+	}
+	page.Limit = min(page.Limit, maxCustomerPageLimit)
+	if page.Offset < 0 {
+		return nil, errors.BadRequestError("offset must not be negative")
 	}
 
-	var accountResponses []models.AccountDTO
-	for _, account := range accounts {
-		accountResponses = append(accountResponses, account.ToAccountDTO())
+	if !access.All() {
+		if page.Offset > 0 {
+			return []models.CustomerDTO{}, nil
+		}
+		customer, err := s.customerRepository.FindByEmailWithAccounts(ctx, access.Owner())
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, errors.NotFoundError("No customers found")
+		}
+		if err != nil {
+			return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve customers: %v", err))
+		}
+		logger.SetCustomerID(ctx, customer.Customer.ID)
+		return []models.CustomerDTO{customer.ToCustomerDTO()}, nil
+	}
+
+	customers, err := s.customerRepository.FindAllWithAccounts(ctx, page)
+	if err != nil {
+		return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve customers: %v", err))
+	}
+	if len(customers) == 0 && page.Offset == 0 {
+		return nil, errors.NotFoundError("No customers found")
 	}
 
-	return customer.ToCustomerDTO(accountResponses...), nil
+	customerResponses := make([]models.CustomerDTO, 0, len(customers))
+	for _, customer := range customers {
+		customerResponses = append(customerResponses, customer.ToCustomerDTO())
+	}
+	return customerResponses, nil
 }
 
-// CreateCustomer creates a new customer and their accounts inline within this method
-func (s *customerService) CreateCustomer(customerDto models.CustomerDTO) (models.CustomerDTO, error) {
-	createdCustomer, err := s.customerRepository.Create(customerDto.ToCustomer())
+// FindCustomerWithAccounts retrieves a CustomerDTO containing customer details and associated account information
+func (s *customerService) FindCustomerWithAccounts(ctx context.Context, email string) (models.CustomerDTO, error) {
+	email = models.NormalizeEmail(email)
+	// Authorize before the lookup so callers cannot probe which emails exist
+	if err := authorizeFor(ctx, s.policy, ActionReadCustomer, email); err != nil {
+		return models.CustomerDTO{}, err
+	}
+
+	customer, err := s.customerRepository.FindByEmailWithAccounts(ctx, email)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return models.CustomerDTO{}, errors.NotFoundError(fmt.Sprintf("Customer with email %s not found: %v", email, err))
+	}
 	if err != nil {
-		return models.CustomerDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to create customer: %v", err))
+		return models.CustomerDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to retrieve customer with email %s: %v", email, err))
+	}
+	logger.SetCustomerID(ctx, customer.Customer.ID)
+
+	return customer.ToCustomerDTO(), nil
+}
+
+// CreateCustomer creates a new customer and their accounts in one transaction
+func (s *customerService) CreateCustomer(ctx context.Context, customerDto models.CustomerDTO) (models.CustomerDTO, error) {
+	customerDto.Email = models.NormalizeEmail(customerDto.Email)
+	if err := authorizeFor(ctx, s.policy, ActionCreateCustomer, customerDto.Email); err != nil {
+		return models.CustomerDTO{}, err
 	}
 
+	var createdCustomer models.Customer
 	var createdAccountDtos []models.AccountDTO
-	if len(customerDto.Accounts) > 0 {
+	err := withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		var err error
+		createdCustomer, err = s.customerRepository.Create(ctx, customerDto.ToCustomer())
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to create customer: %v", err))
+		}
+		if err := recordChange(ctx, s.auditRepository, models.AuditActionCreate, models.AuditEntityCustomer, createdCustomer.Email, nil, createdCustomer); err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, s.outboxRepository, models.EventCustomerCreated, models.AggregateCustomer, createdCustomer.Email, createdCustomer.Email, models.CustomerCreatedPayload{
+			CustomerID: createdCustomer.ID,
+			Customer:   createdCustomer.ToCustomerDTO(),
+		}); err != nil {
+			return err
+		}
+
 		for _, accountDto := range customerDto.Accounts {
 			account := accountDto.ToAccount()
 			account.CustomerID = createdCustomer.ID
-			createdAccount, err := s.accountRepository.CreateAccount(createdCustomer.ID, account)
+			createdAccount, err := s.accountRepository.CreateAccount(ctx, createdCustomer.ID, account)
 			if err != nil {
-				return models.CustomerDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to create account for customer: %v", err))
+				return errors.InternalServerError(fmt.Sprintf("Failed to create account for customer: %v", err))
+			}
+			createdAccountDto := createdAccount.ToAccountDTO()
+			if err := recordChange(ctx, s.auditRepository, models.AuditActionCreate, models.AuditEntityAccount, createdAccount.AccountNumber, nil, createdAccountDto); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, s.outboxRepository, models.EventAccountOpened, models.AggregateAccount, createdAccount.AccountNumber, createdCustomer.Email, models.AccountPayload{
+				CustomerID: createdCustomer.ID,
+				Account:    createdAccountDto,
+			}); err != nil {
+				return err
 			}
-			createdAccountDtos = append(createdAccountDtos, createdAccount.ToAccountDTO())
+			createdAccountDtos = append(createdAccountDtos, createdAccountDto)
 		}
+		return nil
+	})
+	if err != nil {
+		return models.CustomerDTO{}, err
 	}
 
+	logger.SetCustomerID(ctx, createdCustomer.ID)
+	metrics.CustomersCreated.Inc()
+	metrics.AccountsOpened.Add(float64(len(createdAccountDtos)))
+
 	return createdCustomer.ToCustomerDTO(createdAccountDtos...), nil
 }
 
-// Implement the delete method
-func (s *customerService) DeleteCustomerByEmail(email string) error {
-	customer, err := s.customerRepository.FindByEmail(email)
-	if err != nil {
-		return errors.NotFoundError(fmt.Sprintf("Customer with email %s not found: %v", email, err))
+// DeleteCustomerByEmail deletes a customer and their accounts in one transaction
+func (s *customerService) DeleteCustomerByEmail(ctx context.Context, email string) error {
+	email = models.NormalizeEmail(email)
+	if err := authorizeFor(ctx, s.policy, ActionDeleteCustomer, email); err != nil {
+		return err
 	}
 
-	// Delete customer's accounts first (to maintain referential integrity)
-	err = s.accountRepository.DeleteByCustomerID(customer.ID)
-	if err != nil {
-		return errors.InternalServerError(fmt.Sprintf("Failed to delete accounts for customer with email %s: %v", email, err))
-	}
+	return withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		customer, err := s.customerRepository.FindByEmail(ctx, email)
+		if err != nil {
+			return errors.NotFoundError(fmt.Sprintf("Customer with email %s not found: %v", email, err))
+		}
+		logger.SetCustomerID(ctx, customer.ID)
+
+		accounts, err := s.accountRepository.FindByCustomerID(ctx, customer.ID)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to retrieve accounts for customer with email %s: %v", email, err))
+		}
 
-	// Then delete the customer
-	err = s.customerRepository.DeleteByEmail(email)
+		// Delete customer's accounts first (to maintain referential integrity)
+		err = s.accountRepository.DeleteByCustomerID(ctx, customer.ID)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to delete accounts for customer with email %s: %v", email, err))
+		}
+		accountNumbers := make([]string, 0, len(accounts))
+		for _, account := range accounts {
+			if err := recordChange(ctx, s.auditRepository, models.AuditActionDelete, models.AuditEntityAccount, account.AccountNumber, account.ToAccountDTO(), nil); err != nil {
+				return err
+			}
+			accountNumbers = append(accountNumbers, account.AccountNumber)
+		}
+
+		// Then delete the customer
+		err = s.customerRepository.DeleteByEmail(ctx, email)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to delete customer with email %s: %v", email, err))
+		}
+		if err := recordChange(ctx, s.auditRepository, models.AuditActionDelete, models.AuditEntityCustomer, customer.Email, customer, nil); err != nil {
+			return err
+		}
+		return recordEvent(ctx, s.outboxRepository, models.EventCustomerDeleted, models.AggregateCustomer, customer.Email, customer.Email, models.CustomerDeletedPayload{
+			CustomerID:     customer.ID,
+			Email:          customer.Email,
+			AccountNumbers: accountNumbers,
+		})
+	})
+}
+
+// authorizeFor checks that the principal may perform action on the customer with the given email
+func authorizeFor(ctx context.Context, policy IPolicy, action, email string) error {
+	access, err := policy.Authorize(ctx, action)
 	if err != nil {
-		return errors.InternalServerError(fmt.Sprintf("Failed to delete customer with email %s: %v", email, err))
+		return err
+	}
+	if !access.Allows(email) {
+		return errors.ForbiddenError(fmt.Sprintf("Not allowed to perform %s for customer %s", action, email))
 	}
-
 	return nil
 }
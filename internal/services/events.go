@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"org/gg/banking/internal/config/logger"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+)
+
+// recordEvent writes a domain event about the customer with customerEmail to the outbox. It must be called inside
+// the transaction of the change, so the event is published exactly when the change commits.
+func recordEvent(ctx context.Context, outboxRepository repository.IOutboxRepository, eventType, aggregateType, aggregateID, customerEmail string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.InternalServerError(fmt.Sprintf("Failed to encode %s event: %v", eventType, err))
+	}
+
+	event := models.Event{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		RequestID:     logger.RequestID(ctx),
+		Payload:       body,
+		CustomerEmail: customerEmail,
+	}
+	if err := outboxRepository.Add(ctx, event); err != nil {
+		return errors.InternalServerError(fmt.Sprintf("Failed to record %s event for %s %s: %v", eventType, aggregateType, aggregateID, err))
+	}
+	return nil
+}
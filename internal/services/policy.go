@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Roles a principal can hold
+const (
+	RoleCustomer = "customer"
+	RoleTeller   = "teller"
+	RoleAdmin    = "admin"
+	RoleAuditor  = "auditor"
+)
+
+// Actions guarded by the policy, named resource.verb as in the authorization configuration
+const (
+	ActionListCustomers   = "customers.list"
+	ActionReadCustomer    = "customers.read"
+	ActionCreateCustomer  = "customers.create"
+	ActionDeleteCustomer  = "customers.delete"
+	ActionImportCustomers = "customers.import"
+	ActionExportCustomers = "customers.export"
+	ActionReadStatement   = "accounts.statements"
+	ActionListAPIKeys     = "api_keys.list"
+	ActionIssueAPIKey     = "api_keys.issue"
+	ActionRotateAPIKey    = "api_keys.rotate"
+	ActionRevokeAPIKey    = "api_keys.revoke"
+	ActionListAudit       = "audit.list"
+	ActionListWebhooks    = "webhooks.list"
+	ActionReadWebhook     = "webhooks.read"
+	ActionCreateWebhook   = "webhooks.create"
+	ActionDeleteWebhook   = "webhooks.delete"
+	ActionRetryWebhook    = "webhooks.retry"
+)
+
+// ownSuffix restricts a role to the principal's own records, e.g. customer:own
+const ownSuffix = ":own"
+
+var knownRoles = []string{RoleCustomer, RoleTeller, RoleAdmin, RoleAuditor}
+
+// Access is the outcome of an authorization decision: either every record or only the principal's own
+type Access struct {
+	all   bool
+	owner string
+}
+
+// All reports whether access is not restricted to the principal's own records
+func (a Access) All() bool {
+	return a.all
+}
+
+// Owner returns the normalized email of the principal when access is restricted to its own records
+func (a Access) Owner() string {
+	return a.owner
+}
+
+// Allows reports whether the record owned by the customer with the given email may be accessed
+func (a Access) Allows(ownerEmail string) bool {
+	return a.all || (a.owner != "" && a.owner == models.NormalizeEmail(ownerEmail))
+}
+
+// IPolicy decides which actions the principal of a request may perform
+type IPolicy interface {
+	// Authorize returns the access granted for action, or a ForbiddenError when none is
+	Authorize(ctx context.Context, action string) (Access, error)
+}
+
+// RolePolicy grants actions to roles as declared in the authorization configuration.
+// Its rules can be replaced at runtime with Update.
+type RolePolicy struct {
+	rules atomic.Pointer[map[string][]roleGrant]
+}
+
+type roleGrant struct {
+	role string
+	own  bool
+}
+
+// NewRolePolicy compiles the authorization configuration
+func NewRolePolicy(authorizationConfig config.AuthorizationConfiguration) (*RolePolicy, error) {
+	policy := &RolePolicy{}
+	if err := policy.Update(authorizationConfig); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Update atomically replaces the policy rules. The previous rules stay active if the configuration is invalid.
+func (p *RolePolicy) Update(authorizationConfig config.AuthorizationConfiguration) error {
+	rules := map[string][]roleGrant{}
+	for resource, verbs := range authorizationConfig.Policies {
+		for verb, roles := range verbs {
+			action := strings.ToLower(resource + "." + verb)
+			for _, role := range roles {
+				grant := roleGrant{role: strings.ToLower(strings.TrimSuffix(role, ownSuffix)), own: strings.HasSuffix(role, ownSuffix)}
+				if !slices.Contains(knownRoles, grant.role) {
+					return fmt.Errorf("unknown role %q in policy %s, must be one of %s", role, action, strings.Join(knownRoles, ", "))
+				}
+				rules[action] = append(rules[action], grant)
+			}
+		}
+	}
+
+	p.rules.Store(&rules)
+	return nil
+}
+
+// Authorize grants full access if any role of the principal is allowed without restriction,
+// and own-record access if a role is only allowed on the principal's own records
+func (p *RolePolicy) Authorize(ctx context.Context, action string) (Access, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return Access{}, errors.ForbiddenError("Authentication is required")
+	}
+
+	access := Access{}
+	for _, grant := range (*p.rules.Load())[action] {
+		if !principal.HasRole(grant.role) {
+			continue
+		}
+		if !grant.own {
+			return Access{all: true}, nil
+		}
+		if principal.Email != "" {
+			access.owner = models.NormalizeEmail(principal.Email)
+		}
+	}
+
+	if access.owner == "" {
+		return Access{}, errors.ForbiddenError(fmt.Sprintf("Not allowed to perform %s", action))
+	}
+	return access, nil
+}
+
+// allowAllPolicy grants every action, for trusted callers such as the operator CLI
+type allowAllPolicy struct{}
+
+// NewAllowAllPolicy creates a policy that authorizes everything
+func NewAllowAllPolicy() IPolicy {
+	return allowAllPolicy{}
+}
+
+func (allowAllPolicy) Authorize(context.Context, string) (Access, error) {
+	return Access{all: true}, nil
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"testing"
+)
+
+// testPolicies mirrors the policies of configs/config.yml
+var testPolicies = config.AuthorizationConfiguration{Policies: map[string]map[string][]string{
+	"customers": {
+		"list":   {"admin", "teller", "auditor", "customer:own"},
+		"read":   {"admin", "teller", "auditor", "customer:own"},
+		"create": {"admin", "teller"},
+		"delete": {"admin"},
+		"import": {"admin"},
+		"export": {"admin", "auditor"},
+	},
+	"api_keys": {"list": {"admin"}, "issue": {"admin"}, "rotate": {"admin"}, "revoke": {"admin"}},
+	"audit":    {"list": {"admin", "auditor"}},
+	"accounts": {"statements": {"admin", "teller", "auditor", "customer:own"}},
+	"webhooks": {"list": {"admin"}, "read": {"admin"}, "create": {"admin"}, "delete": {"admin"}, "retry": {"admin"}},
+}}
+
+func principalContext(email string, roles ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{Subject: "user-1", Email: email, Roles: roles})
+}
+
+func TestRolePolicyAuthorize(t *testing.T) {
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+
+	const (
+		all       = "all"
+		own       = "own"
+		forbidden = "forbidden"
+	)
+	roles := []string{RoleAdmin, RoleTeller, RoleAuditor, RoleCustomer}
+	// want lists the access of admin, teller, auditor and customer, in that order
+	tests := []struct {
+		action string
+		want   [4]string
+	}{
+		{action: ActionListCustomers, want: [4]string{all, all, all, own}},
+		{action: ActionReadCustomer, want: [4]string{all, all, all, own}},
+		{action: ActionCreateCustomer, want: [4]string{all, all, forbidden, forbidden}},
+		{action: ActionDeleteCustomer, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionImportCustomers, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionExportCustomers, want: [4]string{all, forbidden, all, forbidden}},
+		{action: ActionReadStatement, want: [4]string{all, all, all, own}},
+		{action: ActionListAPIKeys, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionIssueAPIKey, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionRotateAPIKey, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionRevokeAPIKey, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionListAudit, want: [4]string{all, forbidden, all, forbidden}},
+		{action: ActionListWebhooks, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionReadWebhook, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionCreateWebhook, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionDeleteWebhook, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: ActionRetryWebhook, want: [4]string{all, forbidden, forbidden, forbidden}},
+		{action: "customers.unknown", want: [4]string{forbidden, forbidden, forbidden, forbidden}},
+	}
+	for _, tt := range tests {
+		for i, role := range roles {
+			t.Run(tt.action+"/"+role, func(t *testing.T) {
+				access, err := policy.Authorize(principalContext("john.doe@example.com", role), tt.action)
+
+				got := forbidden
+				switch {
+				case err != nil:
+					var appErr errors.AppError
+					if !stderrors.As(err, &appErr) || appErr.StatusCode != http.StatusForbidden {
+						t.Fatalf("Authorize() error = %v, want a forbidden error", err)
+					}
+				case access.All():
+					got = all
+				case access.Owner() == "john.doe@example.com":
+					got = own
+				}
+				if got != tt.want[i] {
+					t.Errorf("Authorize() = %s, want %s", got, tt.want[i])
+				}
+			})
+		}
+	}
+}
+
+func TestRolePolicyOwnAccess(t *testing.T) {
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantErr   bool
+		wantAll   bool
+		wantOwner string
+		allows    map[string]bool
+	}{
+		{
+			name:      "own records",
+			ctx:       principalContext("john.doe@example.com", RoleCustomer),
+			wantOwner: "john.doe@example.com",
+			allows:    map[string]bool{"john.doe@example.com": true, "jane.doe@example.com": false, "": false},
+		},
+		{
+			name:      "token email in mixed case",
+			ctx:       principalContext("John.Doe@Example.COM", RoleCustomer),
+			wantOwner: "john.doe@example.com",
+			allows:    map[string]bool{"john.doe@example.com": true, "JOHN.DOE@example.com": true, "jane.doe@example.com": false},
+		},
+		{
+			name:    "unrestricted role wins over an own role",
+			ctx:     principalContext("john.doe@example.com", RoleCustomer, RoleTeller),
+			wantAll: true,
+			allows:  map[string]bool{"jane.doe@example.com": true},
+		},
+		{name: "own role without email", ctx: principalContext("", RoleCustomer), wantErr: true},
+		{name: "no roles", ctx: principalContext("john.doe@example.com"), wantErr: true},
+		{name: "unknown role", ctx: principalContext("john.doe@example.com", "superuser"), wantErr: true},
+		{name: "unauthenticated", ctx: context.Background(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access, err := policy.Authorize(tt.ctx, ActionReadCustomer)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Authorize() = %+v, want an error", access)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authorize() error = %v", err)
+			}
+			if access.All() != tt.wantAll || access.Owner() != tt.wantOwner {
+				t.Errorf("Authorize() = all %v owner %q, want all %v owner %q", access.All(), access.Owner(), tt.wantAll, tt.wantOwner)
+			}
+			for email, want := range tt.allows {
+				if got := access.Allows(email); got != want {
+					t.Errorf("Allows(%q) = %v, want %v", email, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRolePolicyUpdate(t *testing.T) {
+	policy, err := NewRolePolicy(config.AuthorizationConfiguration{Policies: map[string]map[string][]string{
+		"Customers": {"Read": {"Teller"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	ctx := principalContext("john.doe@example.com", RoleTeller)
+	if _, err := policy.Authorize(ctx, ActionReadCustomer); err != nil {
+		t.Fatalf("Authorize() with mixed case policy names error = %v", err)
+	}
+
+	err = policy.Update(config.AuthorizationConfiguration{Policies: map[string]map[string][]string{
+		"customers": {"read": {"admin", "clerk"}},
+	}})
+	if err == nil {
+		t.Fatal("Update() accepted an unknown role")
+	}
+	if _, err := policy.Authorize(ctx, ActionReadCustomer); err != nil {
+		t.Errorf("Authorize() after a rejected update error = %v, want the previous rules", err)
+	}
+
+	if err := policy.Update(config.AuthorizationConfiguration{Policies: map[string]map[string][]string{
+		"customers": {"read": {"admin"}},
+	}}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := policy.Authorize(ctx, ActionReadCustomer); err == nil {
+		t.Error("Authorize() still granted after the teller role was removed")
+	}
+}
+
+// customerRepositoryStub finds customers by exact email, like the database does
+type customerRepositoryStub struct {
+	repository.ICustomerRepository
+	customers map[string]models.Customer
+}
+
+func (r customerRepositoryStub) FindByEmail(_ context.Context, email string) (models.Customer, error) {
+	customer, ok := r.customers[email]
+	if !ok {
+		return models.Customer{}, repository.ErrNotFound
+	}
+	return customer, nil
+}
+
+func (r customerRepositoryStub) FindByID(_ context.Context, id int64) (models.Customer, error) {
+	for _, customer := range r.customers {
+		if customer.ID == id {
+			return customer, nil
+		}
+	}
+	return models.Customer{}, repository.ErrNotFound
+}
+
+func (r customerRepositoryStub) FindByEmailWithAccounts(ctx context.Context, email string) (models.CustomerWithAccounts, error) {
+	customer, err := r.FindByEmail(ctx, email)
+	return models.CustomerWithAccounts{Customer: customer}, err
+}
+
+func TestCustomerServiceNormalizesEmails(t *testing.T) {
+	policy, err := NewRolePolicy(testPolicies)
+	if err != nil {
+		t.Fatalf("NewRolePolicy: %v", err)
+	}
+	customers := customerRepositoryStub{customers: map[string]models.Customer{
+		"john.doe@example.com": {ID: 1, FirstName: "John", LastName: "Doe", Email: "john.doe@example.com"},
+	}}
+	service := NewCustomerService(customers, nil, nil, nil, nil, policy)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		email      string
+		wantStatus int
+	}{
+		{name: "owner", ctx: principalContext("john.doe@example.com", RoleCustomer), email: "john.doe@example.com"},
+		{name: "owner with mixed case token", ctx: principalContext("John.Doe@Example.com", RoleCustomer), email: "john.doe@example.com"},
+		{name: "owner with mixed case path", ctx: principalContext("john.doe@example.com", RoleCustomer), email: "JOHN.DOE@example.com"},
+		{name: "teller with mixed case path", ctx: principalContext("", RoleTeller), email: "John.Doe@Example.com"},
+		{name: "another customer", ctx: principalContext("jane.doe@example.com", RoleCustomer), email: "john.doe@example.com", wantStatus: http.StatusForbidden},
+		{name: "unknown", ctx: principalContext("", RoleTeller), email: "jane.doe@example.com", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer, err := service.FindCustomerWithAccounts(tt.ctx, tt.email)
+			if tt.wantStatus != 0 {
+				var appErr errors.AppError
+				if !stderrors.As(err, &appErr) || appErr.StatusCode != tt.wantStatus {
+					t.Errorf("FindCustomerWithAccounts() error = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindCustomerWithAccounts() error = %v", err)
+			}
+			if customer.Email != "john.doe@example.com" {
+				t.Errorf("FindCustomerWithAccounts() = %s, want john.doe@example.com", customer.Email)
+			}
+		})
+	}
+
+	ownCustomers, err := service.FindAllWithAccounts(principalContext("John.Doe@EXAMPLE.com", RoleCustomer), models.CustomerPage{})
+	if err != nil || len(ownCustomers) != 1 {
+		t.Errorf("FindAllWithAccounts() for a mixed case token = %v, %v, want the owner's record", ownCustomers, err)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/imports"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/tracing"
+)
+
+// tracedCustomerService wraps every customer service call in a span
+type tracedCustomerService struct {
+	next ICustomerService
+}
+
+// NewTracedCustomerService decorates a customer service with tracing
+func NewTracedCustomerService(next ICustomerService) ICustomerService {
+	return &tracedCustomerService{next: next}
+}
+
+func (s *tracedCustomerService) FindAll(ctx context.Context) (customers []models.CustomerDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CustomerService.FindAll")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.FindAll(ctx)
+}
+
+func (s *tracedCustomerService) FindAllWithAccounts(ctx context.Context, page models.CustomerPage) (customers []models.CustomerDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CustomerService.FindAllWithAccounts")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.FindAllWithAccounts(ctx, page)
+}
+
+func (s *tracedCustomerService) FindCustomerWithAccounts(ctx context.Context, email string) (customer models.CustomerDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CustomerService.FindCustomerWithAccounts")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.FindCustomerWithAccounts(ctx, email)
+}
+
+func (s *tracedCustomerService) CreateCustomer(ctx context.Context, customer models.CustomerDTO) (created models.CustomerDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CustomerService.CreateCustomer")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.CreateCustomer(ctx, customer)
+}
+
+func (s *tracedCustomerService) DeleteCustomerByEmail(ctx context.Context, email string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CustomerService.DeleteCustomerByEmail")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.DeleteCustomerByEmail(ctx, email)
+}
+
+// tracedCustomerImportService wraps every customer import in a span
+type tracedCustomerImportService struct {
+	next ICustomerImportService
+}
+
+// NewTracedCustomerImportService decorates a customer import service with tracing
+func NewTracedCustomerImportService(next ICustomerImportService) ICustomerImportService {
+	return &tracedCustomerImportService{next: next}
+}
+
+func (s *tracedCustomerImportService) Import(ctx context.Context, reader imports.IReader, dryRun bool) (report models.CustomerImportReport, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CustomerImportService.Import")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Import(ctx, reader, dryRun)
+}
+
+// tracedCustomerExportService wraps every customer export in a span
+type tracedCustomerExportService struct {
+	next ICustomerExportService
+}
+
+// NewTracedCustomerExportService decorates a customer export service with tracing
+func NewTracedCustomerExportService(next ICustomerExportService) ICustomerExportService {
+	return &tracedCustomerExportService{next: next}
+}
+
+func (s *tracedCustomerExportService) Export(ctx context.Context, request models.CustomerExportRequest, open ExportOpener) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "CustomerExportService.Export")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Export(ctx, request, open)
+}
+
+// tracedAccountStatementService wraps every account statement in a span
+type tracedAccountStatementService struct {
+	next IAccountStatementService
+}
+
+// NewTracedAccountStatementService decorates an account statement service with tracing
+func NewTracedAccountStatementService(next IAccountStatementService) IAccountStatementService {
+	return &tracedAccountStatementService{next: next}
+}
+
+func (s *tracedAccountStatementService) Statement(ctx context.Context, request models.AccountStatementRequest) (statement models.AccountStatement, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AccountStatementService.Statement")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Statement(ctx, request)
+}
+
+// tracedAccountService wraps every account service call in a span
+type tracedAccountService struct {
+	next IAccountService
+}
+
+// NewTracedAccountService decorates an account service with tracing
+func NewTracedAccountService(next IAccountService) IAccountService {
+	return &tracedAccountService{next: next}
+}
+
+func (s *tracedAccountService) CloseAccount(ctx context.Context, accountNumber string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AccountService.CloseAccount")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.CloseAccount(ctx, accountNumber)
+}
+
+// tracedAPIKeyService wraps every api key service call in a span
+type tracedAPIKeyService struct {
+	next IAPIKeyService
+}
+
+// NewTracedAPIKeyService decorates an api key service with tracing
+func NewTracedAPIKeyService(next IAPIKeyService) IAPIKeyService {
+	return &tracedAPIKeyService{next: next}
+}
+
+func (s *tracedAPIKeyService) List(ctx context.Context) (keys []models.APIKeyDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.List")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.List(ctx)
+}
+
+func (s *tracedAPIKeyService) Issue(ctx context.Context, request models.IssueAPIKeyRequest) (issued models.IssuedAPIKeyDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.Issue")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Issue(ctx, request)
+}
+
+func (s *tracedAPIKeyService) Rotate(ctx context.Context, id int64) (issued models.IssuedAPIKeyDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.Rotate")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Rotate(ctx, id)
+}
+
+func (s *tracedAPIKeyService) Revoke(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.Revoke")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Revoke(ctx, id)
+}
+
+func (s *tracedAPIKeyService) Authenticate(ctx context.Context, key string) (principal auth.Principal, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIKeyService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Authenticate(ctx, key)
+}
+
+// tracedAuditService wraps every audit service call in a span
+type tracedAuditService struct {
+	next IAuditService
+}
+
+// NewTracedAuditService decorates an audit service with tracing
+func NewTracedAuditService(next IAuditService) IAuditService {
+	return &tracedAuditService{next: next}
+}
+
+func (s *tracedAuditService) Find(ctx context.Context, filter models.AuditFilter) (entries []models.AuditEntryDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuditService.Find")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Find(ctx, filter)
+}
+
+// tracedWebhookService wraps every webhook service call in a span
+type tracedWebhookService struct {
+	next IWebhookService
+}
+
+// NewTracedWebhookService decorates a webhook service with tracing
+func NewTracedWebhookService(next IWebhookService) IWebhookService {
+	return &tracedWebhookService{next: next}
+}
+
+func (s *tracedWebhookService) List(ctx context.Context) (subscriptions []models.WebhookSubscriptionDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.List")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.List(ctx)
+}
+
+func (s *tracedWebhookService) Create(ctx context.Context, request models.CreateWebhookSubscriptionRequest) (created models.CreatedWebhookSubscriptionDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.Create")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Create(ctx, request)
+}
+
+func (s *tracedWebhookService) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.Delete")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Delete(ctx, id)
+}
+
+func (s *tracedWebhookService) Deliveries(ctx context.Context, filter models.WebhookDeliveryFilter) (deliveries []models.WebhookDeliveryDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.Deliveries")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Deliveries(ctx, filter)
+}
+
+func (s *tracedWebhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID int64) (delivery models.WebhookDeliveryDTO, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WebhookService.Redeliver")
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.Redeliver(ctx, subscriptionID, deliveryID)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/tracing"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// queryingCustomerService runs a traced query for every lookup, as the repositories do
+type queryingCustomerService struct {
+	ICustomerService
+}
+
+func (queryingCustomerService) FindCustomerWithAccounts(ctx context.Context, email string) (models.CustomerDTO, error) {
+	_, span := tracing.Tracer().Start(ctx, "customers.FindByEmailWithAccounts")
+	span.End()
+	if email != "john.doe@example.com" {
+		return models.CustomerDTO{}, errors.NotFoundError("customer not found")
+	}
+	return models.CustomerDTO{Email: email}, nil
+}
+
+func TestTracedCustomerService(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	service := NewTracedCustomerService(queryingCustomerService{})
+
+	tests := []struct {
+		email      string
+		wantStatus codes.Code
+	}{
+		{email: "john.doe@example.com", wantStatus: codes.Unset},
+		{email: "jane.doe@example.com", wantStatus: codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			recorder.Reset()
+			_, _ = service.FindCustomerWithAccounts(context.Background(), tt.email)
+
+			spans := recorder.Ended()
+			if len(spans) != 2 {
+				t.Fatalf("got %d spans, want the query and the service call", len(spans))
+			}
+			query, call := spans[0], spans[1]
+			if call.Name() != "CustomerService.FindCustomerWithAccounts" || query.Parent().SpanID() != call.SpanContext().SpanID() {
+				t.Errorf("query span %s has parent %s, want the service span %s", query.Name(), query.Parent().SpanID(), call.SpanContext().SpanID())
+			}
+			if call.Status().Code != tt.wantStatus {
+				t.Errorf("service span status %v, want %v", call.Status().Code, tt.wantStatus)
+			}
+			if tt.wantStatus == codes.Error && len(call.Events()) == 0 {
+				t.Error("service span did not record the error")
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"org/gg/banking/internal/auth"
+	"org/gg/banking/internal/middleware/errors"
+	"org/gg/banking/internal/models"
+	"org/gg/banking/internal/repository"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// webhookSecretPrefix marks webhook signing secrets, handed out as whsec_<secret>
+const webhookSecretPrefix = "whsec_"
+
+// Delivery history page sizes
+const (
+	defaultDeliveryLimit = 100
+	maxDeliveryLimit     = 1000
+)
+
+type IWebhookService interface {
+	List(ctx context.Context) ([]models.WebhookSubscriptionDTO, error)
+	// Create registers a subscription and returns its signing secret, which cannot be retrieved again
+	Create(ctx context.Context, request models.CreateWebhookSubscriptionRequest) (models.CreatedWebhookSubscriptionDTO, error)
+	Delete(ctx context.Context, id int64) error
+	// Deliveries lists the delivery history of a subscription, newest first
+	Deliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]models.WebhookDeliveryDTO, error)
+	// Redeliver queues a dead delivery again
+	Redeliver(ctx context.Context, subscriptionID, deliveryID int64) (models.WebhookDeliveryDTO, error)
+}
+
+type webhookService struct {
+	webhookRepository repository.IWebhookRepository
+	auditRepository   repository.IAuditRepository
+	transactor        repository.ITransactor
+	policy            IPolicy
+	// allowHTTP accepts plain http URLs, for local receivers
+	allowHTTP bool
+}
+
+// NewWebhookService creates a new service with the provided repositories, authorizing every call with policy.
+// Subscription changes are audited in the same transaction.
+func NewWebhookService(webhookRepository repository.IWebhookRepository, auditRepository repository.IAuditRepository, transactor repository.ITransactor, policy IPolicy, allowHTTP bool) IWebhookService {
+	return &webhookService{
+		webhookRepository: webhookRepository,
+		auditRepository:   auditRepository,
+		transactor:        transactor,
+		policy:            policy,
+		allowHTTP:         allowHTTP,
+	}
+}
+
+// List returns the subscriptions the principal may see, without their secrets
+func (s *webhookService) List(ctx context.Context) ([]models.WebhookSubscriptionDTO, error) {
+	access, err := s.policy.Authorize(ctx, ActionListWebhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.webhookRepository.FindSubscriptions(ctx, access.Owner())
+	if err != nil {
+		return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve webhook subscriptions: %v", err))
+	}
+
+	subscriptionDtos := make([]models.WebhookSubscriptionDTO, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		subscriptionDtos = append(subscriptionDtos, subscription.ToWebhookSubscriptionDTO())
+	}
+	return subscriptionDtos, nil
+}
+
+// Create validates the URL and event types and generates the signing secret
+func (s *webhookService) Create(ctx context.Context, request models.CreateWebhookSubscriptionRequest) (models.CreatedWebhookSubscriptionDTO, error) {
+	access, err := s.policy.Authorize(ctx, ActionCreateWebhook)
+	if err != nil {
+		return models.CreatedWebhookSubscriptionDTO{}, err
+	}
+	if !access.All() {
+		// Restricted principals can only subscribe for themselves
+		request.OwnerEmail = access.Owner()
+	}
+
+	if err := s.validateURL(request.URL); err != nil {
+		return models.CreatedWebhookSubscriptionDTO{}, err
+	}
+	eventTypes, err := validateEventTypes(request.EventTypes)
+	if err != nil {
+		return models.CreatedWebhookSubscriptionDTO{}, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return models.CreatedWebhookSubscriptionDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to generate webhook secret: %v", err))
+	}
+
+	subscription := models.WebhookSubscription{
+		URL:         request.URL,
+		EventTypes:  eventTypes,
+		Secret:      secret,
+		Description: request.Description,
+		CreatedBy:   systemActor,
+	}
+	if request.OwnerEmail != "" {
+		subscription.OwnerEmail.String, subscription.OwnerEmail.Valid = models.NormalizeEmail(request.OwnerEmail), true
+	}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.Subject != "" {
+		subscription.CreatedBy = principal.Subject
+	}
+
+	var created models.WebhookSubscription
+	err = withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		var err error
+		created, err = s.webhookRepository.CreateSubscription(ctx, subscription)
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to store webhook subscription: %v", err))
+		}
+		return recordChange(ctx, s.auditRepository, models.AuditActionCreate, models.AuditEntityWebhook, strconv.FormatInt(created.ID, 10), nil, created.ToWebhookSubscriptionDTO())
+	})
+	if err != nil {
+		return models.CreatedWebhookSubscriptionDTO{}, err
+	}
+
+	return models.CreatedWebhookSubscriptionDTO{WebhookSubscriptionDTO: created.ToWebhookSubscriptionDTO(), Secret: created.Secret}, nil
+}
+
+// Delete removes a subscription and its delivery history, pending deliveries are dropped
+func (s *webhookService) Delete(ctx context.Context, id int64) error {
+	return withinTx(ctx, s.transactor, func(ctx context.Context) error {
+		subscription, err := s.authorizeSubscription(ctx, ActionDeleteWebhook, id)
+		if err != nil {
+			return err
+		}
+
+		err = s.webhookRepository.DeleteSubscription(ctx, id)
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return errors.NotFoundError(fmt.Sprintf("Webhook subscription %d not found", id))
+		}
+		if err != nil {
+			return errors.InternalServerError(fmt.Sprintf("Failed to delete webhook subscription %d: %v", id, err))
+		}
+		return recordChange(ctx, s.auditRepository, models.AuditActionDelete, models.AuditEntityWebhook, strconv.FormatInt(id, 10), subscription.ToWebhookSubscriptionDTO(), nil)
+	})
+}
+
+// Deliveries pages through the deliveries of a subscription with BeforeID
+func (s *webhookService) Deliveries(ctx context.Context, filter models.WebhookDeliveryFilter) ([]models.WebhookDeliveryDTO, error) {
+	if _, err := s.authorizeSubscription(ctx, ActionReadWebhook, filter.SubscriptionID); err != nil {
+		return nil, err
+	}
+
+	switch filter.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+	default:
+		return nil, errors.BadRequestError(fmt.Sprintf("Unknown status %q, must be one of %s, %s, %s", filter.Status,
+			models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead))
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultDeliveryLimit
+	}
+	filter.Limit = min(filter.Limit, maxDeliveryLimit)
+
+	deliveries, err := s.webhookRepository.FindDeliveries(ctx, filter)
+	if err != nil {
+		return nil, errors.InternalServerError(fmt.Sprintf("Failed to retrieve webhook deliveries: %v", err))
+	}
+
+	deliveryDtos := make([]models.WebhookDeliveryDTO, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryDtos = append(deliveryDtos, delivery.ToWebhookDeliveryDTO())
+	}
+	return deliveryDtos, nil
+}
+
+// Redeliver gives a dead delivery a fresh set of attempts, starting right away
+func (s *webhookService) Redeliver(ctx context.Context, subscriptionID, deliveryID int64) (models.WebhookDeliveryDTO, error) {
+	if _, err := s.authorizeSubscription(ctx, ActionRetryWebhook, subscriptionID); err != nil {
+		return models.WebhookDeliveryDTO{}, err
+	}
+
+	delivery, err := s.webhookRepository.Redeliver(ctx, subscriptionID, deliveryID)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return models.WebhookDeliveryDTO{}, errors.NotFoundError(fmt.Sprintf("Dead delivery %d of webhook subscription %d not found", deliveryID, subscriptionID))
+	}
+	if err != nil {
+		return models.WebhookDeliveryDTO{}, errors.InternalServerError(fmt.Sprintf("Failed to redeliver webhook delivery %d: %v", deliveryID, err))
+	}
+	return delivery.ToWebhookDeliveryDTO(), nil
+}
+
+// authorizeSubscription loads a subscription and checks the principal may perform action on it.
+// Subscriptions of other owners are reported as not found.
+func (s *webhookService) authorizeSubscription(ctx context.Context, action string, id int64) (models.WebhookSubscription, error) {
+	access, err := s.policy.Authorize(ctx, action)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+
+	subscription, err := s.webhookRepository.FindSubscriptionByID(ctx, id)
+	if stderrors.Is(err, repository.ErrNotFound) || (err == nil && !access.Allows(subscription.OwnerEmail.String)) {
+		return models.WebhookSubscription{}, errors.NotFoundError(fmt.Sprintf("Webhook subscription %d not found", id))
+	}
+	if err != nil {
+		return models.WebhookSubscription{}, errors.InternalServerError(fmt.Sprintf("Failed to retrieve webhook subscription %d: %v", id, err))
+	}
+	return subscription, nil
+}
+
+// validateURL accepts absolute https URLs, and http ones when allowed
+func (s *webhookService) validateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return errors.BadRequestError(fmt.Sprintf("Invalid webhook url %q", rawURL))
+	}
+	if parsed.User != nil {
+		return errors.BadRequestError("Webhook url must not contain credentials")
+	}
+	switch {
+	case parsed.Scheme == "https":
+	case parsed.Scheme == "http" && s.allowHTTP:
+	default:
+		return errors.BadRequestError("Webhook url must use https")
+	}
+	return nil
+}
+
+// validateEventTypes checks every type is known and returns them without duplicates
+func validateEventTypes(eventTypes []string) ([]string, error) {
+	var valid []string
+	for _, eventType := range eventTypes {
+		if eventType != models.WebhookAllEvents && !slices.Contains(models.EventTypes, eventType) {
+			return nil, errors.BadRequestError(fmt.Sprintf("Unknown event type %q, must be %s or one of %s",
+				eventType, models.WebhookAllEvents, strings.Join(models.EventTypes, ", ")))
+		}
+		if !slices.Contains(valid, eventType) {
+			valid = append(valid, eventType)
+		}
+	}
+	return valid, nil
+}
+
+// generateWebhookSecret returns a random secret for signing deliveries
+func generateWebhookSecret() (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
@@ -0,0 +1,40 @@
+package statements
+
+import (
+	"io"
+	"org/gg/banking/internal/exports"
+	"org/gg/banking/internal/models"
+	"time"
+)
+
+// csvColumns are the columns of a CSV statement
+var csvColumns = []string{"date", "description", "amount", "balance"}
+
+// csvRenderer writes a row per movement between an opening and a closing balance row. It uses the CSV writer of
+// the exports, so descriptions a spreadsheet would evaluate are neutralized the same way.
+type csvRenderer struct{}
+
+func (csvRenderer) ContentType() string {
+	return exports.ContentTypeCSV
+}
+
+func (csvRenderer) Render(w io.Writer, statement models.AccountStatement) error {
+	writer, err := exports.NewWriter(exports.ContentTypeCSV, w, csvColumns)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.Write([]any{statement.From.Format(dateLayout), "Opening balance", nil, formatAmount(statement.OpeningBalance)}); err != nil {
+		return err
+	}
+	for _, line := range statement.Lines {
+		values := []any{line.OccurredAt.Format(time.RFC3339), line.Description, formatAmount(line.Amount), formatAmount(line.Balance)}
+		if err := writer.Write(values); err != nil {
+			return err
+		}
+	}
+	if err := writer.Write([]any{statement.To.Format(dateLayout), "Closing balance", nil, formatAmount(statement.ClosingBalance)}); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,350 @@
+package statements
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Layout of the A4 page in millimetres
+const (
+	pageMargin   = 15.0
+	pageWidth    = 210.0 - 2*pageMargin
+	bottomMargin = 20.0
+	rowHeight    = 6.5
+)
+
+// tableColumns are the widths of the date, description, amount and balance columns, filling the page width
+var tableColumns = [4]float64{34, 86, 30, 30}
+
+// fontFamily names the TrueType fonts of the branding once registered with a document
+const fontFamily = "branding"
+
+// pdfRenderer writes statements as A4 PDF documents in pure Go, branded with the bank name, address, logo, accent
+// color and the title and footer templates of the configuration
+type pdfRenderer struct {
+	branding config.StatementBrandingConfiguration
+	title    *template.Template
+	footer   *template.Template
+	color    [3]int
+	// logo holds the image file and logoType its gofpdf image type, png or jpg
+	logo     []byte
+	logoType string
+	// font and boldFont hold the TrueType files, Helvetica is used when they are nil
+	font     []byte
+	boldFont []byte
+}
+
+func newPDFRenderer(branding config.StatementBrandingConfiguration) (*pdfRenderer, error) {
+	renderer := &pdfRenderer{branding: branding}
+
+	var err error
+	if renderer.title, err = template.New("title").Parse(branding.Title); err != nil {
+		return nil, fmt.Errorf("parsing statement title: %w", err)
+	}
+	if renderer.footer, err = template.New("footer").Parse(branding.Footer); err != nil {
+		return nil, fmt.Errorf("parsing statement footer: %w", err)
+	}
+	// Unknown fields only show when a template is executed
+	for _, tmpl := range []*template.Template{renderer.title, renderer.footer} {
+		if _, err := execute(tmpl, TemplateData{}); err != nil {
+			return nil, fmt.Errorf("statement %s template: %w", tmpl.Name(), err)
+		}
+	}
+
+	color, err := strconv.ParseUint(strings.TrimPrefix(branding.Color, "#"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parsing statement color %q: %w", branding.Color, err)
+	}
+	renderer.color = [3]int{int(color >> 16 & 0xff), int(color >> 8 & 0xff), int(color & 0xff)}
+
+	if branding.Logo != "" {
+		if renderer.logo, err = os.ReadFile(branding.Logo); err != nil {
+			return nil, fmt.Errorf("reading statement logo: %w", err)
+		}
+		renderer.logoType = "png"
+		if extension := strings.ToLower(filepath.Ext(branding.Logo)); extension == ".jpg" || extension == ".jpeg" {
+			renderer.logoType = "jpg"
+		}
+	}
+
+	if branding.Font != "" {
+		if renderer.font, err = os.ReadFile(branding.Font); err != nil {
+			return nil, fmt.Errorf("reading statement font: %w", err)
+		}
+		renderer.boldFont = renderer.font
+		if branding.BoldFont != "" {
+			if renderer.boldFont, err = os.ReadFile(branding.BoldFont); err != nil {
+				return nil, fmt.Errorf("reading statement bold font: %w", err)
+			}
+		}
+	}
+
+	return renderer, nil
+}
+
+func (r *pdfRenderer) ContentType() string {
+	return "application/pdf"
+}
+
+// Render lays out a summary of the period followed by the movements, the table continuing over as many pages as
+// needed with its header repeated
+func (r *pdfRenderer) Render(w io.Writer, statement models.AccountStatement) error {
+	document := r.newDocument(statement)
+	document.pdf.AddPage()
+	document.details(statement)
+	document.summary(statement)
+	document.movements(statement)
+	return document.pdf.Output(w)
+}
+
+// pdfDocument is a statement being laid out
+type pdfDocument struct {
+	*pdfRenderer
+	pdf    *gofpdf.Fpdf
+	family string
+	// text converts UTF-8 to the encoding of the font, Helvetica only holds Windows-1252
+	text func(string) string
+	data TemplateData
+	// inTable repeats the table header on every page the movements continue on
+	inTable bool
+}
+
+func (r *pdfRenderer) newDocument(statement models.AccountStatement) *pdfDocument {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	document := &pdfDocument{pdfRenderer: r, pdf: pdf, family: "Helvetica", text: func(s string) string { return s }}
+	if r.font != nil {
+		pdf.AddUTF8FontFromBytes(fontFamily, "", r.font)
+		pdf.AddUTF8FontFromBytes(fontFamily, "B", r.boldFont)
+		document.family = fontFamily
+	} else {
+		document.text = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	if r.logo != nil {
+		pdf.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: r.logoType}, bytes.NewReader(r.logo))
+	}
+
+	document.data = TemplateData{
+		BankName:      r.branding.BankName,
+		AccountNumber: statement.Account.AccountNumber,
+		CustomerName:  strings.TrimSpace(statement.Customer.FirstName + " " + statement.Customer.LastName),
+		From:          statement.From.Format(dateLayout),
+		To:            statement.To.Format(dateLayout),
+		Pages:         "{nb}",
+	}
+	title, err := execute(r.title, document.data)
+	if err != nil {
+		pdf.SetError(err)
+	}
+
+	pdf.SetTitle(title, true)
+	pdf.SetAuthor(r.branding.BankName, true)
+	pdf.SetCreationDate(statement.GeneratedAt)
+	pdf.AliasNbPages(document.data.Pages)
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, bottomMargin)
+	pdf.SetHeaderFunc(func() { document.header(title) })
+	pdf.SetFooterFunc(document.footerLine)
+	return document
+}
+
+// header draws the branding at the top of every page
+func (d *pdfDocument) header(title string) {
+	pdf := d.pdf
+	if d.logo != nil {
+		pdf.ImageOptions("logo", pageMargin, pageMargin-3, 0, 16, false, gofpdf.ImageOptions{ImageType: d.logoType}, 0, "")
+	}
+
+	pdf.SetXY(pageMargin, pageMargin-3)
+	d.font("B", 14)
+	pdf.CellFormat(pageWidth, 7, d.text(d.branding.BankName), "", 2, "R", false, 0, "")
+	d.font("", 9)
+	pdf.SetTextColor(96, 96, 96)
+	for _, line := range d.branding.Address {
+		pdf.CellFormat(pageWidth, 4.5, d.text(line), "", 2, "R", false, 0, "")
+	}
+
+	pdf.SetXY(pageMargin, max(pdf.GetY(), pageMargin+14)+4)
+	d.font("B", 15)
+	pdf.SetTextColor(d.color[0], d.color[1], d.color[2])
+	pdf.CellFormat(pageWidth, 9, d.text(title), "", 1, "L", false, 0, "")
+	pdf.SetDrawColor(d.color[0], d.color[1], d.color[2])
+	pdf.SetLineWidth(0.6)
+	pdf.Line(pageMargin, pdf.GetY(), pageMargin+pageWidth, pdf.GetY())
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(5)
+
+	if d.inTable {
+		d.tableHeader()
+	}
+}
+
+// footerLine draws the footer template at the bottom of every page
+func (d *pdfDocument) footerLine() {
+	d.data.Page = d.pdf.PageNo()
+	footer, err := execute(d.footer, d.data)
+	if err != nil {
+		d.pdf.SetError(err)
+		return
+	}
+
+	d.pdf.SetY(-bottomMargin + 5)
+	d.font("", 8)
+	d.pdf.SetTextColor(128, 128, 128)
+	d.pdf.CellFormat(pageWidth, 6, d.text(footer), "T", 0, "C", false, 0, "")
+	d.pdf.SetTextColor(0, 0, 0)
+}
+
+// details lists the customer, the account and the period
+func (d *pdfDocument) details(statement models.AccountStatement) {
+	account := statement.Account.AccountNumber
+	if statement.Account.AccountDescription != "" {
+		account += " - " + statement.Account.AccountDescription
+	}
+	if statement.Account.DeletedAt.Valid {
+		account += ", closed on " + statement.Account.DeletedAt.Time.In(statement.From.Location()).Format(dateLayout)
+	}
+
+	for _, detail := range [][2]string{
+		{"Customer", d.data.CustomerName + " <" + statement.Customer.Email + ">"},
+		{"Account", account},
+		{"Period", d.data.From + " to " + d.data.To},
+		{"Generated", statement.GeneratedAt.Format("2006-01-02 15:04 MST")},
+	} {
+		d.font("", 9)
+		d.pdf.SetTextColor(96, 96, 96)
+		d.pdf.CellFormat(25, 5.5, d.text(detail[0]), "", 0, "L", false, 0, "")
+		d.font("", 10)
+		d.pdf.SetTextColor(0, 0, 0)
+		d.pdf.CellFormat(pageWidth-25, 5.5, d.text(d.fit(detail[1], pageWidth-25)), "", 1, "L", false, 0, "")
+	}
+	d.pdf.Ln(5)
+}
+
+// summary shows the balances and the money in and out of the period in a row of boxes
+func (d *pdfDocument) summary(statement models.AccountStatement) {
+	var in, out float64
+	for _, line := range statement.Lines {
+		if line.Amount > 0 {
+			in += line.Amount
+		} else {
+			out += line.Amount
+		}
+	}
+
+	boxes := [][2]string{
+		{"Opening balance", formatAmount(statement.OpeningBalance)},
+		{"Money in", formatAmount(in)},
+		{"Money out", formatAmount(out)},
+		{"Closing balance", formatAmount(statement.ClosingBalance)},
+	}
+	width := pageWidth / float64(len(boxes))
+	x, y := d.pdf.GetX(), d.pdf.GetY()
+	d.pdf.SetDrawColor(200, 200, 200)
+	d.pdf.SetLineWidth(0.2)
+	for i, box := range boxes {
+		d.pdf.Rect(x+float64(i)*width, y, width, 16, "D")
+		d.pdf.SetXY(x+float64(i)*width, y+2)
+		d.font("", 8)
+		d.pdf.SetTextColor(96, 96, 96)
+		d.pdf.CellFormat(width, 5, d.text(box[0]), "", 2, "C", false, 0, "")
+		d.font("B", 12)
+		d.pdf.SetTextColor(0, 0, 0)
+		d.pdf.CellFormat(width, 7, box[1], "", 0, "C", false, 0, "")
+	}
+	d.pdf.SetXY(x, y+16+7)
+}
+
+// movements draws the table of movements between the opening and the closing balance
+func (d *pdfDocument) movements(statement models.AccountStatement) {
+	d.tableHeader()
+	d.inTable = true
+
+	d.row(false, "B", d.data.From, "Opening balance", "", formatAmount(statement.OpeningBalance))
+	for i, line := range statement.Lines {
+		d.row(i%2 == 0, "", line.OccurredAt.Format("2006-01-02 15:04"), line.Description, formatAmount(line.Amount), formatAmount(line.Balance))
+	}
+	if len(statement.Lines) == 0 {
+		d.font("", 9)
+		d.pdf.SetTextColor(128, 128, 128)
+		d.pdf.CellFormat(pageWidth, rowHeight, d.text("No movements in this period"), "", 1, "C", false, 0, "")
+		d.pdf.SetTextColor(0, 0, 0)
+	}
+	d.row(false, "B", d.data.To, "Closing balance", "", formatAmount(statement.ClosingBalance))
+
+	d.inTable = false
+	d.pdf.SetDrawColor(d.color[0], d.color[1], d.color[2])
+	d.pdf.Line(pageMargin, d.pdf.GetY(), pageMargin+pageWidth, d.pdf.GetY())
+}
+
+// tableHeader draws the column names in the accent color
+func (d *pdfDocument) tableHeader() {
+	d.font("B", 9)
+	d.pdf.SetFillColor(d.color[0], d.color[1], d.color[2])
+	d.pdf.SetTextColor(255, 255, 255)
+	for i, name := range []string{"Date", "Description", "Amount", "Balance"} {
+		align := "L"
+		if i >= 2 {
+			align = "R"
+		}
+		d.pdf.CellFormat(tableColumns[i], rowHeight+0.5, name, "", 0, align, true, 0, "")
+	}
+	d.pdf.Ln(-1)
+	d.pdf.SetTextColor(0, 0, 0)
+}
+
+// row draws one line of the table, shaded when fill is set
+func (d *pdfDocument) row(fill bool, style, date, description, amount, balance string) {
+	// Break before the row rather than within it, so the header of the next page comes first
+	_, pageHeight := d.pdf.GetPageSize()
+	if d.pdf.GetY()+rowHeight > pageHeight-bottomMargin {
+		d.pdf.AddPage()
+	}
+
+	d.font(style, 9)
+	d.pdf.SetFillColor(244, 246, 248)
+	d.pdf.CellFormat(tableColumns[0], rowHeight, date, "", 0, "L", fill, 0, "")
+	d.pdf.CellFormat(tableColumns[1], rowHeight, d.text(d.fit(description, tableColumns[1]-2)), "", 0, "L", fill, 0, "")
+	if strings.HasPrefix(amount, "-") {
+		d.pdf.SetTextColor(176, 0, 0)
+	}
+	d.pdf.CellFormat(tableColumns[2], rowHeight, amount, "", 0, "R", fill, 0, "")
+	d.pdf.SetTextColor(0, 0, 0)
+	d.pdf.CellFormat(tableColumns[3], rowHeight, balance, "", 1, "R", fill, 0, "")
+}
+
+// font selects the regular or bold ("B") style of the document font
+func (d *pdfDocument) font(style string, size float64) {
+	d.pdf.SetFont(d.family, style, size)
+}
+
+// fit shortens text with an ellipsis until it fits width in the current font
+func (d *pdfDocument) fit(text string, width float64) string {
+	if d.pdf.GetStringWidth(d.text(text)) <= width {
+		return text
+	}
+	for text != "" && d.pdf.GetStringWidth(d.text(text+"...")) > width {
+		_, size := utf8.DecodeLastRuneInString(text)
+		text = text[:len(text)-size]
+	}
+	return text + "..."
+}
+
+// execute renders a branding template
+func execute(tmpl *template.Template, data TemplateData) (string, error) {
+	var text strings.Builder
+	if err := tmpl.Execute(&text, data); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,58 @@
+package statements
+
+import (
+	"io"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/models"
+	"strconv"
+)
+
+// Formats of a statement, as chosen with the format query parameter
+const (
+	FormatPDF = "pdf"
+	FormatCSV = "csv"
+)
+
+// dateLayout renders the days of a statement
+const dateLayout = "2006-01-02"
+
+// IRenderer writes an account statement in one format
+type IRenderer interface {
+	// ContentType is the media type of the statements written
+	ContentType() string
+	Render(w io.Writer, statement models.AccountStatement) error
+}
+
+// TemplateData holds the fields available to the title and footer templates of the branding
+type TemplateData struct {
+	BankName      string
+	AccountNumber string
+	CustomerName  string
+	// From and To are the first and the last day of the statement
+	From string
+	To   string
+	// Page is the current page, Pages a placeholder replaced with the page count once the document is complete
+	Page  int
+	Pages string
+}
+
+// NewRenderers returns a renderer for every format keyed by its name. The logo and fonts of the branding are read
+// and its templates checked here, so a broken branding fails at startup rather than with the first statement.
+func NewRenderers(branding config.StatementBrandingConfiguration) (map[string]IRenderer, error) {
+	pdf, err := newPDFRenderer(branding)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]IRenderer{
+		FormatPDF: pdf,
+		FormatCSV: csvRenderer{},
+	}, nil
+}
+
+// formatAmount renders an amount with two decimals, never as -0.00
+func formatAmount(amount float64) string {
+	if amount == 0 {
+		amount = 0
+	}
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
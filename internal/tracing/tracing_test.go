@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/middleware/requestid"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	inboundTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	traceParent    = "00-" + inboundTraceID + "-00f067aa0ba902b7-01"
+)
+
+// initTracing runs Init and restores the global provider and propagator when the test ends
+func initTracing(t *testing.T, tracingConfig config.TracingConfiguration) (func(context.Context) error, error) {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return Init(context.Background(), tracingConfig)
+}
+
+// exportedSpan holds the fields of a span written by the stdout exporter that the tests check
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+	Status struct {
+		Code        string
+		Description string
+	}
+}
+
+func TestInitFileExporter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "traces", "spans.json")
+	shutdown, err := initTracing(t, config.TracingConfiguration{Enabled: true, ServiceName: "banking-test", Exporter: "FILE", Path: path, SampleRatio: 0})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	// The request is sampled although the ratio is 0, it follows the sampling decision of the caller
+	var requestID string
+	router := gin.New()
+	router.Use(otelgin.Middleware("banking-test"), requestid.RequestIDMiddleware())
+	router.GET("/customers/:email", func(c *gin.Context) {
+		requestID = c.Writer.Header().Get(requestid.HeaderName)
+		_, span := Tracer().Start(c.Request.Context(), "CustomerService.FindCustomerWithAccounts")
+		End(span, errors.New("customer not found"))
+		c.Status(http.StatusNotFound)
+	})
+	request := httptest.NewRequest(http.MethodGet, "/customers/john.doe@example.com", nil)
+	request.Header.Set("traceparent", traceParent)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown error = %v", err)
+	}
+
+	if requestID != inboundTraceID {
+		t.Errorf("request id = %q, want the inbound trace id", requestID)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading exported spans: %v", err)
+	}
+	spans := map[string]exportedSpan{}
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	for decoder.More() {
+		var span exportedSpan
+		if err := decoder.Decode(&span); err != nil {
+			t.Fatalf("decoding exported span: %v", err)
+		}
+		spans[span.Name] = span
+	}
+
+	server, ok := spans["/customers/:email"]
+	if !ok {
+		t.Fatalf("exported %v, want a span named after the route template", spans)
+	}
+	service := spans["CustomerService.FindCustomerWithAccounts"]
+	if server.SpanContext.TraceID != inboundTraceID || service.SpanContext.TraceID != inboundTraceID {
+		t.Errorf("trace ids %s and %s, want the inbound %s", server.SpanContext.TraceID, service.SpanContext.TraceID, inboundTraceID)
+	}
+	if server.Parent.SpanID != "00f067aa0ba902b7" || service.Parent.SpanID != server.SpanContext.SpanID {
+		t.Errorf("service span parent %s, request span parent %s, want the request span and the inbound span", service.Parent.SpanID, server.Parent.SpanID)
+	}
+	if service.Status.Code != "Error" || service.Status.Description != "customer not found" {
+		t.Errorf("service span status %+v, want the recorded error", service.Status)
+	}
+}
+
+func TestInitDisabled(t *testing.T) {
+	shutdown, err := initTracing(t, config.TracingConfiguration{Exporter: "unknown"})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown error = %v", err)
+	}
+
+	// Inbound trace context is honored even when no spans are recorded
+	header := http.Header{}
+	header.Set("traceparent", traceParent)
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	if got := trace.SpanContextFromContext(ctx).TraceID().String(); got != inboundTraceID {
+		t.Errorf("extracted trace id %s, want %s", got, inboundTraceID)
+	}
+}
+
+func TestInitRejectsUnknownExporter(t *testing.T) {
+	if _, err := initTracing(t, config.TracingConfiguration{Enabled: true, Exporter: "zipkin"}); err == nil {
+		t.Error("Init() accepted an unknown exporter")
+	}
+}
@@ -0,0 +1,169 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/events"
+	"org/gg/banking/internal/metrics"
+	"org/gg/banking/internal/models"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// userAgent identifies deliveries to receivers
+const userAgent = "banking-webhooks/1"
+
+// maxResponseBytes bounds how much of a response is read before the connection is reused
+const maxResponseBytes = 64 << 10
+
+// IDeliveryStore holds the deliveries the dispatcher works through, implemented by the webhook repository
+type IDeliveryStore interface {
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDispatch, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	MarkFailed(ctx context.Context, id int64, statusCode int, reason string, retryAt time.Time, dead bool) error
+}
+
+// Dispatcher posts queued deliveries to their subscription URL. Any 2xx response confirms a delivery, everything
+// else, redirects included, is retried with exponential backoff until MaxAttempts, after which the delivery is dead.
+type Dispatcher struct {
+	store  IDeliveryStore
+	client *http.Client
+	config config.WebhookConfiguration
+	logger *slog.Logger
+}
+
+func NewDispatcher(store IDeliveryStore, cfg config.WebhookConfiguration, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// A redirect could point anywhere, receivers must answer at the registered URL
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain full batches without waiting for the next tick
+		for d.dispatchBatch(ctx) == d.config.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchBatch delivers one batch with up to Workers deliveries in flight and returns how many were claimed
+func (d *Dispatcher) dispatchBatch(ctx context.Context) int {
+	claimed, err := d.store.ClaimDeliveries(ctx, d.config.BatchSize, d.config.Lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "Failed to claim webhook deliveries", slog.Any("error", err))
+		}
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, max(d.config.Workers, 1))
+	for _, dispatch := range claimed {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			d.deliver(ctx, dispatch)
+		}()
+	}
+	wg.Wait()
+
+	return len(claimed)
+}
+
+// deliver posts one delivery and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, dispatch models.WebhookDispatch) {
+	delivery := dispatch.Delivery
+	attributes := []any{
+		slog.Int64("delivery_id", delivery.ID),
+		slog.Int64("subscription_id", delivery.SubscriptionID),
+		slog.String("event_id", delivery.EventID),
+		slog.String("event_type", delivery.EventType),
+		slog.Int("attempt", delivery.Attempts),
+	}
+
+	statusCode, err := d.post(ctx, dispatch)
+	if err == nil {
+		metrics.WebhookDeliveries.WithLabelValues("delivered").Inc()
+		if err := d.store.MarkDelivered(ctx, delivery.ID, statusCode); err != nil {
+			d.logger.ErrorContext(ctx, "Failed to mark webhook delivered, it will be delivered again", append(attributes, slog.Any("error", err))...)
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// Shutting down, the lease expires and the delivery is retried without counting against the receiver
+		return
+	}
+
+	dead := delivery.Attempts >= d.config.MaxAttempts
+	retryAt := time.Now().Add(events.Backoff(d.config.RetryInitial, d.config.RetryMax, delivery.Attempts))
+	if dead {
+		metrics.WebhookDeliveries.WithLabelValues("dead").Inc()
+		d.logger.WarnContext(ctx, "Webhook delivery failed for the last time, moved to dead letters", append(attributes, slog.Any("error", err))...)
+	} else {
+		metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+		d.logger.InfoContext(ctx, "Webhook delivery failed, will retry", append(attributes, slog.Time("retry_at", retryAt), slog.Any("error", err))...)
+	}
+	if err := d.store.MarkFailed(ctx, delivery.ID, statusCode, err.Error(), retryAt, dead); err != nil {
+		d.logger.ErrorContext(ctx, "Failed to record webhook delivery failure", append(attributes, slog.Any("error", err))...)
+	}
+}
+
+// post sends the signed payload and returns the status code, 0 when no response was received.
+// A non-2xx response is returned as an error.
+func (d *Dispatcher) post(ctx context.Context, dispatch models.WebhookDispatch) (int, error) {
+	delivery := dispatch.Delivery
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", userAgent)
+	request.Header.Set(SignatureHeader, Sign(dispatch.Secret, time.Now(), delivery.Payload))
+	request.Header.Set(EventTypeHeader, delivery.EventType)
+	request.Header.Set(EventIDHeader, delivery.EventID)
+	request.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseBytes))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("receiver responded %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
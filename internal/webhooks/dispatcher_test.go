@@ -0,0 +1,301 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"org/gg/banking/internal/config"
+	"org/gg/banking/internal/events"
+	"org/gg/banking/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryDeliveryStore keeps deliveries like the webhook repository: claiming counts an attempt, failures are
+// retried once due unless they are dead
+type memoryDeliveryStore struct {
+	mu         sync.Mutex
+	dispatches []*models.WebhookDispatch
+	retryAt    map[int64]time.Time
+	failedAt   map[int64]time.Time
+	statusCode map[int64]int
+}
+
+func newMemoryDeliveryStore(dispatches ...models.WebhookDispatch) *memoryDeliveryStore {
+	store := &memoryDeliveryStore{
+		retryAt:    map[int64]time.Time{},
+		failedAt:   map[int64]time.Time{},
+		statusCode: map[int64]int{},
+	}
+	for _, dispatch := range dispatches {
+		dispatch.Delivery.Status = models.WebhookDeliveryPending
+		store.dispatches = append(store.dispatches, &dispatch)
+	}
+	return store
+}
+
+// ClaimDeliveries ignores the retry time so the test does not wait for the backoff
+func (s *memoryDeliveryStore) ClaimDeliveries(_ context.Context, limit int, _ time.Duration) ([]models.WebhookDispatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed []models.WebhookDispatch
+	for _, dispatch := range s.dispatches {
+		if dispatch.Delivery.Status == models.WebhookDeliveryPending && len(claimed) < limit {
+			dispatch.Delivery.Attempts++
+			claimed = append(claimed, *dispatch)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryDeliveryStore) MarkDelivered(_ context.Context, id int64, statusCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.find(id).Delivery.Status = models.WebhookDeliveryDelivered
+	s.statusCode[id] = statusCode
+	return nil
+}
+
+func (s *memoryDeliveryStore) MarkFailed(_ context.Context, id int64, statusCode int, _ string, retryAt time.Time, dead bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dead {
+		s.find(id).Delivery.Status = models.WebhookDeliveryDead
+	}
+	s.retryAt[id] = retryAt
+	s.failedAt[id] = time.Now()
+	s.statusCode[id] = statusCode
+	return nil
+}
+
+func (s *memoryDeliveryStore) find(id int64) *models.WebhookDispatch {
+	for _, dispatch := range s.dispatches {
+		if dispatch.Delivery.ID == id {
+			return dispatch
+		}
+	}
+	panic("unknown delivery " + strconv.FormatInt(id, 10))
+}
+
+func (s *memoryDeliveryStore) delivery(id int64) models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.find(id).Delivery
+}
+
+func testWebhookConfig() config.WebhookConfiguration {
+	return config.WebhookConfiguration{
+		PollInterval: 10 * time.Millisecond,
+		BatchSize:    10,
+		Workers:      2,
+		Timeout:      time.Second,
+		Lease:        time.Minute,
+		MaxAttempts:  4,
+		RetryInitial: time.Second,
+		RetryMax:     3 * time.Second,
+	}
+}
+
+func testDispatch(id int64, url string) models.WebhookDispatch {
+	return models.WebhookDispatch{
+		Delivery: models.WebhookDelivery{
+			ID:             id,
+			SubscriptionID: 7,
+			EventID:        "evt-" + strconv.FormatInt(id, 10),
+			EventType:      "customer.created",
+			Payload:        []byte(`{"type":"customer.created","data":{"email":"john.doe@example.com"}}`),
+		},
+		URL:    url,
+		Secret: "whsec_test",
+	}
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatch := testDispatch(1, server.URL)
+	store := newMemoryDeliveryStore(dispatch)
+	dispatcher := NewDispatcher(store, testWebhookConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	before := time.Now()
+	if claimed := dispatcher.dispatchBatch(context.Background()); claimed != 1 {
+		t.Fatalf("dispatchBatch claimed %d deliveries, want 1", claimed)
+	}
+	request := <-requests
+
+	if string(request.body) != string(dispatch.Delivery.Payload) {
+		t.Errorf("body = %s, want the payload %s", request.body, dispatch.Delivery.Payload)
+	}
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"User-Agent":    userAgent,
+		EventTypeHeader: "customer.created",
+		EventIDHeader:   "evt-1",
+		DeliveryHeader:  "1",
+	}
+	for name, want := range headers {
+		if got := request.header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// The header is t=<unix>,v1=<hex HMAC-SHA256 of "<unix>.<body>">, recomputed here as a receiver would
+	header := request.header.Get(SignatureHeader)
+	timestamp, signature, ok := strings.Cut(header, ",")
+	unix, foundT := strings.CutPrefix(timestamp, "t=")
+	signature, foundV1 := strings.CutPrefix(signature, "v1=")
+	if !ok || !foundT || !foundV1 {
+		t.Fatalf("%s = %q, want t=<unix>,v1=<signature>", SignatureHeader, header)
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || seconds < before.Unix() || seconds > time.Now().Unix() {
+		t.Errorf("timestamp %s is not the time of the delivery", unix)
+	}
+	mac := hmac.New(sha256.New, []byte(dispatch.Secret))
+	mac.Write([]byte(unix + "." + string(request.body)))
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature = %s, want %s", signature, want)
+	}
+	if err := VerifySignature(dispatch.Secret, header, request.body, time.Now(), time.Minute); err != nil {
+		t.Errorf("VerifySignature: %v", err)
+	}
+	if err := VerifySignature("other secret", header, request.body, time.Now(), time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifySignature with another secret = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	if got := store.delivery(1); got.Status != models.WebhookDeliveryDelivered || store.statusCode[1] != http.StatusNoContent {
+		t.Errorf("delivery = %s with status code %d, want delivered with 204", got.Status, store.statusCode[1])
+	}
+}
+
+func TestDispatcherRetriesUntilDead(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := testWebhookConfig()
+	store := newMemoryDeliveryStore(testDispatch(1, server.URL))
+	dispatcher := NewDispatcher(store, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		attempt    int
+		wantStatus string
+		wantDelay  time.Duration
+	}{
+		{attempt: 1, wantStatus: models.WebhookDeliveryPending, wantDelay: time.Second},
+		{attempt: 2, wantStatus: models.WebhookDeliveryPending, wantDelay: 2 * time.Second},
+		{attempt: 3, wantStatus: models.WebhookDeliveryPending, wantDelay: 3 * time.Second},
+		{attempt: 4, wantStatus: models.WebhookDeliveryDead, wantDelay: 3 * time.Second},
+	}
+	for _, tt := range tests {
+		if claimed := dispatcher.dispatchBatch(context.Background()); claimed != 1 {
+			t.Fatalf("attempt %d: claimed %d deliveries, want 1", tt.attempt, claimed)
+		}
+
+		delivery := store.delivery(1)
+		if delivery.Attempts != tt.attempt || delivery.Status != tt.wantStatus {
+			t.Errorf("attempt %d: delivery is %s after %d attempts, want %s", tt.attempt, delivery.Status, delivery.Attempts, tt.wantStatus)
+		}
+		if store.statusCode[1] != http.StatusServiceUnavailable {
+			t.Errorf("attempt %d: recorded status code %d, want 503", tt.attempt, store.statusCode[1])
+		}
+		if want := events.Backoff(cfg.RetryInitial, cfg.RetryMax, tt.attempt); want != tt.wantDelay {
+			t.Fatalf("attempt %d: Backoff = %s, want %s", tt.attempt, want, tt.wantDelay)
+		}
+		if delay := store.retryAt[1].Sub(store.failedAt[1]); delay > tt.wantDelay || delay < tt.wantDelay-time.Second/2 {
+			t.Errorf("attempt %d: retry in %s, want %s", tt.attempt, delay, tt.wantDelay)
+		}
+	}
+
+	if claimed := dispatcher.dispatchBatch(context.Background()); claimed != 0 {
+		t.Errorf("a dead delivery was claimed again")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != cfg.MaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", attempts, cfg.MaxAttempts)
+	}
+}
+
+func TestDispatcherRejectsRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect was followed")
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	store := newMemoryDeliveryStore(testDispatch(1, server.URL))
+	dispatcher := NewDispatcher(store, testWebhookConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	dispatcher.dispatchBatch(context.Background())
+
+	if got := store.delivery(1); got.Status != models.WebhookDeliveryPending || store.statusCode[1] != http.StatusTemporaryRedirect {
+		t.Errorf("delivery = %s with status code %d, want a failed attempt with 307", got.Status, store.statusCode[1])
+	}
+}
+
+func TestDispatcherRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	store := newMemoryDeliveryStore(testDispatch(1, server.URL), testDispatch(2, server.URL), testDispatch(3, server.URL))
+	cfg := testWebhookConfig()
+	cfg.BatchSize = 2
+	dispatcher := NewDispatcher(store, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(stopped)
+	}()
+
+	delivered := func() bool {
+		for id := int64(1); id <= 3; id++ {
+			if store.delivery(id).Status != models.WebhookDeliveryDelivered {
+				return false
+			}
+		}
+		return true
+	}
+	for deadline := time.Now().Add(5 * time.Second); !delivered(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("deliveries were not all delivered")
+		}
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
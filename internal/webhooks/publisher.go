@@ -0,0 +1,30 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"org/gg/banking/internal/events"
+	"org/gg/banking/internal/models"
+)
+
+// IDeliveryQueue queues an event for the subscriptions to its type, implemented by the webhook repository
+type IDeliveryQueue interface {
+	Enqueue(ctx context.Context, event models.Event) (int64, error)
+}
+
+type publisher struct {
+	queue IDeliveryQueue
+}
+
+// NewPublisher creates the outbox publisher that fans events out to webhook subscriptions. It only queues
+// deliveries, the Dispatcher posts them, so a slow partner never holds up the outbox.
+func NewPublisher(queue IDeliveryQueue) events.IEventPublisher {
+	return &publisher{queue: queue}
+}
+
+func (p *publisher) Publish(ctx context.Context, event models.Event) error {
+	if _, err := p.queue.Enqueue(ctx, event); err != nil {
+		return fmt.Errorf("queueing webhook deliveries: %w", err)
+	}
+	return nil
+}